@@ -12,5 +12,6 @@ var (
 	ErrSupervisorRestartsExceeded = errors.New("restart intensity is exceeded")
 	ErrSupervisorChildDuplicate   = errors.New("duplicate child spec Name")
 
-	ErrPoolEmpty = errors.New("no worker process in the pool")
+	ErrPoolEmpty   = errors.New("no worker process in the pool")
+	ErrPoolScaling = errors.New("incorrect pool scaling options")
 )
@@ -55,6 +55,11 @@ type Pool struct {
 
 	options PoolOptions
 	pool    lib.QueueMPSC
+	scaler  *poolScaler
+
+	slots   map[gen.PID]int
+	workers []gen.PID
+	ring    *poolRing
 }
 
 type PoolOptions struct {
@@ -62,6 +67,12 @@ type PoolOptions struct {
 	PoolSize          int64
 	WorkerFactory     gen.ProcessFactory
 	WorkerArgs        []any
+	// Routing defines how messages are distributed across the workers.
+	// By default, the pool uses round robin.
+	Routing PoolRouting
+	// Scaling enables automatic resizing of the pool. With enabled scaling
+	// PoolSize defines the initial number of workers.
+	Scaling PoolScaling
 }
 
 func (p *Pool) AddWorkers(n int) (int64, error) {
@@ -79,6 +90,7 @@ func (p *Pool) AddWorkers(n int) (int64, error) {
 			return 0, err
 		}
 		p.pool.Push(pid)
+		p.workerAdded(pid)
 	}
 
 	return p.pool.Len(), nil
@@ -95,6 +107,7 @@ func (p *Pool) RemoveWorkers(n int) (int64, error) {
 		}
 		pid := v.(gen.PID)
 		p.SendExit(pid, gen.TerminateReasonNormal)
+		p.workerRemoved(pid)
 	}
 
 	return p.pool.Len(), nil
@@ -125,12 +138,26 @@ func (p *Pool) ProcessInit(process gen.Process, args ...any) (rr error) {
 	if err != nil {
 		return err
 	}
-	p.options = options
 	if options.PoolSize < 1 {
 		options.PoolSize = defaultPoolSize
 	}
+	p.options = options
+	if err := p.initScaling(); err != nil {
+		return err
+	}
+	options = p.options
+
+	limit := options.PoolSize
+	if options.Scaling.MaxSize > limit {
+		limit = options.Scaling.MaxSize
+	}
+	if options.Routing.Strategy == PoolRoutingConsistentHash && options.Routing.Key == nil {
+		return fmt.Errorf("ProcessInit: routing key function is required for %s strategy",
+			options.Routing.Strategy)
+	}
 
-	p.pool = lib.NewQueueLimitMPSC(options.PoolSize*100, false)
+	p.pool = lib.NewQueueLimitMPSC(limit*100, false)
+	p.slots = make(map[gen.PID]int)
 	wopt := gen.ProcessOptions{
 		MailboxSize: options.WorkerMailboxSize,
 		LinkParent:  true,
@@ -142,12 +169,14 @@ func (p *Pool) ProcessInit(process gen.Process, args ...any) (rr error) {
 		}
 
 		p.pool.Push(pid)
+		p.workerAdded(pid)
 		if i == 0 {
 			pi, _ := p.Node().ProcessInfo(pid)
 			p.sWorkerBehavior = pi.Behavior
 		}
 	}
 
+	p.scheduleScaling()
 	return nil
 }
 
@@ -196,6 +225,9 @@ func (p *Pool) ProcessRun() (rr error) {
 			if ok {
 				// got new regular message. handle it
 				message = msg.(*gen.MailboxMessage)
+				if p.isScaleTick(message) {
+					break
+				}
 				if message.Type < gen.MailboxMessageTypeExit {
 					// MailboxMessageTypeRegular, MailboxMessageTypeRequest, MailboxMessageTypeEvent
 					p.forward(message)
@@ -217,6 +249,10 @@ func (p *Pool) ProcessRun() (rr error) {
 
 		switch message.Type {
 		case gen.MailboxMessageTypeRegular:
+			if p.isScaleTick(message) {
+				p.scale()
+				continue
+			}
 			if reason := p.behavior.HandleMessage(message.From, message.Message); reason != nil {
 				return reason
 			}
@@ -299,19 +335,35 @@ func (p *Pool) HandleEvent(message gen.MessageEvent) error {
 	return nil
 }
 func (p *Pool) HandleInspect(from gen.PID, item ...string) map[string]string {
-	return map[string]string{
-		"pool_size":           fmt.Sprintf("%d", p.options.PoolSize),
+	info := map[string]string{
+		"pool_size":           fmt.Sprintf("%d", p.pool.Len()),
+		"routing":             p.options.Routing.Strategy.String(),
 		"worker_behavior":     p.sWorkerBehavior,
 		"worker_mailbox_size": fmt.Sprintf("%d", p.options.WorkerMailboxSize),
 		"worker_restarts":     fmt.Sprintf("%d", p.restarts),
 		"messages_forwarded":  fmt.Sprintf("%d", p.forwarded),
 		"messages_unhandled":  fmt.Sprintf("%d", p.unhandled),
 	}
+	p.inspectScaling(info)
+	return info
 }
 
 // private
 
 func (p *Pool) forward(message *gen.MailboxMessage) {
+	if p.isBroadcast(message) {
+		p.broadcast(message)
+		return
+	}
+
+	if pid, ok := p.route(message); ok {
+		if p.forwardTo(pid, message) == false {
+			p.Log().Error("unable to route message from %s. ignored", message.From)
+			p.unhandled++
+		}
+		return
+	}
+
 	var err error
 	l := p.pool.Len()
 	for i := int64(0); i < l; i++ {
@@ -331,13 +383,15 @@ func (p *Pool) forward(message *gen.MailboxMessage) {
 				MailboxSize: p.options.WorkerMailboxSize,
 				LinkParent:  true,
 			}
-			pid, err := p.Spawn(p.options.WorkerFactory, wopt, p.options.WorkerArgs...)
+			newpid, err := p.Spawn(p.options.WorkerFactory, wopt, p.options.WorkerArgs...)
 			if err != nil {
 				p.Log().Error("unable to spawn new worker process: %s", err)
+				p.workerRemoved(pid)
 				continue
 			}
-			p.Forward(pid, message, gen.MessagePriorityNormal)
-			p.pool.Push(pid)
+			p.Forward(newpid, message, gen.MessagePriorityNormal)
+			p.pool.Push(newpid)
+			p.workerReplaced(pid, newpid)
 			p.forwarded++
 			p.restarts++
 			return
@@ -0,0 +1,339 @@
+package actor
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	defaultPoolRoutingReplicas = 100
+)
+
+// PoolRoutingStrategy defines how the pool distributes messages across the workers
+type PoolRoutingStrategy int
+
+func (s PoolRoutingStrategy) String() string {
+	switch s {
+	case PoolRoutingRoundRobin:
+		return "Round Robin"
+	case PoolRoutingConsistentHash:
+		return "Consistent Hash"
+	case PoolRoutingLeastLoaded:
+		return "Least Loaded"
+	case PoolRoutingRandom:
+		return "Random"
+	case PoolRoutingBroadcast:
+		return "Broadcast"
+	}
+	return "Bug: unknown pool routing strategy"
+}
+
+const (
+	// PoolRoutingRoundRobin forwards messages to the workers one by one, skipping
+	// those with the overflowed mailbox. This is the default strategy.
+	PoolRoutingRoundRobin PoolRoutingStrategy = 0
+
+	// PoolRoutingConsistentHash forwards messages with the same key (see PoolRouting.Key)
+	// to the same worker. Changing the number of workers moves only the keys
+	// belonging to the added/removed workers.
+	PoolRoutingConsistentHash PoolRoutingStrategy = 1
+
+	// PoolRoutingLeastLoaded forwards messages to the worker with the smallest
+	// number of messages in its mailbox.
+	PoolRoutingLeastLoaded PoolRoutingStrategy = 2
+
+	// PoolRoutingRandom forwards messages to the randomly chosen worker.
+	PoolRoutingRandom PoolRoutingStrategy = 3
+
+	// PoolRoutingBroadcast forwards messages to all workers. Requests made with
+	// gen.Process.Call are forwarded to a single worker using round robin
+	// since the caller can accept only one response.
+	PoolRoutingBroadcast PoolRoutingStrategy = 4
+)
+
+// PoolRouting defines routing options of the pool.
+type PoolRouting struct {
+	// Strategy defines routing strategy. Default is PoolRoutingRoundRobin.
+	Strategy PoolRoutingStrategy
+	// Key extracts the routing key from the message. Mandatory for PoolRoutingConsistentHash.
+	// Messages with an empty key are forwarded using round robin.
+	Key func(message any) string
+	// Replicas number of points on the hash ring per worker. Default 100.
+	Replicas int
+	// Broadcast makes the pool forward the message to all workers if it returns true,
+	// regardless of the strategy. It can be used for the control messages.
+	Broadcast func(message any) bool
+}
+
+// poolRing is a hash ring used by PoolRoutingConsistentHash. Points are
+// calculated from the worker slot (not PID), so the restarted worker takes
+// over the keys of the terminated one.
+type poolRing struct {
+	points []uint64
+	owners []gen.PID
+}
+
+func newPoolRing(slots map[gen.PID]int, replicas int) *poolRing {
+	ring := &poolRing{}
+	type point struct {
+		hash  uint64
+		owner gen.PID
+	}
+	points := make([]point, 0, len(slots)*replicas)
+	for pid, slot := range slots {
+		prefix := strconv.Itoa(slot) + "#"
+		for i := 0; i < replicas; i++ {
+			points = append(points, point{
+				hash:  poolHash(prefix + strconv.Itoa(i)),
+				owner: pid,
+			})
+		}
+	}
+	sort.Slice(points, func(a, b int) bool {
+		return points[a].hash < points[b].hash
+	})
+	ring.points = make([]uint64, len(points))
+	ring.owners = make([]gen.PID, len(points))
+	for i := range points {
+		ring.points[i] = points[i].hash
+		ring.owners[i] = points[i].owner
+	}
+	return ring
+}
+
+func (r *poolRing) lookup(key string) (gen.PID, bool) {
+	if len(r.points) == 0 {
+		return gen.PID{}, false
+	}
+	h := poolHash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i], true
+}
+
+func poolHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// membership of the workers
+
+func (p *Pool) workerAdded(pid gen.PID) {
+	used := make(map[int]bool, len(p.slots))
+	for _, slot := range p.slots {
+		used[slot] = true
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	p.slots[pid] = slot
+	p.workers = nil
+	p.ring = nil
+}
+
+func (p *Pool) workerRemoved(pid gen.PID) {
+	delete(p.slots, pid)
+	p.workers = nil
+	p.ring = nil
+}
+
+func (p *Pool) workerReplaced(old, pid gen.PID) {
+	slot, exist := p.slots[old]
+	if exist == false {
+		p.workerAdded(pid)
+		return
+	}
+	delete(p.slots, old)
+	p.slots[pid] = slot
+	p.workers = nil
+	p.ring = nil
+}
+
+// members returns the list of workers in the round robin order
+func (p *Pool) members() []gen.PID {
+	if p.workers != nil {
+		return p.workers
+	}
+	l := p.pool.Len()
+	p.workers = make([]gen.PID, 0, l)
+	for i := int64(0); i < l; i++ {
+		v, _ := p.pool.Pop()
+		p.pool.Push(v)
+		p.workers = append(p.workers, v.(gen.PID))
+	}
+	return p.workers
+}
+
+// routing
+
+// route returns the worker for the given message. Returns false if the message
+// must be forwarded using round robin.
+func (p *Pool) route(message *gen.MailboxMessage) (gen.PID, bool) {
+	routing := p.options.Routing
+
+	switch routing.Strategy {
+	case PoolRoutingConsistentHash:
+		if routing.Key == nil {
+			return gen.PID{}, false
+		}
+		key := routing.Key(message.Message)
+		if key == "" {
+			return gen.PID{}, false
+		}
+		if p.ring == nil {
+			replicas := routing.Replicas
+			if replicas < 1 {
+				replicas = defaultPoolRoutingReplicas
+			}
+			p.ring = newPoolRing(p.slots, replicas)
+		}
+		return p.ring.lookup(key)
+
+	case PoolRoutingLeastLoaded:
+		m, ok := p.Process.(mailboxReader)
+		if ok == false {
+			return gen.PID{}, false
+		}
+		return leastLoaded(p.members(), m.MailboxLen)
+
+	case PoolRoutingRandom:
+		workers := p.members()
+		if len(workers) == 0 {
+			return gen.PID{}, false
+		}
+		return workers[rand.Intn(len(workers))], true
+	}
+
+	return gen.PID{}, false
+}
+
+// mailboxReader is implemented by the process of the node. It is not a part of
+// gen.Process, so the least loaded routing falls back to the round robin for
+// any other implementation.
+type mailboxReader interface {
+	MailboxLen(to any) (int64, error)
+}
+
+// leastLoaded returns the worker with the shortest mailbox. The terminated
+// workers are skipped, they are restarted on forwarding with round robin.
+func leastLoaded(workers []gen.PID, mailboxLen func(to any) (int64, error)) (gen.PID, bool) {
+	var pid gen.PID
+	var found bool
+	min := int64(0)
+	for _, w := range workers {
+		l, err := mailboxLen(w)
+		if err != nil {
+			continue
+		}
+		if found && l >= min {
+			continue
+		}
+		pid = w
+		min = l
+		found = true
+		if min == 0 {
+			break
+		}
+	}
+	return pid, found
+}
+
+func (p *Pool) isBroadcast(message *gen.MailboxMessage) bool {
+	if message.Type == gen.MailboxMessageTypeRequest {
+		return false
+	}
+	routing := p.options.Routing
+	if routing.Strategy == PoolRoutingBroadcast {
+		return true
+	}
+	if routing.Broadcast == nil {
+		return false
+	}
+	return routing.Broadcast(message.Message)
+}
+
+func (p *Pool) broadcast(message *gen.MailboxMessage) {
+	workers := p.members()
+	if len(workers) == 0 {
+		p.Log().Error("no available worker process. ignored message from %s", message.From)
+		p.unhandled++
+		return
+	}
+
+	// copy the list since it could be changed on restarting the worker
+	list := make([]gen.PID, len(workers))
+	copy(list, workers)
+	for i, pid := range list {
+		m := message
+		if i < len(list)-1 {
+			// every worker releases the message after handling
+			m = gen.TakeMailboxMessage()
+			*m = *message
+		}
+		if p.forwardTo(pid, m) == false {
+			p.unhandled++
+			if m != message {
+				gen.ReleaseMailboxMessage(m)
+			}
+		}
+	}
+}
+
+// forwardTo forwards message to the given worker restarting it if it was terminated
+func (p *Pool) forwardTo(pid gen.PID, message *gen.MailboxMessage) bool {
+	err := p.Forward(pid, message, gen.MessagePriorityNormal)
+	if err == nil {
+		p.forwarded++
+		return true
+	}
+	if err != gen.ErrProcessUnknown && err != gen.ErrProcessTerminated {
+		p.Log().Error("unable to forward message from %s to %s: %s", message.From, pid, err)
+		return false
+	}
+
+	newpid, err := p.restartWorker(pid)
+	if err != nil {
+		p.Log().Error("unable to spawn new worker process: %s", err)
+		return false
+	}
+	if err := p.Forward(newpid, message, gen.MessagePriorityNormal); err != nil {
+		p.Log().Error("unable to forward message from %s to %s: %s", message.From, newpid, err)
+		return false
+	}
+	p.forwarded++
+	return true
+}
+
+// restartWorker spawns a new worker replacing the terminated one
+func (p *Pool) restartWorker(old gen.PID) (gen.PID, error) {
+	wopt := gen.ProcessOptions{
+		MailboxSize: p.options.WorkerMailboxSize,
+		LinkParent:  true,
+	}
+	pid, err := p.Spawn(p.options.WorkerFactory, wopt, p.options.WorkerArgs...)
+	if err != nil {
+		return pid, err
+	}
+
+	l := p.pool.Len()
+	for i := int64(0); i < l; i++ {
+		v, _ := p.pool.Pop()
+		if v.(gen.PID) == old {
+			v = pid
+		}
+		p.pool.Push(v)
+	}
+	p.workerReplaced(old, pid)
+	p.restarts++
+	return pid, nil
+}
@@ -0,0 +1,368 @@
+package actor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	defaultPoolScalingInterval    = time.Second
+	defaultPoolScalingGrowAfter   = 2
+	defaultPoolScalingShrinkAfter = 5
+
+	defaultPoolScalingGrowBacklog   = 10
+	defaultPoolScalingShrinkBacklog = 1
+)
+
+// PoolScaling defines options for the automatic resizing of the pool.
+type PoolScaling struct {
+	// Enable enables autoscaling of the pool
+	Enable bool
+	// MinSize defines the minimal number of workers. Default 1.
+	MinSize int64
+	// MaxSize defines the maximal number of workers. Must be greater or equal to MinSize.
+	MaxSize int64
+	// Interval defines how often the pool evaluates its workers. Default 1 second.
+	Interval time.Duration
+	// GrowAfter defines the number of consecutive evaluations the policy must
+	// ask for growing before the pool adds workers. Default 2.
+	GrowAfter int
+	// ShrinkAfter defines the number of consecutive evaluations the policy must
+	// ask for shrinking before the pool removes workers. Default 5.
+	ShrinkAfter int
+	// Policy makes the scaling decisions. Default is PoolScalingDefault{}.
+	Policy PoolScalingPolicy
+}
+
+// PoolScalingPolicy interface
+type PoolScalingPolicy interface {
+	// Decide returns the number of workers to add (positive value) or
+	// to remove (negative value). Zero value keeps the pool size as is.
+	Decide(stats PoolStats) int64
+}
+
+// PoolStats contains the worker statistics collected by the pool during
+// the last evaluation interval.
+type PoolStats struct {
+	// Size number of workers in the pool
+	Size int64
+	// Backlog total number of messages in the mailboxes of workers
+	Backlog int64
+	// BacklogMax the largest number of messages in the mailbox of a single worker
+	BacklogMax int64
+	// Handled number of messages handled by workers during the interval
+	Handled uint64
+	// Latency average handling time of a message during the interval
+	Latency time.Duration
+	// Utilization fraction of the interval workers spent in the running state (0..1)
+	Utilization float64
+	// Interval duration of the evaluation interval
+	Interval time.Duration
+}
+
+// PoolScalingDefault the default scaling policy. It grows the pool if the average
+// backlog per worker or the average handling time reaches the "grow" threshold,
+// and shrinks it once both values fall to the "shrink" threshold. The gap
+// between thresholds prevents the pool from flapping.
+type PoolScalingDefault struct {
+	// GrowBacklog average number of queued messages per worker to grow the pool. Default 10.
+	GrowBacklog int64
+	// ShrinkBacklog average number of queued messages per worker to shrink the pool. Default 1.
+	ShrinkBacklog int64
+	// GrowLatency average handling time of a message to grow the pool. Zero value disables it.
+	GrowLatency time.Duration
+	// ShrinkLatency average handling time of a message to shrink the pool. Default GrowLatency/2.
+	ShrinkLatency time.Duration
+	// Step number of workers to add or remove at once. Default 1.
+	Step int64
+}
+
+func (d PoolScalingDefault) Decide(stats PoolStats) int64 {
+	if stats.Size < 1 {
+		return 1
+	}
+
+	grow := d.GrowBacklog
+	if grow < 1 {
+		grow = defaultPoolScalingGrowBacklog
+	}
+	shrink := d.ShrinkBacklog
+	if shrink < 1 {
+		shrink = defaultPoolScalingShrinkBacklog
+	}
+	step := d.Step
+	if step < 1 {
+		step = 1
+	}
+
+	backlog := stats.Backlog / stats.Size
+	if backlog >= grow {
+		return step
+	}
+	if d.GrowLatency > 0 && stats.Latency >= d.GrowLatency {
+		return step
+	}
+
+	if backlog > shrink {
+		return 0
+	}
+	if d.GrowLatency > 0 {
+		shrinkLatency := d.ShrinkLatency
+		if shrinkLatency <= 0 {
+			shrinkLatency = d.GrowLatency / 2
+		}
+		if stats.Latency > shrinkLatency {
+			return 0
+		}
+	}
+	return -step
+}
+
+type poolScaleTick struct{}
+
+type poolWorkerSample struct {
+	messagesIn  uint64
+	runningTime uint64
+}
+
+type poolScaler struct {
+	options PoolScaling
+
+	samples map[gen.PID]poolWorkerSample
+	last    time.Time
+	stats   PoolStats
+
+	growChecks   int
+	shrinkChecks int
+
+	grown    uint64
+	shrunk   uint64
+	decision string
+}
+
+func (p *Pool) initScaling() error {
+	scaling := p.options.Scaling
+	if scaling.Enable == false {
+		return nil
+	}
+
+	if scaling.MinSize < 1 {
+		scaling.MinSize = 1
+	}
+	if scaling.MaxSize < scaling.MinSize {
+		return fmt.Errorf("%w: MaxSize must be greater or equal to MinSize", ErrPoolScaling)
+	}
+	if scaling.Interval <= 0 {
+		scaling.Interval = defaultPoolScalingInterval
+	}
+	if scaling.GrowAfter < 1 {
+		scaling.GrowAfter = defaultPoolScalingGrowAfter
+	}
+	if scaling.ShrinkAfter < 1 {
+		scaling.ShrinkAfter = defaultPoolScalingShrinkAfter
+	}
+	if scaling.Policy == nil {
+		scaling.Policy = PoolScalingDefault{}
+	}
+
+	if p.options.PoolSize < scaling.MinSize {
+		p.options.PoolSize = scaling.MinSize
+	}
+	if p.options.PoolSize > scaling.MaxSize {
+		p.options.PoolSize = scaling.MaxSize
+	}
+
+	p.options.Scaling = scaling
+	p.scaler = &poolScaler{
+		options: scaling,
+		samples: make(map[gen.PID]poolWorkerSample),
+	}
+	return nil
+}
+
+func (p *Pool) scheduleScaling() {
+	if p.scaler == nil {
+		return
+	}
+	if _, err := p.SendAfter(p.PID(), poolScaleTick{}, p.scaler.options.Interval); err != nil {
+		p.Log().Error("unable to schedule pool scaling: %s", err)
+	}
+}
+
+func (p *Pool) isScaleTick(message *gen.MailboxMessage) bool {
+	if message.Type != gen.MailboxMessageTypeRegular || message.From != p.PID() {
+		return false
+	}
+	_, ok := message.Message.(poolScaleTick)
+	return ok
+}
+
+func (p *Pool) scale() {
+	s := p.scaler
+	defer p.scheduleScaling()
+
+	now := time.Now()
+	stats, idle := p.collectStats(now)
+	s.stats = stats
+	s.last = now
+
+	delta := s.options.Policy.Decide(stats)
+	switch {
+	case delta > 0:
+		s.shrinkChecks = 0
+		s.growChecks++
+		if s.growChecks < s.options.GrowAfter {
+			return
+		}
+		s.growChecks = 0
+		if stats.Size+delta > s.options.MaxSize {
+			delta = s.options.MaxSize - stats.Size
+		}
+
+	case delta < 0:
+		s.growChecks = 0
+		s.shrinkChecks++
+		if s.shrinkChecks < s.options.ShrinkAfter {
+			return
+		}
+		s.shrinkChecks = 0
+		if stats.Size+delta < s.options.MinSize {
+			delta = s.options.MinSize - stats.Size
+		}
+
+	default:
+		s.growChecks = 0
+		s.shrinkChecks = 0
+		return
+	}
+
+	if delta == 0 {
+		return
+	}
+
+	if delta > 0 {
+		n, err := p.AddWorkers(int(delta))
+		if err != nil {
+			p.Log().Error("unable to grow the pool: %s", err)
+			return
+		}
+		s.grown += uint64(delta)
+		s.decision = p.scaleDecision("grow", stats.Size, n, stats, now)
+		p.Log().Debug("pool scaling: %s", s.decision)
+		return
+	}
+
+	n := p.removeIdleWorkers(int(-delta), idle)
+	s.shrunk += uint64(stats.Size - n)
+	s.decision = p.scaleDecision("shrink", stats.Size, n, stats, now)
+	p.Log().Debug("pool scaling: %s", s.decision)
+}
+
+func (p *Pool) scaleDecision(action string, from, to int64, stats PoolStats, now time.Time) string {
+	return fmt.Sprintf("%s %d -> %d (backlog: %d, latency: %s, utilization: %.2f) at %s",
+		action, from, to, stats.Backlog, stats.Latency, stats.Utilization,
+		now.Format(time.RFC3339))
+}
+
+// collectStats gathers statistics of the workers and returns them along with
+// the set of workers that have nothing in their mailboxes.
+func (p *Pool) collectStats(now time.Time) (PoolStats, map[gen.PID]bool) {
+	var running uint64
+
+	s := p.scaler
+	stats := PoolStats{
+		Size:     p.pool.Len(),
+		Interval: s.options.Interval,
+	}
+	if s.last.IsZero() == false {
+		stats.Interval = now.Sub(s.last)
+	}
+
+	idle := make(map[gen.PID]bool)
+	samples := make(map[gen.PID]poolWorkerSample, stats.Size)
+	for i := int64(0); i < stats.Size; i++ {
+		v, _ := p.pool.Pop()
+		p.pool.Push(v)
+		pid := v.(gen.PID)
+
+		info, err := p.Node().ProcessInfo(pid)
+		if err != nil {
+			// will be restarted on forwarding
+			continue
+		}
+
+		queued := info.MailboxQueues.Main + info.MailboxQueues.System + info.MailboxQueues.Urgent
+		stats.Backlog += queued
+		if queued > stats.BacklogMax {
+			stats.BacklogMax = queued
+		}
+		if queued == 0 {
+			idle[pid] = true
+		}
+
+		sample := poolWorkerSample{
+			messagesIn:  info.MessagesIn,
+			runningTime: info.RunningTime,
+		}
+		samples[pid] = sample
+		prev, exist := s.samples[pid]
+		if exist == false {
+			continue
+		}
+		stats.Handled += sample.messagesIn - prev.messagesIn
+		running += sample.runningTime - prev.runningTime
+	}
+	s.samples = samples
+
+	if stats.Handled > 0 {
+		stats.Latency = time.Duration(running / stats.Handled)
+	}
+	if stats.Size > 0 && stats.Interval > 0 {
+		stats.Utilization = float64(running) / float64(stats.Interval.Nanoseconds()*stats.Size)
+	}
+	return stats, idle
+}
+
+// removeIdleWorkers stops up to n workers preferring those with empty mailbox.
+// Returns the number of workers left in the pool.
+func (p *Pool) removeIdleWorkers(n int, idle map[gen.PID]bool) int64 {
+	l := p.pool.Len()
+	for i := int64(0); i < l && n > 0; i++ {
+		v, _ := p.pool.Pop()
+		pid := v.(gen.PID)
+		if idle[pid] == false {
+			p.pool.Push(v)
+			continue
+		}
+		p.SendExit(pid, gen.TerminateReasonNormal)
+		p.workerRemoved(pid)
+		n--
+	}
+
+	if n > 0 {
+		// not enough idle workers. shrink anyway
+		p.RemoveWorkers(n)
+	}
+	return p.pool.Len()
+}
+
+func (p *Pool) inspectScaling(info map[string]string) {
+	s := p.scaler
+	if s == nil {
+		info["scaling"] = "disabled"
+		return
+	}
+	info["scaling"] = "enabled"
+	info["scaling_min_size"] = fmt.Sprintf("%d", s.options.MinSize)
+	info["scaling_max_size"] = fmt.Sprintf("%d", s.options.MaxSize)
+	info["scaling_interval"] = s.options.Interval.String()
+	info["scaling_workers"] = fmt.Sprintf("%d", p.pool.Len())
+	info["scaling_backlog"] = fmt.Sprintf("%d", s.stats.Backlog)
+	info["scaling_latency"] = s.stats.Latency.String()
+	info["scaling_utilization"] = fmt.Sprintf("%.2f", s.stats.Utilization)
+	info["scaling_grown"] = fmt.Sprintf("%d", s.grown)
+	info["scaling_shrunk"] = fmt.Sprintf("%d", s.shrunk)
+	info["scaling_last_decision"] = s.decision
+}
@@ -0,0 +1,144 @@
+package actor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+func Test_poolRingRebalance(t *testing.T) {
+	node := gen.Atom("node1@localhost")
+	slots := make(map[gen.PID]int)
+	for i := 0; i < 5; i++ {
+		slots[gen.PID{Node: node, ID: uint64(1000 + i)}] = i
+	}
+	ring := newPoolRing(slots, defaultPoolRoutingReplicas)
+
+	keys := 10000
+	before := make([]gen.PID, keys)
+	for i := 0; i < keys; i++ {
+		before[i], _ = ring.lookup(fmt.Sprintf("key%d", i))
+	}
+
+	// add worker. keys may only move to the new one
+	added := gen.PID{Node: node, ID: 2000}
+	slots[added] = 5
+	ring = newPoolRing(slots, defaultPoolRoutingReplicas)
+	moved := 0
+	for i := 0; i < keys; i++ {
+		pid, _ := ring.lookup(fmt.Sprintf("key%d", i))
+		if pid == before[i] {
+			continue
+		}
+		if pid != added {
+			t.Fatalf("key%d moved from %s to %s", i, before[i], pid)
+		}
+		moved++
+	}
+	if moved == 0 || moved > keys/3 {
+		t.Fatalf("unexpected number of moved keys: %d", moved)
+	}
+
+	// restarted worker takes over the keys of the terminated one
+	delete(slots, added)
+	restarted := gen.PID{Node: node, ID: 1000}
+	delete(slots, restarted)
+	replaced := gen.PID{Node: node, ID: 3000}
+	slots[replaced] = 0
+	ring = newPoolRing(slots, defaultPoolRoutingReplicas)
+	for i := 0; i < keys; i++ {
+		pid, _ := ring.lookup(fmt.Sprintf("key%d", i))
+		exp := before[i]
+		if exp == restarted {
+			exp = replaced
+		}
+		if pid != exp {
+			t.Fatalf("key%d routed to %s (exp: %s)", i, pid, exp)
+		}
+	}
+}
+
+func Test_poolRoutingLeastLoaded(t *testing.T) {
+	node := gen.Atom("node1@localhost")
+	var workers []gen.PID
+	for i := 0; i < 4; i++ {
+		workers = append(workers, gen.PID{Node: node, ID: uint64(1000 + i)})
+	}
+	mailbox := map[gen.PID]int64{
+		workers[0]: 5,
+		workers[1]: 3,
+		// workers[2] is terminated
+		workers[3]: 1,
+	}
+	mailboxLen := func(to any) (int64, error) {
+		l, found := mailbox[to.(gen.PID)]
+		if found == false {
+			return 0, gen.ErrProcessUnknown
+		}
+		return l, nil
+	}
+
+	if pid, found := leastLoaded(workers, mailboxLen); found == false || pid != workers[3] {
+		t.Fatalf("expected %s, got %s", workers[3], pid)
+	}
+
+	// terminated worker must be skipped even being the first one
+	mailbox[workers[3]] = 10
+	if pid, found := leastLoaded(workers[2:], mailboxLen); found == false || pid != workers[3] {
+		t.Fatalf("expected %s, got %s", workers[3], pid)
+	}
+
+	// no alive workers. round robin is used
+	if _, found := leastLoaded(workers[2:3], mailboxLen); found {
+		t.Fatal("must not be found")
+	}
+}
+
+func Test_poolRoutingRandom(t *testing.T) {
+	node := gen.Atom("node1@localhost")
+	p := &Pool{}
+	p.options.Routing.Strategy = PoolRoutingRandom
+	for i := 0; i < 4; i++ {
+		p.workers = append(p.workers, gen.PID{Node: node, ID: uint64(1000 + i)})
+	}
+
+	hits := make(map[gen.PID]int)
+	for i := 0; i < 1000; i++ {
+		pid, found := p.route(&gen.MailboxMessage{})
+		if found == false {
+			t.Fatal("must be routed")
+		}
+		hits[pid]++
+	}
+	for _, w := range p.workers {
+		if hits[w] == 0 {
+			t.Fatalf("worker %s has never been chosen: %v", w, hits)
+		}
+	}
+
+	p.workers = []gen.PID{}
+	if _, found := p.route(&gen.MailboxMessage{}); found {
+		t.Fatal("must not be routed with no workers")
+	}
+}
+
+func Test_poolScalingDefaultLatency(t *testing.T) {
+	d := PoolScalingDefault{GrowLatency: 100 * time.Millisecond}
+	cases := []struct {
+		latency time.Duration
+		decide  int64
+	}{
+		{150 * time.Millisecond, 1},
+		{70 * time.Millisecond, 0},
+		// ShrinkLatency is GrowLatency/2 by default
+		{40 * time.Millisecond, -1},
+	}
+	for _, c := range cases {
+		stats := PoolStats{Size: 2, Latency: c.latency}
+		if decide := d.Decide(stats); decide != c.decide {
+			t.Fatalf("latency %s: expected %d, got %d", c.latency, c.decide, decide)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package sharding
+
+import (
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	defaultName gen.Atom = "sharding_region"
+)
+
+// CreateApp creates application with the sharding region process
+// registered with the name Options.Name.
+func CreateApp(options Options) gen.ApplicationBehavior {
+	if options.Name == "" {
+		options.Name = defaultName
+	}
+	return &shardingApp{
+		options: options,
+	}
+}
+
+type shardingApp struct {
+	options Options
+}
+
+func (sa *shardingApp) Load(node gen.Node, args ...any) (gen.ApplicationSpec, error) {
+	return gen.ApplicationSpec{
+		Name:        sa.options.Name,
+		Description: "Sharding Region",
+		Depends: gen.ApplicationDepends{
+			Network: true,
+		},
+		Group: []gen.ApplicationMemberSpec{
+			{
+				Factory: Factory,
+				Name:    sa.options.Name,
+				Args:    []any{sa.options},
+			},
+		},
+		Mode: gen.ApplicationModeTransient,
+	}, nil
+}
+
+func (sa *shardingApp) Start(mode gen.ApplicationMode) {}
+func (sa *shardingApp) Terminate(reason error)         {}
@@ -0,0 +1,66 @@
+package sharding
+
+import (
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+// Options defines the sharding region
+type Options struct {
+	// Name registered name of the region process and the name of the application.
+	// Must be the same on all nodes of the cluster. Default "sharding_region".
+	Name gen.Atom
+	// Nodes list of the nodes sharing the entities. The local node is added
+	// automatically. Empty list makes the region serve all shards locally.
+	Nodes []gen.Atom
+	// Shards number of shards. Must be the same on all nodes. Default 100.
+	Shards int
+
+	// Factory spawns the entity process. The entity ID (string) is passed
+	// as the first argument to the Init callback.
+	Factory gen.ProcessFactory
+	// EntityOptions process options of the entity. LinkParent is always enabled.
+	EntityOptions gen.ProcessOptions
+
+	// PassivateAfter stops the entity if it has received no messages through
+	// the region for the given duration. Zero value disables passivation.
+	PassivateAfter time.Duration
+	// ReconnectInterval defines how often the region tries to reach
+	// the unavailable nodes. Default 5 seconds.
+	ReconnectInterval time.Duration
+}
+
+// MessageEntity envelope for the message sent to the entity
+type MessageEntity struct {
+	ID      string
+	Message any
+}
+
+// RequestEntity envelope for the request made to the entity
+type RequestEntity struct {
+	ID      string
+	Request any
+}
+
+// redirect is the response of the region to the local caller
+// if the entity belongs to the shard of the remote node
+type redirect struct {
+	node gen.Atom
+}
+
+func init() {
+	types := []any{
+		MessageEntity{},
+		RequestEntity{},
+	}
+
+	for _, t := range types {
+		err := sdf.RegisterTypeOf(t)
+		if err == nil || err == gen.ErrTaken {
+			continue
+		}
+		panic(err)
+	}
+}
@@ -0,0 +1,463 @@
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+const (
+	defaultShards            = 100
+	defaultReconnectInterval = 5 * time.Second
+)
+
+var (
+	errNoFactory = errors.New("sharding: entity factory is not defined")
+)
+
+// Factory creates the region process. Expects Options as the first argument.
+// Use CreateApp to start the region as an application.
+func Factory() gen.ProcessBehavior {
+	return &region{}
+}
+
+type entity struct {
+	id    string
+	pid   gen.PID
+	shard int
+	seen  time.Time
+}
+
+type regionTick struct{}
+
+type nodeUp struct {
+	name gen.Atom
+}
+
+type nodeFailed struct {
+	name gen.Atom
+}
+
+type region struct {
+	gen.Process
+
+	mailbox gen.ProcessMailbox
+	options Options
+
+	members    []gen.Atom
+	live       map[gen.Atom]bool
+	connecting map[gen.Atom]bool
+	nodes      []gen.Atom // live nodes (sorted)
+
+	entities map[string]*entity
+	pids     map[gen.PID]*entity
+
+	spawned      uint64
+	passivated   uint64
+	handedOff    uint64
+	routedRemote uint64
+}
+
+//
+// gen.ProcessBehavior implementation
+//
+
+func (r *region) ProcessInit(process gen.Process, args ...any) (rr error) {
+	r.Process = process
+	r.mailbox = process.Mailbox()
+
+	if lib.Recover() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				pc, fn, line, _ := runtime.Caller(2)
+				r.Log().Panic("sharding region initialization failed. Panic reason: %#v at %s[%s:%d]",
+					rcv, runtime.FuncForPC(pc).Name(), fn, line)
+				rr = gen.TerminateReasonPanic
+			}
+		}()
+	}
+
+	if len(args) == 0 {
+		return gen.ErrIncorrect
+	}
+	options, ok := args[0].(Options)
+	if ok == false {
+		return gen.ErrIncorrect
+	}
+	if options.Factory == nil {
+		return errNoFactory
+	}
+	if options.Name == "" {
+		options.Name = defaultName
+	}
+	if options.Shards < 1 {
+		options.Shards = defaultShards
+	}
+	if options.ReconnectInterval <= 0 {
+		options.ReconnectInterval = defaultReconnectInterval
+	}
+	options.EntityOptions.LinkParent = true
+	r.options = options
+
+	local := r.Node().Name()
+	r.live = map[gen.Atom]bool{local: true}
+	r.connecting = make(map[gen.Atom]bool)
+	r.members = []gen.Atom{local}
+	for _, name := range options.Nodes {
+		if name == local {
+			continue
+		}
+		r.members = append(r.members, name)
+	}
+	r.entities = make(map[string]*entity)
+	r.pids = make(map[gen.PID]*entity)
+	r.updateNodes()
+
+	interval := options.ReconnectInterval
+	if options.PassivateAfter > 0 && options.PassivateAfter/2 < interval {
+		interval = options.PassivateAfter / 2
+	}
+	if _, err := r.SendInterval(r.PID(), regionTick{}, interval); err != nil {
+		return err
+	}
+	// try to reach the other nodes right away
+	return r.Send(r.PID(), regionTick{})
+}
+
+func (r *region) ProcessRun() (rr error) {
+	var message *gen.MailboxMessage
+
+	if lib.Recover() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				pc, fn, line, _ := runtime.Caller(2)
+				r.Log().Panic("sharding region terminated. Panic reason: %#v at %s[%s:%d]",
+					rcv, runtime.FuncForPC(pc).Name(), fn, line)
+				rr = gen.TerminateReasonPanic
+			}
+		}()
+	}
+
+	for {
+		if r.State() != gen.ProcessStateRunning {
+			// process was killed by the node.
+			return gen.TerminateReasonKill
+		}
+
+		if message != nil {
+			gen.ReleaseMailboxMessage(message)
+			message = nil
+		}
+
+		msg, ok := r.mailbox.Urgent.Pop()
+		if ok == false {
+			msg, ok = r.mailbox.System.Pop()
+		}
+		if ok == false {
+			msg, ok = r.mailbox.Main.Pop()
+		}
+		if ok == false {
+			if _, ok := r.mailbox.Log.Pop(); ok {
+				panic("sharding region can not be a logger")
+			}
+			// no messages in the mailbox
+			return nil
+		}
+		message = msg.(*gen.MailboxMessage)
+
+		switch message.Type {
+		case gen.MailboxMessageTypeRegular:
+			if r.handleMessage(message) {
+				// forwarded to the entity. it shouldn't be "released" back to the pool
+				message = nil
+			}
+
+		case gen.MailboxMessageTypeRequest:
+			if r.handleRequest(message) {
+				message = nil
+			}
+
+		case gen.MailboxMessageTypeEvent:
+			r.Log().Warning("sharding region: unhandled event message from %s", message.From)
+
+		case gen.MailboxMessageTypeExit:
+			switch exit := message.Message.(type) {
+			case gen.MessageExitPID:
+				return fmt.Errorf("%s: %w", exit.PID, exit.Reason)
+
+			case gen.MessageExitProcessID:
+				return fmt.Errorf("%s: %w", exit.ProcessID, exit.Reason)
+
+			case gen.MessageExitAlias:
+				return fmt.Errorf("%s: %w", exit.Alias, exit.Reason)
+
+			case gen.MessageExitEvent:
+				return fmt.Errorf("%s: %w", exit.Event, exit.Reason)
+
+			case gen.MessageExitNode:
+				return fmt.Errorf("%s: %w", exit.Name, gen.ErrNoConnection)
+
+			default:
+				panic(fmt.Sprintf("unknown exit message: %#v", exit))
+			}
+
+		case gen.MailboxMessageTypeInspect:
+			r.SendResponse(message.From, message.Ref, r.inspect())
+		}
+	}
+}
+
+func (r *region) ProcessTerminate(reason error) {}
+
+//
+// routing
+//
+
+// handleMessage returns true if the message was forwarded to the entity
+func (r *region) handleMessage(message *gen.MailboxMessage) bool {
+	switch m := message.Message.(type) {
+	case MessageEntity:
+		shard, owner := r.owner(m.ID)
+		if owner != r.Node().Name() && message.From.Node == r.Node().Name() {
+			to := gen.ProcessID{Name: r.options.Name, Node: owner}
+			err := r.Send(to, m)
+			if err == nil {
+				r.routedRemote++
+				return false
+			}
+			r.Log().Warning("unable to route message for %q to %s: %s. handle it locally",
+				m.ID, owner, err)
+		}
+		message.Message = m.Message
+		if err := r.deliver(m.ID, shard, message); err != nil {
+			r.Log().Error("unable to deliver message from %s to %q: %s", message.From, m.ID, err)
+			return false
+		}
+		return true
+
+	case regionTick:
+		r.connectNodes()
+		r.passivate()
+
+	case nodeUp:
+		delete(r.connecting, m.name)
+		if r.live[m.name] {
+			break
+		}
+		// the previous monitor (if any) has been triggered already
+		r.DemonitorNode(m.name)
+		if err := r.MonitorNode(m.name); err != nil {
+			r.Log().Warning("unable to monitor node %s: %s", m.name, err)
+			break
+		}
+		r.Log().Info("node %s joined the sharding region", m.name)
+		r.live[m.name] = true
+		r.rebalance()
+
+	case nodeFailed:
+		delete(r.connecting, m.name)
+
+	case gen.MessageDownNode:
+		if r.live[m.Name] == false {
+			break
+		}
+		r.Log().Info("node %s left the sharding region", m.Name)
+		delete(r.live, m.Name)
+		r.rebalance()
+
+	case gen.MessageDownPID:
+		e, exist := r.pids[m.PID]
+		if exist == false {
+			break
+		}
+		r.remove(e)
+
+	default:
+		r.Log().Warning("sharding region: unhandled message from %s", message.From)
+	}
+	return false
+}
+
+// handleRequest returns true if the request was forwarded to the entity
+func (r *region) handleRequest(message *gen.MailboxMessage) bool {
+	m, ok := message.Message.(RequestEntity)
+	if ok == false {
+		r.SendResponseError(message.From, message.Ref, gen.ErrUnsupported)
+		return false
+	}
+
+	shard, owner := r.owner(m.ID)
+	if owner != r.Node().Name() && message.From.Node == r.Node().Name() {
+		// the caller makes the request to the owner directly (see Call)
+		r.SendResponse(message.From, message.Ref, redirect{node: owner})
+		r.routedRemote++
+		return false
+	}
+
+	message.Message = m.Request
+	if err := r.deliver(m.ID, shard, message); err != nil {
+		r.SendResponseError(message.From, message.Ref, err)
+		return false
+	}
+	return true
+}
+
+func (r *region) owner(id string) (int, gen.Atom) {
+	shard := shardOf(id, r.options.Shards)
+	return shard, ownerOf(shard, r.nodes)
+}
+
+func (r *region) deliver(id string, shard int, message *gen.MailboxMessage) error {
+	e, err := r.entity(id, shard)
+	if err != nil {
+		return err
+	}
+
+	err = r.Forward(e.pid, message, gen.MessagePriorityNormal)
+	if err == gen.ErrProcessUnknown || err == gen.ErrProcessTerminated {
+		// terminated, but the down message hasn't been handled yet
+		r.remove(e)
+		if e, err = r.entity(id, shard); err != nil {
+			return err
+		}
+		err = r.Forward(e.pid, message, gen.MessagePriorityNormal)
+	}
+	if err != nil {
+		return err
+	}
+	e.seen = time.Now()
+	return nil
+}
+
+// entity returns the entity spawning it if needed
+func (r *region) entity(id string, shard int) (*entity, error) {
+	if e, exist := r.entities[id]; exist {
+		return e, nil
+	}
+
+	pid, err := r.Spawn(r.options.Factory, r.options.EntityOptions, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.MonitorPID(pid); err != nil {
+		r.SendExit(pid, gen.TerminateReasonNormal)
+		return nil, err
+	}
+
+	e := &entity{
+		id:    id,
+		pid:   pid,
+		shard: shard,
+		seen:  time.Now(),
+	}
+	r.entities[id] = e
+	r.pids[pid] = e
+	r.spawned++
+	return e, nil
+}
+
+func (r *region) remove(e *entity) {
+	delete(r.pids, e.pid)
+	if r.entities[e.id] == e {
+		delete(r.entities, e.id)
+	}
+}
+
+// stop terminates the entity. The next message for this entity spawns a new one.
+func (r *region) stop(e *entity) {
+	r.remove(e)
+	r.DemonitorPID(e.pid)
+	r.SendExit(e.pid, gen.TerminateReasonNormal)
+}
+
+func (r *region) passivate() {
+	if r.options.PassivateAfter <= 0 {
+		return
+	}
+	now := time.Now()
+	for _, e := range r.entities {
+		if now.Sub(e.seen) < r.options.PassivateAfter {
+			continue
+		}
+		r.stop(e)
+		r.passivated++
+	}
+}
+
+//
+// membership
+//
+
+func (r *region) updateNodes() {
+	r.nodes = make([]gen.Atom, 0, len(r.live))
+	for name := range r.live {
+		r.nodes = append(r.nodes, name)
+	}
+	sort.Slice(r.nodes, func(i, j int) bool {
+		return r.nodes[i] < r.nodes[j]
+	})
+}
+
+// rebalance stops the local entities whose shards have been moved to the other node
+func (r *region) rebalance() {
+	r.updateNodes()
+	local := r.Node().Name()
+	for _, e := range r.entities {
+		if ownerOf(e.shard, r.nodes) == local {
+			continue
+		}
+		r.stop(e)
+		r.handedOff++
+	}
+}
+
+func (r *region) connectNodes() {
+	node := r.Node()
+	pid := r.PID()
+	for _, name := range r.members {
+		if r.live[name] || r.connecting[name] {
+			continue
+		}
+		r.connecting[name] = true
+		go func(name gen.Atom) {
+			// making connection may take a while, so do it in the background
+			var message any = nodeUp{name: name}
+			if _, err := node.Network().GetNode(name); err != nil {
+				message = nodeFailed{name: name}
+			}
+			node.Send(pid, message)
+		}(name)
+	}
+}
+
+func (r *region) inspect() map[string]string {
+	local := r.Node().Name()
+	owned := 0
+	for shard := 0; shard < r.options.Shards; shard++ {
+		if ownerOf(shard, r.nodes) == local {
+			owned++
+		}
+	}
+	nodes := make([]string, len(r.nodes))
+	for i := range r.nodes {
+		nodes[i] = string(r.nodes[i])
+	}
+	return map[string]string{
+		"shards":          fmt.Sprintf("%d", r.options.Shards),
+		"shards_local":    fmt.Sprintf("%d", owned),
+		"nodes":           fmt.Sprintf("%d", len(r.members)),
+		"nodes_live":      strings.Join(nodes, ", "),
+		"entities":        fmt.Sprintf("%d", len(r.entities)),
+		"spawned":         fmt.Sprintf("%d", r.spawned),
+		"passivated":      fmt.Sprintf("%d", r.passivated),
+		"handed_off":      fmt.Sprintf("%d", r.handedOff),
+		"routed_remote":   fmt.Sprintf("%d", r.routedRemote),
+		"passivate_after": r.options.PassivateAfter.String(),
+	}
+}
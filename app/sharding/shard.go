@@ -0,0 +1,44 @@
+package sharding
+
+import (
+	"hash/fnv"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+// shardOf returns the shard number of the entity
+func shardOf(id string, shards int) int {
+	return int(mix(hash(id)) % uint64(shards))
+}
+
+// ownerOf returns the node owning the shard. It uses rendezvous hashing,
+// so adding or removing a node moves only the shards of this node.
+func ownerOf(shard int, nodes []gen.Atom) gen.Atom {
+	var owner gen.Atom
+	var max uint64
+	for _, node := range nodes {
+		h := mix(hash(string(node)) ^ mix(uint64(shard)+1))
+		if owner == "" || h > max || (h == max && node < owner) {
+			owner = node
+			max = h
+		}
+	}
+	return owner
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix is the finalizer of murmur3. FNV alone gives poor distribution
+// for the similar strings (like node names).
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,58 @@
+package sharding
+
+import (
+	"testing"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+func Test_ownerRebalance(t *testing.T) {
+	shards := 1000
+	nodes := []gen.Atom{"node1@localhost", "node2@localhost", "node3@localhost"}
+
+	before := make([]gen.Atom, shards)
+	count := make(map[gen.Atom]int)
+	for i := 0; i < shards; i++ {
+		before[i] = ownerOf(i, nodes)
+		count[before[i]]++
+	}
+	for _, node := range nodes {
+		if count[node] < shards/6 {
+			t.Fatalf("unbalanced shards: %v", count)
+		}
+	}
+
+	// joined node takes over shards from the others only
+	joined := gen.Atom("node4@localhost")
+	moved := 0
+	for i := 0; i < shards; i++ {
+		owner := ownerOf(i, append(nodes, joined))
+		if owner == before[i] {
+			continue
+		}
+		if owner != joined {
+			t.Fatalf("shard %d moved from %s to %s", i, before[i], owner)
+		}
+		moved++
+	}
+	if moved == 0 || moved > shards/2 {
+		t.Fatalf("unexpected number of moved shards: %d", moved)
+	}
+
+	// shards of the left node are distributed across the rest
+	left := nodes[1]
+	rest := []gen.Atom{nodes[2], nodes[0]}
+	for i := 0; i < shards; i++ {
+		owner := ownerOf(i, rest)
+		if before[i] != left && owner != before[i] {
+			t.Fatalf("shard %d moved from %s to %s", i, before[i], owner)
+		}
+		if owner == left {
+			t.Fatalf("shard %d is owned by the left node", i)
+		}
+	}
+
+	if shardOf("entity", 100) != shardOf("entity", 100) {
+		t.Fatal("shard must be the same for the same id")
+	}
+}
@@ -0,0 +1,42 @@
+// Package sharding implements entities distributed across the cluster.
+// Every entity is identified by its ID. The ID is mapped to the shard and
+// the shard is mapped to the node using rendezvous hashing over the
+// available nodes. The entity process is spawned on the owner node on the
+// first message and stopped once it becomes idle (see Options.PassivateAfter)
+// or its shard moves to another node.
+//
+// Use CreateApp to start the region on every node of the cluster, and
+// Send/Call to communicate with the entities.
+package sharding
+
+import (
+	"github.com/sllt/sparrow/gen"
+)
+
+// Send sends the message to the entity with the given ID through the local region.
+// The entity receives it with the "from" value of the sender if the entity
+// is local, otherwise the "from" value is the region process of the sender's node.
+func Send(process gen.Process, region gen.Atom, id string, message any) error {
+	return process.Send(region, MessageEntity{ID: id, Message: message})
+}
+
+// Call makes a request to the entity with the given ID. If the entity belongs
+// to the remote node, the request is made to the region on that node.
+func Call(process gen.Process, region gen.Atom, id string, request any) (any, error) {
+	return CallWithTimeout(process, region, id, request, gen.DefaultRequestTimeout)
+}
+
+// CallWithTimeout makes a request to the entity with the given timeout (in seconds)
+func CallWithTimeout(process gen.Process, region gen.Atom, id string, request any, timeout int) (any, error) {
+	req := RequestEntity{ID: id, Request: request}
+	result, err := process.CallWithTimeout(region, req, timeout)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := result.(redirect)
+	if ok == false {
+		return result, nil
+	}
+	// the remote region always serves the request locally
+	return process.CallWithTimeout(gen.ProcessID{Name: region, Node: r.node}, req, timeout)
+}
@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cron schedule. each field is a bitset of the allowed values
+type cron struct {
+	second uint64
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// day of month/week matching follows the standard cron rule: if both
+	// fields are restricted the day matches if any of them matches.
+	domAny bool
+	dowAny bool
+}
+
+type cronBounds struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronSeconds = cronBounds{0, 59, nil}
+	cronMinutes = cronBounds{0, 59, nil}
+	cronHours   = cronBounds{0, 23, nil}
+	cronDom     = cronBounds{1, 31, nil}
+	cronMonths  = cronBounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronBounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// parseCron parses the cron expression. It supports the standard 5-field format
+// (minute, hour, day of month, month, day of week), the 6-field format with the
+// leading seconds field and the descriptors @yearly, @monthly, @weekly, @daily,
+// @hourly.
+func parseCron(expr string) (*cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, found := cronDescriptors[strings.ToLower(expr)]; found {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("incorrect cron expression %q: expected 5 or 6 fields", expr)
+	}
+
+	c := &cron{}
+	var err error
+	if c.second, err = parseCronField(fields[0], cronSeconds); err != nil {
+		return nil, err
+	}
+	if c.minute, err = parseCronField(fields[1], cronMinutes); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[2], cronHours); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[3], cronDom); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[4], cronMonths); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[5], cronDow); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday as well
+	if c.dow&(1<<7) > 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[3] == "*" || fields[3] == "?"
+	c.dowAny = fields[5] == "*" || fields[5] == "?"
+	return c, nil
+}
+
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		b, err := parseCronRange(part, bounds)
+		if err != nil {
+			return 0, fmt.Errorf("incorrect cron field %q: %w", field, err)
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parseCronRange(part string, bounds cronBounds) (uint64, error) {
+	var err error
+	step := 1
+	if i := strings.Index(part, "/"); i > -1 {
+		if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+			return 0, fmt.Errorf("incorrect step %q", part[i+1:])
+		}
+		part = part[:i]
+	}
+
+	start, end := bounds.min, bounds.max
+	switch {
+	case part == "*" || part == "?":
+	case strings.Contains(part, "-"):
+		r := strings.SplitN(part, "-", 2)
+		if start, err = parseCronValue(r[0], bounds); err != nil {
+			return 0, err
+		}
+		if end, err = parseCronValue(r[1], bounds); err != nil {
+			return 0, err
+		}
+	default:
+		if start, err = parseCronValue(part, bounds); err != nil {
+			return 0, err
+		}
+		if step == 1 {
+			end = start
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("incorrect range %d-%d", start, end)
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, bounds cronBounds) (int, error) {
+	if v, found := bounds.names[strings.ToLower(s)]; found {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect value %q", s)
+	}
+	if v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("value %d is out of range [%d-%d]", v, bounds.min, bounds.max)
+	}
+	return v, nil
+}
+
+// next returns the closest time after the given one matching the schedule.
+// Returns zero value if there is no such time within the next 5 years.
+func (c *cron) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	added := false
+	limit := t.Year() + 5
+
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for c.month&(1<<uint(t.Month())) == 0 {
+		if added == false {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for c.matchDay(t) == false {
+		if added == false {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		if added == false {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		if added == false {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.second&(1<<uint(t.Second())) == 0 {
+		if added == false {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) > 0
+	dow := c.dow&(1<<uint(t.Weekday())) > 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
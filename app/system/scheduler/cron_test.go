@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	utc := time.UTC
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	cases := []struct {
+		expr string
+		from time.Time
+		exp  time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 0, 30, 0, utc), time.Date(2024, 1, 1, 10, 1, 0, 0, utc)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 7, 0, 0, utc), time.Date(2024, 1, 1, 10, 15, 0, 0, utc)},
+		{"30 2 * * *", time.Date(2024, 1, 1, 10, 0, 0, 0, utc), time.Date(2024, 1, 2, 2, 30, 0, 0, utc)},
+		{"0 9 * * mon-fri", time.Date(2024, 1, 5, 10, 0, 0, 0, utc), time.Date(2024, 1, 8, 9, 0, 0, 0, utc)},
+		{"0 0 29 feb *", time.Date(2024, 3, 1, 0, 0, 0, 0, utc), time.Date(2028, 2, 29, 0, 0, 0, 0, utc)},
+		{"@monthly", time.Date(2024, 1, 31, 23, 0, 0, 0, utc), time.Date(2024, 2, 1, 0, 0, 0, 0, utc)},
+		{"*/10 * * * * *", time.Date(2024, 1, 1, 10, 0, 5, 0, utc), time.Date(2024, 1, 1, 10, 0, 10, 0, utc)},
+		{"0 0 1,15 * *", time.Date(2024, 1, 2, 0, 0, 0, 0, utc), time.Date(2024, 1, 15, 0, 0, 0, 0, utc)},
+		// standard cron rule: restricted day of month and day of week match either
+		{"0 0 13 * fri", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), time.Date(2024, 1, 5, 0, 0, 0, 0, utc)},
+		// time zone
+		{"0 9 * * *", time.Date(2024, 6, 1, 10, 0, 0, 0, berlin), time.Date(2024, 6, 2, 9, 0, 0, 0, berlin)},
+	}
+
+	for _, c := range cases {
+		cr, err := parseCron(c.expr)
+		if err != nil {
+			t.Fatalf("%q: %s", c.expr, err)
+		}
+		next := cr.next(c.from)
+		if next.Equal(c.exp) == false {
+			t.Fatalf("%q: next after %s is %s (exp: %s)", c.expr, c.from, next, c.exp)
+		}
+	}
+}
+
+func TestCronParseError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Fatalf("%q: expected error", expr)
+		}
+	}
+}
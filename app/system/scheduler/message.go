@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+// JobPolicy defines what to do if the job is still running at the time
+// of the next run. It is applied to the jobs spawning a process only.
+// The job is running as long as the spawned process is alive.
+type JobPolicy int
+
+func (p JobPolicy) String() string {
+	switch p {
+	case JobPolicySkip:
+		return "skip"
+	case JobPolicyOverlap:
+		return "overlap"
+	}
+	return "unknown job policy"
+}
+
+func (p JobPolicy) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + p.String() + "\""), nil
+}
+
+const (
+	// JobPolicySkip skips the run if the previous one is still running. Default.
+	JobPolicySkip JobPolicy = 0
+	// JobPolicyOverlap starts the job regardless of the previous runs.
+	JobPolicyOverlap JobPolicy = 1
+)
+
+// JobSpec defines the job
+type JobSpec struct {
+	// Name unique name of the job
+	Name gen.Atom
+
+	// Cron defines the schedule in cron format ("*/5 * * * *"). The leading
+	// seconds field is optional. Descriptors @yearly, @monthly, @weekly, @daily
+	// and @hourly are supported as well.
+	Cron string
+	// Interval defines fixed schedule. It is used if Cron is empty. The runs
+	// are kept at the fixed rate, the missed ones are skipped.
+	Interval time.Duration
+	// Location time zone name ("Europe/Berlin") the cron schedule is evaluated in.
+	// Default is the local time zone.
+	Location string
+	// Policy defines what to do if the job is still running. Default JobPolicySkip.
+	Policy JobPolicy
+	// Disabled adds the job in the disabled state
+	Disabled bool
+
+	// To and Message define the message the scheduler sends on every run
+	To      any
+	Message any
+
+	// Factory name of the registered factory (see RequestRegisterFactory). If defined,
+	// the scheduler spawns a new process on every run instead of sending a message.
+	Factory gen.Atom
+	Options gen.ProcessOptions
+	Args    []any
+}
+
+// JobInfo
+type JobInfo struct {
+	Spec JobSpec
+	// Next time of the upcoming run. Zero value for the disabled job.
+	Next    time.Time
+	LastRun time.Time
+	Runs    uint64
+	// Skipped number of runs skipped due to JobPolicySkip
+	Skipped uint64
+	Failed  uint64
+	// Running number of alive processes spawned by this job
+	Running   int
+	LastError string
+}
+
+// RequestRegisterFactory registers the factory the jobs can refer to by name.
+// Response: true or error.
+type RequestRegisterFactory struct {
+	Name    gen.Atom
+	Factory gen.ProcessFactory
+}
+
+// RequestAddJob adds a new job. Response: true or error.
+type RequestAddJob struct {
+	Spec JobSpec
+}
+
+// RequestRemoveJob removes the job. Response: true or error.
+type RequestRemoveJob struct {
+	Name gen.Atom
+}
+
+// RequestEnableJob enables the disabled job. Response: true or error.
+type RequestEnableJob struct {
+	Name gen.Atom
+}
+
+// RequestDisableJob disables the job. Running processes of this job are not
+// affected. Response: true or error.
+type RequestDisableJob struct {
+	Name gen.Atom
+}
+
+// RequestJobList returns the list of jobs. Response: []JobInfo
+type RequestJobList struct{}
+
+// RequestJobInfo returns the job information. Response: JobInfo or error.
+type RequestJobInfo struct {
+	Name gen.Atom
+}
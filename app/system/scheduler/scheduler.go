@@ -0,0 +1,325 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	Name gen.Atom = "system_scheduler"
+)
+
+func Factory() gen.ProcessBehavior {
+	return &scheduler{}
+}
+
+type scheduler struct {
+	actor.Actor
+
+	factories map[gen.Atom]gen.ProcessFactory
+	jobs      map[gen.Atom]*job
+	running   map[gen.PID]gen.Atom
+	id        uint64
+}
+
+type job struct {
+	// id makes the fired timers of the removed job distinguishable from
+	// the ones belonging to the job added again with the same name
+	id       uint64
+	spec     JobSpec
+	cron     *cron
+	location *time.Location
+
+	next    time.Time
+	lastRun time.Time
+	runs    uint64
+	skipped uint64
+	failed  uint64
+	lastErr string
+	running map[gen.PID]bool
+}
+
+type fire struct {
+	name gen.Atom
+	id   uint64
+}
+
+func (s *scheduler) Init(args ...any) error {
+	s.factories = make(map[gen.Atom]gen.ProcessFactory)
+	s.jobs = make(map[gen.Atom]*job)
+	s.running = make(map[gen.PID]gen.Atom)
+	s.Log().Debug("%s started", s.Name())
+	return nil
+}
+
+func (s *scheduler) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case fire:
+		j, exist := s.jobs[m.name]
+		if exist == false || j.id != m.id {
+			// removed
+			return nil
+		}
+		s.run(j)
+		s.schedule(j)
+
+	case gen.MessageDownPID:
+		name, exist := s.running[m.PID]
+		if exist == false {
+			return nil
+		}
+		delete(s.running, m.PID)
+		if j, exist := s.jobs[name]; exist {
+			delete(j.running, m.PID)
+		}
+
+	default:
+		s.Log().Trace("received unknown message: %#v", message)
+	}
+	return nil
+}
+
+func (s *scheduler) HandleCall(from gen.PID, ref gen.Ref, request any) (any, error) {
+	switch r := request.(type) {
+	case RequestRegisterFactory:
+		if r.Name == "" || r.Factory == nil {
+			return gen.ErrIncorrect, nil
+		}
+		s.factories[r.Name] = r.Factory
+		return true, nil
+
+	case RequestAddJob:
+		if err := s.add(r.Spec); err != nil {
+			return err, nil
+		}
+		return true, nil
+
+	case RequestRemoveJob:
+		j, exist := s.jobs[r.Name]
+		if exist == false {
+			return gen.ErrUnknown, nil
+		}
+		s.CancelTimer(timerName(j))
+		delete(s.jobs, r.Name)
+		for pid := range j.running {
+			// keep monitoring to clean up s.running
+			s.running[pid] = ""
+		}
+		return true, nil
+
+	case RequestEnableJob:
+		j, exist := s.jobs[r.Name]
+		if exist == false {
+			return gen.ErrUnknown, nil
+		}
+		if j.spec.Disabled == false {
+			return true, nil
+		}
+		j.spec.Disabled = false
+		s.schedule(j)
+		return true, nil
+
+	case RequestDisableJob:
+		j, exist := s.jobs[r.Name]
+		if exist == false {
+			return gen.ErrUnknown, nil
+		}
+		j.spec.Disabled = true
+		j.next = time.Time{}
+		s.CancelTimer(timerName(j))
+		return true, nil
+
+	case RequestJobList:
+		list := make([]JobInfo, 0, len(s.jobs))
+		for _, j := range s.jobs {
+			list = append(list, j.info())
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Spec.Name < list[j].Spec.Name
+		})
+		return list, nil
+
+	case RequestJobInfo:
+		j, exist := s.jobs[r.Name]
+		if exist == false {
+			return gen.ErrUnknown, nil
+		}
+		return j.info(), nil
+	}
+
+	s.Log().Trace("received unknown request: %#v", request)
+	return gen.ErrUnsupported, nil
+}
+
+func (s *scheduler) HandleInspect(from gen.PID, item ...string) map[string]string {
+	info := map[string]string{
+		"jobs":      fmt.Sprintf("%d", len(s.jobs)),
+		"factories": fmt.Sprintf("%d", len(s.factories)),
+	}
+	for name, j := range s.jobs {
+		next := "disabled"
+		if j.next.IsZero() == false {
+			next = j.next.Format(time.RFC3339)
+		}
+		info[string(name)] = fmt.Sprintf("next: %s, runs: %d, skipped: %d, failed: %d, running: %d",
+			next, j.runs, j.skipped, j.failed, len(j.running))
+	}
+	return info
+}
+
+func (s *scheduler) Terminate(reason error) {
+	s.Log().Debug("%s terminated with reason: %s", s.Name(), reason)
+}
+
+// internals
+
+func (s *scheduler) add(spec JobSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("%w: job name is empty", gen.ErrIncorrect)
+	}
+	if _, exist := s.jobs[spec.Name]; exist {
+		return gen.ErrTaken
+	}
+
+	s.id++
+	j := &job{
+		id:       s.id,
+		spec:     spec,
+		location: time.Local,
+		running:  make(map[gen.PID]bool),
+	}
+
+	if spec.Cron != "" {
+		c, err := parseCron(spec.Cron)
+		if err != nil {
+			return fmt.Errorf("%w: %s", gen.ErrIncorrect, err)
+		}
+		j.cron = c
+	} else if spec.Interval <= 0 {
+		return fmt.Errorf("%w: job must have Cron or Interval defined", gen.ErrIncorrect)
+	}
+
+	if spec.Location != "" {
+		loc, err := time.LoadLocation(spec.Location)
+		if err != nil {
+			return fmt.Errorf("%w: %s", gen.ErrIncorrect, err)
+		}
+		j.location = loc
+	}
+
+	if spec.Factory != "" {
+		if _, exist := s.factories[spec.Factory]; exist == false {
+			return fmt.Errorf("%w: unknown factory %s", gen.ErrIncorrect, spec.Factory)
+		}
+	} else if spec.To == nil {
+		return fmt.Errorf("%w: job must have To or Factory defined", gen.ErrIncorrect)
+	}
+
+	s.jobs[spec.Name] = j
+	s.schedule(j)
+	return nil
+}
+
+func (s *scheduler) schedule(j *job) {
+	if j.spec.Disabled {
+		return
+	}
+
+	now := time.Now().In(j.location)
+	if j.cron != nil {
+		j.next = j.cron.next(now)
+	} else {
+		j.next = nextInterval(j.next, now, j.spec.Interval)
+	}
+	if j.next.IsZero() {
+		s.Log().Warning("job %s has no upcoming runs", j.spec.Name)
+		return
+	}
+
+	f := fire{name: j.spec.Name, id: j.id}
+	if _, err := s.SendAfterNamed(timerName(j), s.PID(), f, time.Until(j.next)); err != nil {
+		s.Log().Error("unable to schedule job %s: %s", j.spec.Name, err)
+	}
+}
+
+// nextInterval returns the next run of the interval job. The runs are kept at
+// the fixed rate from the previous one, so the delays of the timer don't add up.
+// The missed runs are skipped.
+func nextInterval(prev time.Time, now time.Time, interval time.Duration) time.Time {
+	if prev.IsZero() {
+		// the first run (or enabled again)
+		return now.Add(interval)
+	}
+	next := prev.Add(interval)
+	if next.After(now) {
+		return next
+	}
+	missed := now.Sub(next)/interval + 1
+	return next.Add(missed * interval)
+}
+
+func (s *scheduler) run(j *job) {
+	j.lastRun = time.Now()
+
+	if j.spec.Factory == "" {
+		if err := s.Send(j.spec.To, j.spec.Message); err != nil {
+			j.failed++
+			j.lastErr = err.Error()
+			s.Log().Error("job %s: unable to send message to %s: %s", j.spec.Name, j.spec.To, err)
+			return
+		}
+		j.runs++
+		return
+	}
+
+	if len(j.running) > 0 && j.spec.Policy == JobPolicySkip {
+		j.skipped++
+		s.Log().Debug("job %s is still running. skipped", j.spec.Name)
+		return
+	}
+
+	factory, exist := s.factories[j.spec.Factory]
+	if exist == false {
+		j.failed++
+		j.lastErr = fmt.Sprintf("unknown factory %s", j.spec.Factory)
+		return
+	}
+
+	// spawned by the node, so the process doesn't belong to the system application
+	pid, err := s.Node().Spawn(factory, j.spec.Options, j.spec.Args...)
+	if err != nil {
+		j.failed++
+		j.lastErr = err.Error()
+		s.Log().Error("job %s: unable to spawn process: %s", j.spec.Name, err)
+		return
+	}
+	j.runs++
+
+	if err := s.MonitorPID(pid); err != nil {
+		// has already terminated
+		return
+	}
+	j.running[pid] = true
+	s.running[pid] = j.spec.Name
+}
+
+func (j *job) info() JobInfo {
+	return JobInfo{
+		Spec:      j.spec,
+		Next:      j.next,
+		LastRun:   j.lastRun,
+		Runs:      j.runs,
+		Skipped:   j.skipped,
+		Failed:    j.failed,
+		Running:   len(j.running),
+		LastError: j.lastErr,
+	}
+}
+
+func timerName(j *job) string {
+	return "job:" + string(j.spec.Name)
+}
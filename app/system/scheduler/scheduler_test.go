@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextInterval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	interval := 10 * time.Second
+
+	cases := []struct {
+		prev time.Time
+		now  time.Time
+		exp  time.Time
+	}{
+		// first run
+		{time.Time{}, start, start.Add(interval)},
+		// the timer fired late, no drift
+		{start, start.Add(50 * time.Millisecond), start.Add(interval)},
+		{start.Add(interval), start.Add(interval + 20*time.Millisecond), start.Add(2 * interval)},
+		// the missed runs are skipped
+		{start, start.Add(interval), start.Add(2 * interval)},
+		{start, start.Add(35 * time.Second), start.Add(40 * time.Second)},
+	}
+	for _, c := range cases {
+		if next := nextInterval(c.prev, c.now, interval); next.Equal(c.exp) == false {
+			t.Fatalf("next after %s (now %s) is %s (exp: %s)", c.prev, c.now, next, c.exp)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package system
 import (
 	"github.com/sllt/sparrow/actor"
 	"github.com/sllt/sparrow/app/system/inspect"
+	"github.com/sllt/sparrow/app/system/scheduler"
 	"github.com/sllt/sparrow/gen"
 )
 
@@ -27,6 +28,10 @@ func (s *sup) Init(args ...any) (actor.SupervisorSpec, error) {
 				Factory: inspect.Factory,
 				Name:    inspect.Name,
 			},
+			{
+				Factory: scheduler.Factory,
+				Name:    scheduler.Name,
+			},
 		},
 	}
 	spec.Restart.Strategy = actor.SupervisorStrategyPermanent
@@ -2,6 +2,8 @@ package gen
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"strings"
 	"sync"
 )
 
@@ -41,3 +43,23 @@ func (cm *certManager) GetCertificate() tls.Certificate {
 	defer cm.RUnlock()
 	return *cm.cert
 }
+
+// CertificateMatchNode returns true if the certificate is issued for the given
+// node name. The name is expected in the Subject Alternative Name of the
+// certificate, either as an email address (node@host) or as a DNS name.
+func CertificateMatchNode(name Atom, cert *x509.Certificate) bool {
+	if cert == nil || name == "" {
+		return false
+	}
+	for _, email := range cert.EmailAddresses {
+		if strings.EqualFold(email, string(name)) {
+			return true
+		}
+	}
+	for _, dns := range cert.DNSNames {
+		if strings.EqualFold(dns, string(name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -53,6 +53,13 @@ type Core interface {
 
 	RouteNodeDown(node Atom, reason error)
 
+	// NetworkFaults returns the faults to be injected into the messages sent to
+	// the given peer. Returns false if the fault injection is disabled for it.
+	NetworkFaults(peer Atom) (NetworkFaults, bool)
+	// NetworkBandwidthLimit returns the limit of the outgoing traffic (bytes
+	// per second) to the given peer. Zero means no limit.
+	NetworkBandwidthLimit(peer Atom) int
+
 	MakeRef() Ref
 	Name() Atom
 	Creation() int64
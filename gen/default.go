@@ -11,6 +11,10 @@ var (
 	DefaultCompressionLevel     CompressionLevel = CompressionDefault
 	DefaultCompressionThreshold int              = 1024
 
+	// DefaultAdaptiveCompressionBandwidth the network bandwidth (bytes per second)
+	// the adaptive compression uses to estimate the time of sending
+	DefaultAdaptiveCompressionBandwidth int = 125_000_000 // 1Gbit
+
 	DefaultLogFilter = []LogLevel{
 		LogLevelTrace,
 		LogLevelDebug,
@@ -24,6 +28,29 @@ var (
 	DefaultTCPBufferSize   int           = 65535
 	DefaultPort            uint16        = 11144
 
+	// DefaultCookieExpiry how long the previous cookie is accepted after rotation
+	DefaultCookieExpiry time.Duration = time.Hour
+
+	// DefaultHeartbeatInterval the interval between the heartbeats (see NetworkHeartbeat)
+	DefaultHeartbeatInterval time.Duration = time.Second
+	// DefaultHeartbeatMaxMissed the number of missed heartbeats to declare the node down
+	DefaultHeartbeatMaxMissed int = 3
+	// DefaultHeartbeatPhiWindow the number of the intervals the phi-accrual
+	// failure detector estimates the distribution with
+	DefaultHeartbeatPhiWindow int = 100
+
+	// DefaultPoolScalingInterval the interval between the throughput checks
+	// of the connection pool (see NetworkPoolScaling)
+	DefaultPoolScalingInterval time.Duration = 5 * time.Second
+	// DefaultPoolScalingMaxSize the maximal size of the connection pool
+	DefaultPoolScalingMaxSize int = 8
+	// DefaultPoolScalingGrow the throughput per link to grow the pool
+	DefaultPoolScalingGrow int = 50_000_000
+
+	// DefaultSendQueueLimit the number of the queued messages of the pool link
+	// per priority (see NetworkOptions.SendQueueLimit)
+	DefaultSendQueueLimit int = 8192
+
 	DefaultNetworkFlags = NetworkFlags{
 		Enable:                       true,
 		EnableRemoteSpawn:            true,
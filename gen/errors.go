@@ -39,10 +39,11 @@ var (
 
 	ErrAtomTooLong = errors.New("too long Atom (max: 255)")
 
-	ErrTimeout     = errors.New("timed out")
-	ErrUnsupported = errors.New("not supported")
-	ErrUnknown     = errors.New("unknown")
-	ErrNotAllowed  = errors.New("not allowed")
+	ErrTimeout      = errors.New("timed out")
+	ErrUnsupported  = errors.New("not supported")
+	ErrUnknown      = errors.New("unknown")
+	ErrNotAllowed   = errors.New("not allowed")
+	ErrAccessDenied = errors.New("access denied")
 
 	ErrIncorrect       = errors.New("incorrect value or argument")
 	ErrMalformed       = errors.New("malformed value")
@@ -53,6 +54,8 @@ var (
 	ErrNetworkStopped = errors.New("network stack is stopped")
 	ErrNoConnection   = errors.New("no connection")
 	ErrNoRoute        = errors.New("no route")
+	ErrNoHeartbeat    = errors.New("no heartbeat")
+	ErrDigestMismatch = errors.New("handshake digest mismatch")
 
 	ErrInternal = errors.New("internal error")
 )
@@ -1,16 +1,27 @@
 package gen
 
 import (
+	"crypto/x509"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
+	"time"
 )
 
 type Network interface {
 	Registrar() (Registrar, error)
 	Cookie() string
+	// SetCookie replaces the cookie. The previous cookies are no longer accepted.
 	SetCookie(cookie string) error
+	// RotateCookie makes the given cookie current. The previous one is still
+	// accepted for the incoming connections and tried for the outgoing ones
+	// until it expires. The established connections are kept alive.
+	// Zero expiry means DefaultCookieExpiry.
+	RotateCookie(cookie string, expiry time.Duration) error
+	// CookieGeneration returns the generation of the current cookie. It is
+	// incremented on every SetCookie/RotateCookie call.
+	CookieGeneration() int
 	MaxMessageSize() int
 	SetMaxMessageSize(size int)
 	NetworkFlags() NetworkFlags
@@ -39,6 +50,10 @@ type Network interface {
 
 	RegisterProto(proto NetworkProto)
 	RegisterHandshake(handshake NetworkHandshake)
+	// RegisterTransport registers the transport to make the outgoing connections
+	// with the nodes advertising it in their routes. Replaces the existing one
+	// with the same name.
+	RegisterTransport(transport NetworkTransport)
 
 	// EnableSpawn allows the starting of the given process by the remote node(s)
 	// Leaving argument "nodes" empty makes spawning this process by any remote node
@@ -50,6 +65,29 @@ type Network interface {
 	EnableApplicationStart(name Atom, nodes ...Atom) error
 	DisableApplicationStart(name Atom, nodes ...Atom) error
 
+	// ACL returns the access control list for the incoming remote requests
+	ACL() NetworkACL
+	// SetACL replaces the access control list. Takes effect immediately for
+	// the existing connections.
+	SetACL(acl NetworkACL)
+
+	// InjectFaults enables fault injection for the outgoing messages sent to the
+	// given peer. Empty peer name sets the faults for all the peers having no
+	// faults set explicitly. Takes effect immediately for the existing connections.
+	// Intended for testing only.
+	InjectFaults(peer Atom, faults NetworkFaults) error
+	// RemoveFaults disables fault injection for the given peer
+	RemoveFaults(peer Atom)
+	// Faults returns the faults injected per peer
+	Faults() map[Atom]NetworkFaults
+
+	// SetBandwidthLimit limits the outgoing traffic to the given peer (bytes per
+	// second). Empty peer name sets the limit for all the peers having no limit
+	// set explicitly. Zero value removes the limit.
+	SetBandwidthLimit(peer Atom, limit int) error
+	// BandwidthLimits returns the bandwidth limits per peer
+	BandwidthLimits() map[Atom]int
+
 	Info() (NetworkInfo, error)
 	Mode() NetworkMode
 }
@@ -82,12 +120,21 @@ type RemoteNode interface {
 
 	Creation() int64
 
+	// SetPoolSize changes the number of the links in the connection pool. The
+	// links are dialed and closed by the node that established the connection.
+	// If the connection was accepted, the request is sent to the peer node
+	// and the pool is resized asynchronously.
+	SetPoolSize(size int) error
+
 	Disconnect()
 }
 
 type Acceptor interface {
 	Cookie() string
 	SetCookie(cokie string)
+	// RotateCookie makes the given cookie current for this acceptor keeping the
+	// previous one valid until it expires. See Network.RotateCookie
+	RotateCookie(cookie string, expiry time.Duration)
 	NetworkFlags() NetworkFlags
 	SetNetworkFlags(flags NetworkFlags)
 	MaxMessageSize() int
@@ -201,18 +248,161 @@ type NetworkOptions struct {
 	Acceptors []AcceptorOptions
 	// InsecureSkipVerify skips the certificate verification
 	InsecureSkipVerify bool
+	// MutualTLS enables mutual TLS for the outgoing connections. Can be
+	// overridden by the NetworkRoute.MutualTLS
+	MutualTLS *MutualTLSOptions
 	// MaxMessageSize limit the message size for the incoming messages.
 	MaxMessageSize int
 	// ProxyAccept options for incomming proxy connections
 	ProxyAccept ProxyAcceptOptions
 	// ProxyTransit options for the proxy connections through this node
 	ProxyTransit ProxyTransitOptions
+	// ACL access control list for the incoming remote requests
+	ACL NetworkACL
+	// Heartbeat enables the heartbeat of the connections with the remote nodes
+	Heartbeat NetworkHeartbeat
+	// BandwidthLimits limits the outgoing traffic per peer (bytes per second).
+	// Empty peer name sets the limit for all peers (see Network.SetBandwidthLimit)
+	BandwidthLimits map[Atom]int
+	// PoolScaling enables the resizing of the connection pools by the throughput
+	PoolScaling NetworkPoolScaling
+	// SendQueueLimit limits the number of the queued messages of the pool link
+	// per priority. The sending fails with ErrTooLarge if the queue is full.
+	// Default value is DefaultSendQueueLimit
+	SendQueueLimit int
 
 	// TODO
 	// FragmentationUnit chunck size in bytes
 	//FragmentationUnit int
 }
 
+// NetworkHeartbeat defines the heartbeat of the connection. The ping frames
+// are sent over every link of the connection pool, the peer replies with
+// the pong ones. The round of the heartbeat is missed if any link hasn't
+// replied before the next one, so the half-open links are detected as well.
+// The support of the heartbeat is negotiated during the handshake, it is not
+// enabled for the peers that don't reply to the ping frames.
+type NetworkHeartbeat struct {
+	Enable bool
+	// Interval between the heartbeats. Default DefaultHeartbeatInterval.
+	Interval time.Duration
+	// MaxMissed the number of the missed heartbeats in a row to declare the node
+	// down. Default DefaultHeartbeatMaxMissed. Ignored if PhiThreshold is set.
+	MaxMissed int
+	// PhiThreshold enables the phi-accrual failure detector. The node is declared
+	// down once the suspicion level (phi) exceeds this value. Typical value is 8.
+	PhiThreshold float64
+	// PhiWindow the number of the last intervals between the heartbeats to
+	// estimate their distribution. Default DefaultHeartbeatPhiWindow.
+	PhiWindow int
+}
+
+// NetworkPoolScaling defines the automatic resizing of the connection pool. It is
+// made by the node that established the connection. The throughput per link
+// is the largest of the incoming and outgoing ones.
+type NetworkPoolScaling struct {
+	Enable bool
+	// Interval between the throughput checks. Default DefaultPoolScalingInterval.
+	Interval time.Duration
+	// MinSize the minimal size of the pool. Default 1.
+	MinSize int
+	// MaxSize the maximal size of the pool. Default DefaultPoolScalingMaxSize.
+	MaxSize int
+	// Grow the throughput per link (bytes per second) to add a new link to the
+	// pool. Default DefaultPoolScalingGrow.
+	Grow int
+	// Shrink the throughput per link (bytes per second) to remove a link from
+	// the pool. Default a quarter of Grow.
+	Shrink int
+}
+
+// HeartbeatInfo statistics of the connection heartbeat
+type HeartbeatInfo struct {
+	Enabled bool
+	RTTMin  time.Duration
+	RTTAvg  time.Duration
+	RTTMax  time.Duration
+	// RTTLast the last measured round-trip time
+	RTTLast time.Duration
+	// Missed the number of the heartbeats missed in a row
+	Missed int
+	// MissedTotal the number of the missed heartbeats
+	MissedTotal uint64
+	// Phi the current suspicion level of the phi-accrual failure detector
+	Phi float64
+}
+
+// NetworkACLOperation defines the operations the remote nodes request with the
+// local targets. Can be combined.
+type NetworkACLOperation int
+
+const (
+	// NetworkACLSend sending messages and exit signals
+	NetworkACLSend NetworkACLOperation = 1
+	// NetworkACLCall making synchronous requests
+	NetworkACLCall NetworkACLOperation = 2
+	// NetworkACLLink creating links
+	NetworkACLLink NetworkACLOperation = 4
+	// NetworkACLMonitor creating monitors
+	NetworkACLMonitor NetworkACLOperation = 8
+
+	NetworkACLAny = NetworkACLSend | NetworkACLCall | NetworkACLLink | NetworkACLMonitor
+)
+
+func (op NetworkACLOperation) String() string {
+	switch op {
+	case NetworkACLSend:
+		return "send"
+	case NetworkACLCall:
+		return "call"
+	case NetworkACLLink:
+		return "link"
+	case NetworkACLMonitor:
+		return "monitor"
+	case NetworkACLAny:
+		return "any"
+	}
+	return fmt.Sprintf("operations %04b", int(op))
+}
+
+func (op NetworkACLOperation) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + op.String() + "\""), nil
+}
+
+// NetworkACL defines which remote nodes may send to, call, link to or monitor
+// the local processes (by their registered names or aliases) and events.
+// Removing links and monitors is always allowed. Denied requests are failed
+// with ErrAccessDenied.
+type NetworkACL struct {
+	// Groups named groups of the remote nodes to refer them in the rules
+	Groups map[string][]Atom
+	// Rules are checked in order, the first matching rule takes effect
+	Rules []NetworkACLRule
+	// DefaultDeny denies the requests no rule matched. By default, such
+	// requests are allowed.
+	DefaultDeny bool
+}
+
+// NetworkACLRule matches the request if all the conditions are met. An empty
+// condition matches any value.
+type NetworkACLRule struct {
+	// Nodes remote nodes the rule applies to
+	Nodes []Atom
+	// Groups groups of the remote nodes (see NetworkACL.Groups) the rule applies to
+	Groups []string
+	// Operations the rule applies to. Zero value means any operation.
+	Operations NetworkACLOperation
+	// Names registered names of the local processes. Applies to the requests
+	// made by PID or alias of the named process as well.
+	Names []Atom
+	// Aliases local aliases
+	Aliases []Alias
+	// Events local events
+	Events []Atom
+	// Deny denies the matched request. Otherwise, the request is allowed.
+	Deny bool
+}
+
 type ProxyAcceptOptions struct {
 	// Cookie sets cookie for incoming connections
 	Cookie string
@@ -324,11 +514,17 @@ type RemoteNodeInfo struct {
 
 	HandshakeVersion Version
 	ProtoVersion     Version
+	// Codec the name of the codec chosen during the handshake
+	Codec string
+	// CookieGeneration the generation of the cookie the peer was authenticated with
+	CookieGeneration int
 
 	NetworkFlags NetworkFlags
 
 	PoolSize int
 	PoolDSN  []string
+	// PoolLinks the number of the links in the pool at the moment
+	PoolLinks int
 
 	MaxMessageSize int
 	MessagesIn     uint64
@@ -339,6 +535,66 @@ type RemoteNodeInfo struct {
 
 	TransitBytesIn  uint64
 	TransitBytesOut uint64
+
+	// Compression statistics of the outgoing messages compression
+	Compression CompressionInfo
+	// Faults statistics of the faults injected into the outgoing messages
+	Faults FaultsInfo
+	// Heartbeat round-trip time and the missed heartbeats
+	Heartbeat HeartbeatInfo
+	// SendQueue the depth of the send queues of the pool links
+	SendQueue SendQueueInfo
+}
+
+// SendQueueInfo the state of the send queues of the connection. The messages
+// are queued if the link is busy and sent in order of their priority.
+type SendQueueInfo struct {
+	// Normal, High, Max the number of the queued messages by priority
+	Normal int
+	High   int
+	Max    int
+	// Peak the maximum depth of the queue of the link
+	Peak int
+	// Limit the maximum depth of the queue of the link per priority
+	Limit int
+	// Dropped the number of the messages rejected due to the full queue
+	Dropped uint64
+	// BandwidthLimit bytes per second. Zero means no limit
+	BandwidthLimit int
+	// Throttled total time the sending was delayed by the bandwidth limit
+	Throttled time.Duration
+}
+
+// CompressionInfo statistics of the outgoing messages compression
+type CompressionInfo struct {
+	// Messages number of the compressed messages
+	Messages uint64
+	// BytesIn size of the compressed messages before the compression
+	BytesIn uint64
+	// BytesOut size of the compressed messages after the compression
+	BytesOut uint64
+	// Skipped number of the messages the adaptive compression has sent uncompressed
+	Skipped uint64
+	// Decisions of the adaptive compression
+	Decisions []CompressionDecision
+}
+
+// CompressionDecision the decision of the adaptive compression for the messages
+// of the given type and size
+type CompressionDecision struct {
+	MessageType string
+	// Size the lower bound of the message size (the upper one is Size*2)
+	Size int
+	// Enable is false if the compression doesn't pay off
+	Enable bool
+	Type   CompressionType
+	Level  CompressionLevel
+	// Ratio the observed ratio of the compressed message size to the original one
+	Ratio float64
+	// Cost the observed time (in nanoseconds) of compressing a kilobyte
+	Cost int64
+	// Messages number of the messages the decision has been made for
+	Messages uint64
 }
 
 type AcceptorOptions struct {
@@ -356,6 +612,10 @@ type AcceptorOptions struct {
 	// TCP defines the TCP network. By default will be used IPv4 only.
 	// For IPv6 use "tcp6". To listen on any available address use "tcp"
 	TCP string
+	// Transport defines the transport for the incoming connections. Default
+	// is TCP (see TCP option). The transport name is advertised by the
+	// registrar, so the remote nodes must have it registered.
+	Transport NetworkTransport
 	// BufferSize defines buffer size for the TCP connection
 	BufferSize int
 	// MaxMessageSize set max message size. overrides gen.NetworkOptions.MaxMessageSize
@@ -366,12 +626,52 @@ type AcceptorOptions struct {
 
 	CertManager        CertManager
 	InsecureSkipVerify bool
+	// MutualTLS requires the certificate from the connecting node. Makes sense
+	// with the CertManager only.
+	MutualTLS *MutualTLSOptions
 
 	Registrar Registrar
 	Handshake NetworkHandshake
 	Proto     NetworkProto
 }
 
+// MutualTLSOptions defines the mutual TLS authentication of the nodes. Both
+// sides present their certificates issued by one of the CAs, and the node name
+// must match the certificate (see CertificateMatchNode). It replaces the
+// cookie-only authentication: the cookie is still checked by the handshake.
+type MutualTLSOptions struct {
+	// CAs the certificate authorities the certificate of the peer node must be
+	// issued by
+	CAs *x509.CertPool
+	// Policy decides which flags are granted to the authenticated peer node.
+	// Leave it nil to grant the flags of the acceptor (or the route).
+	Policy NetworkPolicy
+}
+
+// NetworkPolicy is invoking once the peer node is authenticated. It takes the
+// flags this node is going to grant to the peer node and returns the ones that
+// are granted. Returning error rejects the connection.
+type NetworkPolicy func(peer Atom, cert *x509.Certificate, flags NetworkFlags) (NetworkFlags, error)
+
+// NetworkTransport establishes the connections between the nodes. The host and
+// port of the route (acceptor) are mapped onto the transport address.
+type NetworkTransport interface {
+	// Name of the transport. Advertised within the route (Route.Transport)
+	Name() string
+	// Address returns the address for the given host and port
+	Address(host string, port uint16) string
+	Listen(address string) (net.Listener, error)
+	Dial(address string, timeout time.Duration) (net.Conn, error)
+}
+
+// NetworkTransportPath is implemented by the transports working over HTTP.
+// The path is advertised within the route (Route.Path) and appended to
+// the address on dialing.
+type NetworkTransportPath interface {
+	NetworkTransport
+	Path() string
+}
+
 // Handshake defines handshake interface
 type NetworkHandshake interface {
 	NetworkFlags() NetworkFlags
@@ -386,11 +686,26 @@ type NetworkHandshake interface {
 	Version() Version
 }
 
+// NetworkCookie the previous cookie accepted during the cookie rotation
+type NetworkCookie struct {
+	Cookie     string
+	Generation int
+	Expires    time.Time
+}
+
 type HandshakeOptions struct {
-	Cookie         string
-	Flags          NetworkFlags
-	CertManager    CertManager
-	MaxMessageSize int
+	Cookie string
+	// CookieGeneration the generation of the Cookie
+	CookieGeneration int
+	// PreviousCookies are accepted from the peer as well (cookie rotation)
+	PreviousCookies []NetworkCookie
+	Flags           NetworkFlags
+	CertManager     CertManager
+	MaxMessageSize  int
+	// Authorize is invoking once the peer node introduced itself. It takes the
+	// flags for this connection and returns the ones granted to the peer node.
+	// Returning error rejects the connection.
+	Authorize func(conn net.Conn, peer Atom, flags NetworkFlags) (NetworkFlags, error)
 }
 
 type HandshakeResult struct {
@@ -406,6 +721,16 @@ type HandshakeResult struct {
 	NodeFlags          NetworkFlags
 	NodeMaxMessageSize int
 
+	// CookieGeneration the generation of the cookie the handshake was made with
+	CookieGeneration int
+
+	// Heartbeat options for the connection. Set by the node.
+	Heartbeat NetworkHeartbeat
+	// PoolScaling options for the connection. Set by the node.
+	PoolScaling NetworkPoolScaling
+	// SendQueueLimit for the pool links of the connection. Set by the node.
+	SendQueueLimit int
+
 	AtomMapping map[Atom]Atom
 
 	// Tail if something is left in the buffer after the handshaking we should
@@ -444,6 +769,42 @@ type NetworkInfo struct {
 	Flags                   NetworkFlags
 	EnabledSpawn            []NetworkSpawnInfo
 	EnabledApplicationStart []NetworkApplicationStartInfo
+
+	// ACLDenied number of the remote requests denied by the ACL
+	ACLDenied uint64
+	// CookieGeneration the generation of the current cookie
+	CookieGeneration int
+
+	// FaultInjection is true if the faults are injected for any peer
+	FaultInjection bool
+	// Faults the faults injected per peer (see Network.InjectFaults)
+	Faults map[Atom]NetworkFaults
+}
+
+// NetworkFaults defines the faults injected into the outgoing messages of the
+// connection. Zero value means no faults.
+type NetworkFaults struct {
+	// Latency delays delivering of every message
+	Latency time.Duration
+	// Jitter random delay (0..Jitter) added to the Latency. The order of the messages
+	// sent over the same link of the pool is kept.
+	Jitter time.Duration
+	// DropRate probability (0..1) of the message to be dropped
+	DropRate float64
+	// ReorderRate probability (0..1) of the message to be sent over the random
+	// link of the pool, so it can overtake the messages sent before
+	ReorderRate float64
+	// DisconnectRate probability (0..1) of the link of the pool to be closed
+	// on sending a message. The message is lost.
+	DisconnectRate float64
+}
+
+// FaultsInfo statistics of the injected faults
+type FaultsInfo struct {
+	Delayed      uint64
+	Dropped      uint64
+	Reordered    uint64
+	Disconnected uint64
 }
 
 type NetworkSpawnInfo struct {
@@ -461,9 +822,16 @@ type NetworkRoute struct {
 	Resolver Resolver
 	Route    Route
 
-	Cookie             string
+	// Transport overrides the transport advertised by the route
+	Transport NetworkTransport
+
+	Cookie string
+	// PreviousCookies are tried if the handshake with Cookie has failed, which
+	// happens if the peer hasn't got the rotated cookie yet
+	PreviousCookies    []string
 	Cert               CertManager
 	InsecureSkipVerify bool
+	MutualTLS          *MutualTLSOptions
 	Flags              NetworkFlags
 
 	AtomMapping map[Atom]Atom
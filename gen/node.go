@@ -2,6 +2,8 @@ package gen
 
 import (
 	"time"
+
+	"github.com/sllt/sparrow/lib"
 )
 
 type Node interface {
@@ -261,13 +263,23 @@ type Compression struct {
 	// Enable enables compression for all outgoing messages having size
 	// greater than the defined threshold.
 	Enable bool
-	// Type defines type of compression. Use gen.CompressionTypeZLIB or gen.CompressionTypeLZW. By default is using gen.CompressionTypeGZIP
+	// Type defines type of compression. Use gen.CompressionTypeZLIB, gen.CompressionTypeLZW,
+	// gen.CompressionTypeDeflate, gen.CompressionTypeSnappy or the name of the compressor
+	// registered with lib.RegisterCompressor. By default is using gen.CompressionTypeGZIP
 	Type CompressionType
 	// Level defines compression level. Use gen.CompressionBestSize or gen.CompressionBestSpeed. By default is using gen.CompressionDefault
 	Level CompressionLevel
 	// Threshold defines the minimal message size for the compression.
 	// Messages less of this threshold will not be compressed.
 	Threshold int
+	// Adaptive enables the adaptive compression. For every message type and size
+	// the connection measures the compression ratio and cost of the given Type
+	// and Level (as well as the other levels and gen.CompressionTypeSnappy) and
+	// picks the one that takes less time to send the message, taking into account
+	// DefaultAdaptiveCompressionBandwidth. If none of them pays off the message
+	// is sent uncompressed. Threshold is ignored. The decisions are reported
+	// in RemoteNodeInfo.Compression.
+	Adaptive bool
 }
 
 type CompressionLevel int
@@ -290,17 +302,15 @@ func (cl CompressionLevel) MarshalJSON() ([]byte, error) {
 	return []byte("\"" + cl.String() + "\""), nil
 }
 
+// ID returns the identifier of the compression type within the compressed
+// message. Returns 0 if there is no registered compressor with this name
+// (see lib.RegisterCompressor)
 func (ct CompressionType) ID() uint8 {
-	switch ct {
-	case CompressionTypeLZW:
-		return 100
-	case CompressionTypeZLIB:
-		return 101
-	case CompressionTypeGZIP:
-		return 102
-	default:
+	c, found := lib.CompressorByName(string(ct))
+	if found == false {
 		return 0
 	}
+	return c.ID()
 }
 
 const (
@@ -311,6 +321,11 @@ const (
 	CompressionTypeGZIP CompressionType = "gzip"
 	CompressionTypeLZW  CompressionType = "lzw"
 	CompressionTypeZLIB CompressionType = "zlib"
+	// CompressionTypeDeflate raw DEFLATE stream. Supports the shared dictionary.
+	CompressionTypeDeflate CompressionType = "deflate"
+	// CompressionTypeSnappy Snappy block format. Faster than DEFLATE-based
+	// algorithms with a lower compression ratio. Supports the shared dictionary.
+	CompressionTypeSnappy CompressionType = "snappy"
 )
 
 type NodeInfo struct {
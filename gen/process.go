@@ -128,7 +128,9 @@ type Process interface {
 
 	// CompressionType returns type of compression
 	CompressionType() CompressionType
-	// SetCompressionType defines the compression type. Use gen.CompressionTypeZLIB or gen.CompressionTypeLZW. Be default is using gen.CompressionTypeGZIP
+	// SetCompressionType defines the compression type. Use gen.CompressionTypeZLIB, gen.CompressionTypeLZW,
+	// gen.CompressionTypeDeflate, gen.CompressionTypeSnappy or the name of the registered compressor
+	// (see lib.RegisterCompressor). Be default is using gen.CompressionTypeGZIP
 	SetCompressionType(ctype CompressionType) error
 
 	// CompressionLevel returns comression level for the process
@@ -144,6 +146,13 @@ type Process interface {
 	// Value must be greater than DefaultCompressionThreshold (1024)
 	SetCompressionThreshold(threshold int) error
 
+	// CompressionAdaptive returns true if the adaptive compression is enabled for this process
+	CompressionAdaptive() bool
+	// SetCompressionAdaptive enables/disables the adaptive compression. The connection picks
+	// the compression type, level and threshold for the messages of this process using the
+	// observed compression ratio and cost (see Compression.Adaptive)
+	SetCompressionAdaptive(adaptive bool) error
+
 	// SendPriority returns priority for the sending messages
 	SendPriority() MessagePriority
 
@@ -184,9 +193,27 @@ type Process interface {
 	// SendAfter starts a timer. When the timer expires, the message sends to the process
 	// identified by 'to'. Returns cancel function in order to discard
 	// sending a message. CancelFunc returns bool value. If it returns false, than the timer has
-	// already expired and the message has been sent.
+	// already expired and the message has been sent. All timers started by the process
+	// are canceled on its termination.
 	SendAfter(to any, message any, after time.Duration) (CancelFunc, error)
 
+	// SendInterval starts a periodic timer. The message is sent to the process identified
+	// by 'to' every time the interval expires until the timer is canceled. Unlike SendAfter,
+	// the timer methods below return ErrUnsupported for the unsupported type of 'to'.
+	SendInterval(to any, message any, interval time.Duration) (CancelFunc, error)
+
+	// SendAfterNamed starts a timer with the given name. Starting a timer with the name
+	// of the active one replaces it.
+	SendAfterNamed(name string, to any, message any, after time.Duration) (CancelFunc, error)
+
+	// SendIntervalNamed starts a periodic timer with the given name. Starting a timer with
+	// the name of the active one replaces it.
+	SendIntervalNamed(name string, to any, message any, interval time.Duration) (CancelFunc, error)
+
+	// CancelTimer cancels the timer with the given name. Returns false if there is no
+	// active timer with this name.
+	CancelTimer(name string) bool
+
 	// SendEvent sends event message to the subscribers (to the processes that made link/monitor
 	// on this event). Event must be registered with RegisterEvent method.
 	SendEvent(name Atom, token Ref, message any) error
@@ -441,6 +468,22 @@ type ProcessInfo struct {
 	KeepNetworkOrder bool
 	// ImportantDelivery
 	ImportantDelivery bool
+	// Timers list of the active timers started by this process
+	Timers []TimerInfo
+}
+
+// TimerInfo
+type TimerInfo struct {
+	// Name of the timer. Empty for the timers started with SendAfter/SendInterval
+	Name string
+	// To the recipient of the timer message
+	To string
+	// Interval of the periodic timer in ns. Zero for the one-shot timer.
+	Interval int64
+	// Deadline time (in ns) when the timer fires next time
+	Deadline int64
+	// Fired number of times the timer has fired
+	Fired uint64
 }
 
 // ProcessShortInfo
@@ -69,6 +69,8 @@ type AcceptorInfo struct {
 	MaxMessageSize   int
 	Flags            NetworkFlags
 	TLS              bool
+	MutualTLS        bool
+	Transport        string
 	CustomRegistrar  bool
 	RegistrarServer  string
 	RegistrarVersion Version
@@ -93,6 +95,13 @@ type Route struct {
 	TLS              bool
 	HandshakeVersion Version
 	ProtoVersion     Version
+	// Transport the name of the transport (see NetworkTransport).
+	// Empty value means TCP.
+	Transport string
+	// Path the HTTP path of the endpoint for the transports working over HTTP
+	// (see NetworkTransportPath). Allows the path-based routing by the HTTP
+	// infrastructure.
+	Path string
 }
 
 type ProxyRoute struct {
@@ -124,6 +133,7 @@ type RouteInfo struct {
 	ProtoVersion     Version
 	Host             string
 	Port             uint16
+	Transport        string
 }
 
 type ProxyRouteInfo struct {
@@ -174,4 +174,298 @@ func init() {
 			},
 		}
 	}
+
+	builtin := []Compressor{
+		compressorLZW{},
+		compressorZLIB{},
+		compressorGZIP{},
+		compressorDeflate{},
+		compressorSnappy{},
+	}
+	for _, c := range builtin {
+		if err := RegisterCompressor(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Compressor implements the compression algorithm for the network messages.
+// Use RegisterCompressor to make it available for the gen.CompressionType
+// with the same name. Both nodes must have the same set of compressors.
+type Compressor interface {
+	// Name returns the name of the algorithm (gen.CompressionType)
+	Name() string
+	// ID returns the identifier of the algorithm within the compressed
+	// message. Must be in range 1..127
+	ID() uint8
+	// Compress appends compressed src to the dst. Level: 0 - default,
+	// 1 - best speed, 2 - best size
+	Compress(dst *Buffer, src []byte, level int) error
+	// Decompress decompresses src into the dst. The length of dst is the
+	// length of the unpacked data
+	Decompress(dst []byte, src []byte) error
+}
+
+// DictCompressor is implemented by the compression algorithms supporting
+// the shared dictionary (see TrainDictionary)
+type DictCompressor interface {
+	Compressor
+	CompressDict(dst *Buffer, src []byte, level int, dict []byte) error
+	DecompressDict(dst []byte, src []byte, dict []byte) error
+}
+
+var (
+	compressors     sync.Map // name => Compressor
+	compressorsByID sync.Map // ID => Compressor
+)
+
+// RegisterCompressor registers the compression algorithm
+func RegisterCompressor(c Compressor) error {
+	id := c.ID()
+	if id == 0 || id > 127 {
+		return fmt.Errorf("incorrect compressor ID %d (must be 1..127)", id)
+	}
+	if c.Name() == "" {
+		return fmt.Errorf("empty compressor name")
+	}
+	if _, exist := compressorsByID.LoadOrStore(id, c); exist {
+		return fmt.Errorf("compressor ID %d is already taken", id)
+	}
+	if _, exist := compressors.LoadOrStore(c.Name(), c); exist {
+		compressorsByID.Delete(id)
+		return fmt.Errorf("compressor %q is already registered", c.Name())
+	}
+	return nil
+}
+
+// CompressorByName returns the registered compressor with the given name
+func CompressorByName(name string) (Compressor, bool) {
+	v, found := compressors.Load(name)
+	if found == false {
+		return nil, false
+	}
+	return v.(Compressor), true
+}
+
+// CompressorByID returns the registered compressor with the given ID
+func CompressorByID(id uint8) (Compressor, bool) {
+	v, found := compressorsByID.Load(id)
+	if found == false {
+		return nil, false
+	}
+	return v.(Compressor), true
+}
+
+// Compress compresses src using the given compressor. The first 'preallocate'
+// bytes of the result are reserved for the header, the next 4 bytes keep the
+// length of src. The dictionary is used if the compressor supports it.
+func Compress(c Compressor, src *Buffer, preallocate uint, level int, dict []byte) (dst *Buffer, err error) {
+	if src.Len() > math.MaxUint32 {
+		return nil, fmt.Errorf("message to large")
+	}
+
+	zBuffer := TakeBuffer()
+	zBuffer.Allocate(int(preallocate) + 4)
+	binary.BigEndian.PutUint32(zBuffer.B[preallocate:], uint32(src.Len()))
+
+	if dc, ok := c.(DictCompressor); ok && len(dict) > 0 {
+		err = dc.CompressDict(zBuffer, src.B, level, dict)
+	} else {
+		err = c.Compress(zBuffer, src.B, level)
+	}
+	if err != nil {
+		ReleaseBuffer(zBuffer)
+		return nil, err
+	}
+	return zBuffer, nil
+}
+
+// Decompress decompresses the data compressed by Compress skipping the
+// header of the given size
+func Decompress(c Compressor, src *Buffer, skip uint, dict []byte) (dst *Buffer, err error) {
+	if src.Len() < int(skip)+4 {
+		return nil, fmt.Errorf("too short source buffer")
+	}
+	source := src.B[skip:]
+	lenUnpacked := int(binary.BigEndian.Uint32(source[:4]))
+	dst = TakeBuffer()
+	dst.Allocate(lenUnpacked)
+
+	if dc, ok := c.(DictCompressor); ok && len(dict) > 0 {
+		err = dc.DecompressDict(dst.B, source[4:], dict)
+	} else {
+		err = c.Decompress(dst.B, source[4:])
+	}
+	if err != nil {
+		ReleaseBuffer(dst)
+		return nil, err
+	}
+	return dst, nil
+}
+
+type writerKey struct {
+	id    uint8
+	level int
+	dict  uint32
+}
+
+// writers keeps the pools of the compression writers (writerKey => *sync.Pool).
+// The writers keep their level and dictionary on Reset.
+var writers sync.Map
+
+func writerPool(id uint8, level int, dict []byte) *sync.Pool {
+	key := writerKey{id: id, level: level}
+	if len(dict) > 0 {
+		key.dict = DictionaryID(dict)
+	}
+	if v, found := writers.Load(key); found {
+		return v.(*sync.Pool)
+	}
+	v, _ := writers.LoadOrStore(key, &sync.Pool{})
+	return v.(*sync.Pool)
+}
+
+func flateLevel(level int) int {
+	switch level {
+	case 2:
+		return flate.BestCompression
+	case 1:
+		return flate.BestSpeed
+	}
+	return flate.DefaultCompression
+}
+
+type compressorLZW struct{}
+
+func (compressorLZW) Name() string { return "lzw" }
+func (compressorLZW) ID() uint8    { return 100 }
+
+func (compressorLZW) Compress(dst *Buffer, src []byte, level int) error {
+	zWriter := lzw.NewWriter(dst, lzw.LSB, 8)
+	if _, err := zWriter.Write(src); err != nil {
+		return err
+	}
+	return zWriter.Close()
+}
+
+func (compressorLZW) Decompress(dst []byte, src []byte) error {
+	return decompress(dst, lzw.NewReader(bytes.NewReader(src), lzw.LSB, 8))
+}
+
+type compressorZLIB struct{}
+
+func (compressorZLIB) Name() string { return "zlib" }
+func (compressorZLIB) ID() uint8    { return 101 }
+
+func (c compressorZLIB) Compress(dst *Buffer, src []byte, level int) error {
+	return c.CompressDict(dst, src, level, nil)
+}
+
+func (c compressorZLIB) Decompress(dst []byte, src []byte) error {
+	return c.DecompressDict(dst, src, nil)
+}
+
+func (c compressorZLIB) CompressDict(dst *Buffer, src []byte, level int, dict []byte) error {
+	pool := writerPool(c.ID(), level, dict)
+	zWriter, ok := pool.Get().(*zlib.Writer)
+	if ok {
+		zWriter.Reset(dst)
+	} else {
+		w, err := zlib.NewWriterLevelDict(dst, flateLevel(level), dict)
+		if err != nil {
+			return err
+		}
+		zWriter = w
+	}
+	if _, err := zWriter.Write(src); err != nil {
+		return err
+	}
+	if err := zWriter.Close(); err != nil {
+		return err
+	}
+	pool.Put(zWriter)
+	return nil
+}
+
+func (compressorZLIB) DecompressDict(dst []byte, src []byte, dict []byte) error {
+	reader, err := zlib.NewReaderDict(bytes.NewReader(src), dict)
+	if err != nil {
+		return err
+	}
+	return decompress(dst, reader)
+}
+
+type compressorGZIP struct{}
+
+func (compressorGZIP) Name() string { return "gzip" }
+func (compressorGZIP) ID() uint8    { return 102 }
+
+func (compressorGZIP) Compress(dst *Buffer, src []byte, level int) error {
+	var zWriter *gzip.Writer
+	if level < 0 || level > 2 {
+		level = 0
+	}
+	if w, ok := gzipWriters[level].Get().(*gzip.Writer); ok {
+		zWriter = w
+		zWriter.Reset(dst)
+	} else {
+		zWriter, _ = gzip.NewWriterLevel(dst, flateLevel(level))
+	}
+	if _, err := zWriter.Write(src); err != nil {
+		return err
+	}
+	if err := zWriter.Close(); err != nil {
+		return err
+	}
+	gzipWriters[level].Put(zWriter)
+	return nil
+}
+
+func (compressorGZIP) Decompress(dst []byte, src []byte) error {
+	reader, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return err
+	}
+	return decompress(dst, reader)
+}
+
+// compressorDeflate raw DEFLATE stream (no header and checksum) with the
+// dictionary support
+type compressorDeflate struct{}
+
+func (compressorDeflate) Name() string { return "deflate" }
+func (compressorDeflate) ID() uint8    { return 103 }
+
+func (c compressorDeflate) Compress(dst *Buffer, src []byte, level int) error {
+	return c.CompressDict(dst, src, level, nil)
+}
+
+func (c compressorDeflate) Decompress(dst []byte, src []byte) error {
+	return c.DecompressDict(dst, src, nil)
+}
+
+func (c compressorDeflate) CompressDict(dst *Buffer, src []byte, level int, dict []byte) error {
+	pool := writerPool(c.ID(), level, dict)
+	zWriter, ok := pool.Get().(*flate.Writer)
+	if ok {
+		zWriter.Reset(dst)
+	} else {
+		w, err := flate.NewWriterDict(dst, flateLevel(level), dict)
+		if err != nil {
+			return err
+		}
+		zWriter = w
+	}
+	if _, err := zWriter.Write(src); err != nil {
+		return err
+	}
+	if err := zWriter.Close(); err != nil {
+		return err
+	}
+	pool.Put(zWriter)
+	return nil
+}
+
+func (compressorDeflate) DecompressDict(dst []byte, src []byte, dict []byte) error {
+	return decompress(dst, flate.NewReaderDict(bytes.NewReader(src), dict))
 }
@@ -1,6 +1,9 @@
 package lib
 
 import (
+	"bytes"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -64,3 +67,156 @@ func TestCompressDecompressLZW(t *testing.T) {
 		t.Fatal("incorrect result")
 	}
 }
+
+func testCompressSamples() [][]byte {
+	var samples [][]byte
+	for i := 0; i < 200; i++ {
+		s := fmt.Sprintf(`{"id":%d,"name":"user-%d","email":"user%d@example.com",`+
+			`"active":true,"roles":["reader","writer"],"created":"2024-01-%02dT10:00:00Z"}`,
+			i, i*7, i*13, i%28+1)
+		samples = append(samples, []byte(s))
+	}
+	return samples
+}
+
+func TestCompressors(t *testing.T) {
+	long := bytes.Repeat([]byte("abcdefgh12345678"), 10000)
+	random := []byte(RandomString(100000))
+	// repeated block of random data far away (copy with 4 bytes offset)
+	far := append(append(append([]byte{}, random...), random[:1000]...), 'x')
+
+	sources := [][]byte{
+		{},
+		[]byte("a"),
+		[]byte("abcd"),
+		[]byte(srcCompress),
+		long,
+		random,
+		far,
+		testCompressSamples()[0],
+	}
+	dict := TrainDictionary(testCompressSamples()[1:], 4096)
+
+	for _, name := range []string{"lzw", "zlib", "gzip", "deflate", "snappy"} {
+		c, found := CompressorByName(name)
+		if found == false {
+			t.Fatalf("compressor %s is not registered", name)
+		}
+		if cc, _ := CompressorByID(c.ID()); cc != c {
+			t.Fatalf("compressor %s is not registered by ID", name)
+		}
+		for _, d := range [][]byte{nil, dict} {
+			for _, src := range sources {
+				buf := TakeBuffer()
+				buf.Append(src)
+				header := uint(12)
+				dst, err := Compress(c, buf, header, 0, d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := Decompress(c, dst, header, d)
+				if err != nil {
+					t.Fatalf("%s (len %d): %s", name, len(src), err)
+				}
+				if bytes.Equal(res.B, src) == false {
+					t.Fatalf("%s (len %d): incorrect result", name, len(src))
+				}
+				ReleaseBuffer(buf)
+				ReleaseBuffer(dst)
+				ReleaseBuffer(res)
+			}
+		}
+	}
+
+	if err := RegisterCompressor(compressorSnappy{}); err == nil {
+		t.Fatal("compressor must be registered once")
+	}
+}
+
+func TestCompressDictionary(t *testing.T) {
+	samples := testCompressSamples()
+	dict := TrainDictionary(samples[:150], 2048)
+	if len(dict) == 0 || len(dict) > 2048 {
+		t.Fatalf("incorrect dictionary size %d", len(dict))
+	}
+
+	for _, name := range []string{"deflate", "snappy"} {
+		c, _ := CompressorByName(name)
+		var plain, withDict int
+		for _, sample := range samples[150:] {
+			buf := TakeBuffer()
+			buf.Append(sample)
+			dst, _ := Compress(c, buf, 0, 0, nil)
+			plain += dst.Len()
+			ReleaseBuffer(dst)
+			dst, _ = Compress(c, buf, 0, 0, dict)
+			withDict += dst.Len()
+			ReleaseBuffer(dst)
+			ReleaseBuffer(buf)
+		}
+		if withDict*2 > plain {
+			t.Fatalf("%s: dictionary must improve compression (%d => %d)", name, plain, withDict)
+		}
+
+		// wrong dictionary
+		buf := TakeBuffer()
+		buf.Append(samples[160])
+		dst, _ := Compress(c, buf, 0, 0, dict)
+		wrong := append([]byte{}, dict...)
+		for i := range wrong {
+			wrong[i]++
+		}
+		if res, err := Decompress(c, dst, 0, wrong); err == nil && bytes.Equal(res.B, samples[160]) {
+			t.Fatalf("%s: must not be decompressed with another dictionary", name)
+		}
+	}
+
+	sampler := NewDictionarySampler(2, 10)
+	for _, sample := range samples {
+		sampler.Add(sample)
+	}
+	if s := sampler.Samples(); len(s) != 10 || bytes.Equal(s[9], samples[199]) == false {
+		t.Fatal("incorrect samples")
+	}
+	if DictionaryID(dict) == DictionaryID(sampler.Train(2048)) {
+		t.Fatal("dictionaries must differ")
+	}
+
+	// every rate-th message is sampled by the concurrent senders as well
+	sampler = NewDictionarySampler(4, 1000)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, sample := range samples {
+				sampler.Add(sample)
+			}
+		}()
+	}
+	wg.Wait()
+	if l := len(sampler.Samples()); l != 8*len(samples)/4 {
+		t.Fatalf("expected %d samples, got %d", 8*len(samples)/4, l)
+	}
+}
+
+func TestSnappyCorrupt(t *testing.T) {
+	c := compressorSnappy{}
+	cases := [][]byte{
+		{},
+		{0x05},                                  // no data
+		{0x04, 0x0c, 'a'},                       // literal is too short
+		{0x04, 0x01, 0x05},                      // copy before data
+		{0x04, 0x00, 'a', 0x0e},                 // truncated copy2
+		{0x02, 0x00, 'a', 0x00, 'b', 0x00, 'c'}, // too long
+	}
+	for _, src := range cases {
+		dst := make([]byte, 4)
+		if len(src) > 0 {
+			dst = make([]byte, src[0])
+		}
+		if err := c.Decompress(dst, src); err == nil {
+			t.Fatalf("must be an error for %v", src)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	dictGramSize    = 8
+	dictSegmentSize = 64
+)
+
+// DictionaryID returns the identifier of the compression dictionary. Nodes
+// refer to the shared dictionaries by their identifiers.
+func DictionaryID(dict []byte) uint32 {
+	return crc32.ChecksumIEEE(dict)
+}
+
+// DictionarySampler collects the samples of the messages to train the
+// compression dictionary. It keeps every rate-th message and replaces the
+// oldest samples once the limit is reached. It is safe for concurrent use.
+type DictionarySampler struct {
+	rate    uint64
+	limit   int
+	counter uint64
+
+	mutex   sync.Mutex
+	added   int
+	samples [][]byte
+}
+
+// NewDictionarySampler creates a sampler keeping every rate-th message, up to
+// the limit of samples
+func NewDictionarySampler(rate int, limit int) *DictionarySampler {
+	if rate < 1 {
+		rate = 1
+	}
+	if limit < 1 {
+		limit = 1000
+	}
+	return &DictionarySampler{
+		rate:  uint64(rate),
+		limit: limit,
+	}
+}
+
+// Add takes a copy of the message if it must be sampled. The skipped messages
+// cost an atomic increment only.
+func (s *DictionarySampler) Add(message []byte) {
+	if atomic.AddUint64(&s.counter, 1)%s.rate != 0 {
+		return
+	}
+	sample := append([]byte{}, message...)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.samples) < s.limit {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.added%s.limit] = sample
+	}
+	s.added++
+}
+
+// Samples returns the collected samples
+func (s *DictionarySampler) Samples() [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]byte{}, s.samples...)
+}
+
+// Train makes the dictionary of the given size out of the collected samples
+func (s *DictionarySampler) Train(size int) []byte {
+	return TrainDictionary(s.Samples(), size)
+}
+
+// TrainDictionary makes the compression dictionary of the given size (max
+// 32KB are used by DEFLATE-based algorithms) out of the samples. It picks the
+// segments of the samples containing the byte sequences most of the samples
+// have. The most valuable segments are placed at the end of the dictionary,
+// since the closer references are cheaper.
+func TrainDictionary(samples [][]byte, size int) []byte {
+	if size < 1 {
+		return nil
+	}
+
+	// number of samples having the gram
+	freq := make(map[uint64]int)
+	for _, sample := range samples {
+		seen := make(map[uint64]bool)
+		for i := 0; i+dictGramSize <= len(sample); i++ {
+			g := binary.LittleEndian.Uint64(sample[i:])
+			if seen[g] {
+				continue
+			}
+			seen[g] = true
+			freq[g]++
+		}
+	}
+
+	used := make(map[uint64]bool)
+	score := func(segment []byte) int {
+		var sc int
+		for i := 0; i+dictGramSize <= len(segment); i++ {
+			g := binary.LittleEndian.Uint64(segment[i:])
+			if used[g] {
+				continue
+			}
+			// the gram must be shared by the samples
+			if n := freq[g]; n > 1 {
+				sc += n
+			}
+		}
+		return sc
+	}
+
+	var candidates dictSegments
+	step := dictSegmentSize / 2
+	for _, sample := range samples {
+		for i := 0; i < len(sample); i += step {
+			end := i + dictSegmentSize
+			if end > len(sample) {
+				end = len(sample)
+			}
+			segment := sample[i:end]
+			if sc := score(segment); sc > 0 {
+				candidates = append(candidates, dictSegment{segment, sc})
+			}
+			if end == len(sample) {
+				break
+			}
+		}
+	}
+	heap.Init(&candidates)
+
+	var selected [][]byte
+	total := 0
+	for total < size && candidates.Len() > 0 {
+		best := heap.Pop(&candidates).(dictSegment)
+		// scores only decrease as the grams are used, so the stored score
+		// is the upper bound. recheck it
+		sc := score(best.data)
+		if sc == 0 {
+			continue
+		}
+		if candidates.Len() > 0 && sc < candidates[0].score {
+			best.score = sc
+			heap.Push(&candidates, best)
+			continue
+		}
+
+		for i := 0; i+dictGramSize <= len(best.data); i++ {
+			used[binary.LittleEndian.Uint64(best.data[i:])] = true
+		}
+		selected = append(selected, best.data)
+		total += len(best.data)
+	}
+
+	dict := make([]byte, 0, total)
+	for i := len(selected) - 1; i >= 0; i-- {
+		dict = append(dict, selected[i]...)
+	}
+	if len(dict) > size {
+		dict = dict[len(dict)-size:]
+	}
+	return dict
+}
+
+type dictSegment struct {
+	data  []byte
+	score int
+}
+
+type dictSegments []dictSegment
+
+func (s dictSegments) Len() int           { return len(s) }
+func (s dictSegments) Less(i, j int) bool { return s[i].score > s[j].score }
+func (s dictSegments) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s *dictSegments) Push(x any)        { *s = append(*s, x.(dictSegment)) }
+func (s *dictSegments) Pop() any {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[:n-1]
+	return x
+}
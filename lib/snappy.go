@@ -0,0 +1,255 @@
+package lib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// compressorSnappy implements the Snappy block format. It is much faster than
+// DEFLATE-based algorithms with a lower compression ratio. With the dictionary
+// the encoder refers to the data of the dictionary as it precedes the source,
+// so the result can be decompressed with the same dictionary only.
+type compressorSnappy struct{}
+
+const (
+	snappyTagLiteral = 0x00
+	snappyTagCopy1   = 0x01
+	snappyTagCopy2   = 0x02
+	snappyTagCopy4   = 0x03
+
+	snappyTableBits = 14
+	snappyMinMatch  = 4
+)
+
+var (
+	errSnappyCorrupt = fmt.Errorf("snappy: corrupt input")
+
+	snappyTables = &sync.Pool{
+		New: func() any {
+			return new([1 << snappyTableBits]int32)
+		},
+	}
+)
+
+func (compressorSnappy) Name() string { return "snappy" }
+func (compressorSnappy) ID() uint8    { return 104 }
+
+func (c compressorSnappy) Compress(dst *Buffer, src []byte, level int) error {
+	return c.CompressDict(dst, src, level, nil)
+}
+
+func (c compressorSnappy) Decompress(dst []byte, src []byte) error {
+	return c.DecompressDict(dst, src, nil)
+}
+
+func (compressorSnappy) CompressDict(dst *Buffer, src []byte, level int, dict []byte) error {
+	dst.B = binary.AppendUvarint(dst.B, uint64(len(src)))
+
+	in := src
+	base := 0
+	if len(dict) > 0 {
+		in = make([]byte, 0, len(dict)+len(src))
+		in = append(in, dict...)
+		in = append(in, src...)
+		base = len(dict)
+	}
+
+	// the table keeps position+1, so zero means no position
+	shift := uint32(32 - snappyTableBits)
+	for n := 1 << snappyTableBits; n > 256 && n >= len(in)*2; n >>= 1 {
+		shift++
+	}
+	t := snappyTables.Get().(*[1 << snappyTableBits]int32)
+	defer snappyTables.Put(t)
+	table := t[:1<<(32-shift)]
+	for i := range table {
+		table[i] = 0
+	}
+	for i := 0; i+snappyMinMatch <= base; i++ {
+		table[snappyHash(in, i, shift)] = int32(i + 1)
+	}
+
+	s := base
+	lit := base
+	for s+snappyMinMatch <= len(in) {
+		h := snappyHash(in, s, shift)
+		candidate := int(table[h]) - 1
+		table[h] = int32(s + 1)
+
+		if candidate < 0 ||
+			binary.LittleEndian.Uint32(in[candidate:]) != binary.LittleEndian.Uint32(in[s:]) {
+			s++
+			continue
+		}
+
+		m := snappyMinMatch
+		for s+m+8 <= len(in) {
+			x := binary.LittleEndian.Uint64(in[s+m:]) ^ binary.LittleEndian.Uint64(in[candidate+m:])
+			if x != 0 {
+				m += bits.TrailingZeros64(x) / 8
+				break
+			}
+			m += 8
+		}
+		for s+m < len(in) && in[candidate+m] == in[s+m] {
+			m++
+		}
+		if lit < s {
+			dst.B = snappyEmitLiteral(dst.B, in[lit:s])
+		}
+		dst.B = snappyEmitCopy(dst.B, s-candidate, m)
+		s += m
+		lit = s
+		if s-1+snappyMinMatch <= len(in) {
+			table[snappyHash(in, s-1, shift)] = int32(s)
+		}
+	}
+	if lit < len(in) {
+		dst.B = snappyEmitLiteral(dst.B, in[lit:])
+	}
+	return nil
+}
+
+func (compressorSnappy) DecompressDict(dst []byte, src []byte, dict []byte) error {
+	n, l := binary.Uvarint(src)
+	if l <= 0 {
+		return errSnappyCorrupt
+	}
+	if n != uint64(len(dst)) {
+		return fmt.Errorf("unpacked size mismatch")
+	}
+	src = src[l:]
+
+	out := dst
+	base := 0
+	if len(dict) > 0 {
+		out = make([]byte, len(dict)+len(dst))
+		copy(out, dict)
+		base = len(dict)
+	}
+
+	d := base
+	for len(src) > 0 {
+		var length, offset int
+
+		tag := src[0]
+		switch tag & 0x03 {
+		case snappyTagLiteral:
+			x := int(tag >> 2)
+			src = src[1:]
+			if x >= 60 {
+				// length is in the next 1..4 bytes
+				nb := x - 59
+				if len(src) < nb {
+					return errSnappyCorrupt
+				}
+				x = 0
+				for i := 0; i < nb; i++ {
+					x |= int(src[i]) << (8 * i)
+				}
+				src = src[nb:]
+			}
+			length = x + 1
+			if length <= 0 || length > len(src) || length > len(out)-d {
+				return errSnappyCorrupt
+			}
+			copy(out[d:], src[:length])
+			d += length
+			src = src[length:]
+			continue
+
+		case snappyTagCopy1:
+			if len(src) < 2 {
+				return errSnappyCorrupt
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+
+		case snappyTagCopy2:
+			if len(src) < 3 {
+				return errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:3]))
+			src = src[3:]
+
+		case snappyTagCopy4:
+			if len(src) < 5 {
+				return errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:5]))
+			src = src[5:]
+		}
+
+		if offset <= 0 || offset > d || length > len(out)-d {
+			return errSnappyCorrupt
+		}
+		// regions may overlap
+		for i := 0; i < length; i++ {
+			out[d+i] = out[d-offset+i]
+		}
+		d += length
+	}
+
+	if d != len(out) {
+		return errSnappyCorrupt
+	}
+	if base > 0 {
+		copy(dst, out[base:])
+	}
+	return nil
+}
+
+func snappyHash(b []byte, i int, shift uint32) uint32 {
+	return (binary.LittleEndian.Uint32(b[i:]) * 0x1e35a7bd) >> shift
+}
+
+func snappyEmitLiteral(dst []byte, lit []byte) []byte {
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyTagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyTagLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|snappyTagLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+	return append(dst, lit...)
+}
+
+func snappyEmitCopy(dst []byte, offset, length int) []byte {
+	if offset >= 1<<16 {
+		for length > 0 {
+			l := length
+			if l > 64 {
+				l = 64
+			}
+			dst = append(dst, byte(l-1)<<2|snappyTagCopy4,
+				byte(offset), byte(offset>>8), byte(offset>>16), byte(offset>>24))
+			length -= l
+		}
+		return dst
+	}
+
+	for length >= 68 {
+		dst = append(dst, 63<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 64
+	}
+	if length > 64 {
+		// keep at least 4 bytes for the last copy
+		dst = append(dst, 59<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 60
+	}
+	if length >= 12 || offset >= 2048 {
+		return append(dst, byte(length-1)<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+	}
+	return append(dst, byte(offset>>8)<<5|byte(length-4)<<2|snappyTagCopy1, byte(offset))
+}
@@ -0,0 +1,123 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	defaultFlowHighWatermark int64         = 1000
+	defaultFlowCheckInterval time.Duration = 10 * time.Millisecond
+)
+
+func (f FlowControl) validate() (FlowControl, error) {
+	if f.Enable == false {
+		return f, nil
+	}
+	if f.HighWatermark < 1 {
+		f.HighWatermark = defaultFlowHighWatermark
+	}
+	if f.LowWatermark < 1 {
+		f.LowWatermark = f.HighWatermark / 2
+	}
+	if f.LowWatermark >= f.HighWatermark {
+		return f, errors.New("options.FlowControl.LowWatermark must be less than HighWatermark")
+	}
+	if f.CheckInterval <= 0 {
+		f.CheckInterval = defaultFlowCheckInterval
+	}
+	return f, nil
+}
+
+// mailbox is implemented by the meta process of the node. It is not a part of
+// gen.MetaProcess, so the flow control is disabled for any other implementation.
+type mailbox interface {
+	MailboxLen(to any) (int64, error)
+}
+
+// flow keeps the state of the flow control
+type flow struct {
+	options FlowControl
+	stop    chan struct{}
+	paused  int32
+	pauses  uint64
+	dropped uint64 // packets
+	bytes   uint64 // dropped bytes
+}
+
+func newFlow(options FlowControl) *flow {
+	if options.Enable == false {
+		return nil
+	}
+	return &flow{options: options, stop: make(chan struct{})}
+}
+
+// overloaded returns true if the delivering to the process must be paused.
+// The state is switched at the high/low watermarks.
+func (f *flow) overloaded(mp gen.MetaProcess, to any) bool {
+	m, ok := mp.(mailbox)
+	if ok == false {
+		return false
+	}
+	l, err := m.MailboxLen(to)
+	if err != nil {
+		// remote or unknown process, nothing to check
+		return false
+	}
+
+	if atomic.LoadInt32(&f.paused) == 1 {
+		if l > f.options.LowWatermark {
+			return true
+		}
+		atomic.StoreInt32(&f.paused, 0)
+		return false
+	}
+
+	if l <= f.options.HighWatermark {
+		return false
+	}
+	atomic.StoreInt32(&f.paused, 1)
+	atomic.AddUint64(&f.pauses, 1)
+	return true
+}
+
+// wait blocks until the delivering to the process can be resumed. Returns false
+// if the flow has been closed (the meta process is terminated).
+func (f *flow) wait(mp gen.MetaProcess, to any) bool {
+	if f.overloaded(mp, to) == false {
+		return true
+	}
+	ticker := time.NewTicker(f.options.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return false
+		case <-ticker.C:
+			if f.overloaded(mp, to) == false {
+				return true
+			}
+		}
+	}
+}
+
+// close releases the waiting reader
+func (f *flow) close() {
+	close(f.stop)
+}
+
+func (f *flow) drop(n int) {
+	atomic.AddUint64(&f.dropped, 1)
+	atomic.AddUint64(&f.bytes, uint64(n))
+}
+
+func (f *flow) inspect(info map[string]string) {
+	info["flow paused"] = fmt.Sprintf("%t", atomic.LoadInt32(&f.paused) == 1)
+	info["flow pauses"] = fmt.Sprintf("%d", atomic.LoadUint64(&f.pauses))
+	info["flow dropped"] = fmt.Sprintf("%d", atomic.LoadUint64(&f.dropped))
+	info["flow dropped bytes"] = fmt.Sprintf("%d", atomic.LoadUint64(&f.bytes))
+}
@@ -0,0 +1,23 @@
+package meta
+
+import (
+	"time"
+)
+
+// FlowControl enables the backpressure for the meta process delivering the
+// incoming data to the process. Once the mailbox of the target process exceeds
+// HighWatermark, the meta process pauses delivering until the mailbox goes
+// down to LowWatermark. The TCP connection stops reading from the socket
+// (so the remote peer is throttled by TCP itself), the UDP server drops
+// the incoming packets.
+type FlowControl struct {
+	Enable bool
+	// HighWatermark the number of messages in the mailbox of the target
+	// process that pauses delivering. Default 1000.
+	HighWatermark int64
+	// LowWatermark the number of messages in the mailbox of the target process
+	// that resumes delivering. Default HighWatermark/2.
+	LowWatermark int64
+	// CheckInterval the interval of the mailbox checks while paused. Default 10ms.
+	CheckInterval time.Duration
+}
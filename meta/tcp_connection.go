@@ -1,40 +1,22 @@
 package meta
 
 import (
+	"bufio"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/sllt/sparrow/gen"
 )
 
 func CreateTCPConnection(options TCPConnectionOptions) (gen.MetaBehavior, error) {
-	var conn net.Conn
-
-	hp := net.JoinHostPort(options.Host, strconv.Itoa(int(options.Port)))
-	if options.CertManager != nil {
-		config := &tls.Config{
-			GetCertificate:     options.CertManager.GetCertificateFunc(),
-			InsecureSkipVerify: options.InsecureSkipVerify,
-		}
-		c, err := tls.Dial("tcp", hp, config)
-		if err != nil {
-			return nil, err
-		}
-		conn = c
-	} else {
-		dialer := net.Dialer{}
-		if options.KeepAlivePeriod > 0 {
-			dialer.KeepAlive = options.KeepAlivePeriod
-		}
-		c, err := dialer.Dial("tcp", hp)
-		if err != nil {
-			return nil, err
-		}
-		conn = c
+	framing, err := options.Framing.validate()
+	if err != nil {
+		return nil, err
 	}
 
 	if options.BufferSize < 1 {
@@ -42,19 +24,49 @@ func CreateTCPConnection(options TCPConnectionOptions) (gen.MetaBehavior, error)
 	}
 
 	c := &tcpconnection{
-		conn:       conn,
+		process:    options.Process,
 		bufpool:    options.BufferPool,
 		bufferSize: options.BufferSize,
+		framing:    framing,
 	}
 
-	if options.Process == "" {
+	if options.Reconnect.Enable {
+		// dialing is made by the meta process
+		c.reconnect = options.Reconnect.normalize()
+		c.dial = func() (net.Conn, error) {
+			return dialTCP(options, c.reconnect.DialTimeout)
+		}
+		c.stop = make(chan struct{})
 		return c, nil
 	}
 
-	c.process = options.Process
+	conn, err := dialTCP(options, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
 	return c, nil
 }
 
+func dialTCP(options TCPConnectionOptions, timeout time.Duration) (net.Conn, error) {
+	hp := net.JoinHostPort(options.Host, strconv.Itoa(int(options.Port)))
+	dialer := net.Dialer{
+		Timeout: timeout,
+	}
+	if options.CertManager != nil {
+		config := &tls.Config{
+			GetCertificate:     options.CertManager.GetCertificateFunc(),
+			InsecureSkipVerify: options.InsecureSkipVerify,
+		}
+		return tls.DialWithDialer(&dialer, "tcp", hp, config)
+	}
+
+	if options.KeepAlivePeriod > 0 {
+		dialer.KeepAlive = options.KeepAlivePeriod
+	}
+	return dialer.Dial("tcp", hp)
+}
+
 //
 // Connection gen.MetaBehavior implementation
 //
@@ -65,8 +77,20 @@ type tcpconnection struct {
 	conn       net.Conn
 	bufpool    *sync.Pool
 	bufferSize int
+	framing    TCPFraming
+	flow       *flow // accepted by the TCP server with flow control enabled
 	bytesIn    uint64
 	bytesOut   uint64
+
+	// reconnecting client
+	reconnect   TCPReconnect
+	dial        func() (net.Conn, error)
+	stop        chan struct{}
+	mutex       sync.Mutex // protects conn, pending and terminated
+	pending     [][]byte   // outgoing data buffered while disconnected
+	pendingSize int
+	terminated  bool
+	connects    uint64
 }
 
 func (t *tcpconnection) Init(process gen.MetaProcess) error {
@@ -74,18 +98,21 @@ func (t *tcpconnection) Init(process gen.MetaProcess) error {
 	return nil
 }
 
+func (t *tcpconnection) to() any {
+	if t.process == "" {
+		return t.Parent()
+	}
+	return t.process
+}
+
 func (t *tcpconnection) Start() error {
-	var to any
-	var buf []byte
+	if t.reconnect.Enable {
+		return t.startReconnect()
+	}
 
+	to := t.to()
 	id := t.ID()
 
-	if t.process == "" {
-		to = t.Parent()
-	} else {
-		to = t.process
-	}
-
 	defer func() {
 		t.conn.Close()
 		message := MessageTCPDisconnect{
@@ -97,17 +124,36 @@ func (t *tcpconnection) Start() error {
 		}
 	}()
 
+	return t.serve(t.conn, to)
+}
+
+// serve notifies about the connection and reads the data until it is closed
+func (t *tcpconnection) serve(conn net.Conn, to any) error {
+	var buf []byte
+
+	id := t.ID()
+
 	message := MessageTCPConnect{
 		ID:         id,
-		RemoteAddr: t.conn.RemoteAddr(),
-		LocalAddr:  t.conn.LocalAddr(),
+		RemoteAddr: conn.RemoteAddr(),
+		LocalAddr:  conn.LocalAddr(),
 	}
 	if err := t.Send(to, message); err != nil {
 		t.Log().Error("unable to send MessageTCPConnect to %v: %s", to, err)
 		return err
 	}
 
+	if t.framing.Mode != TCPFramingNone {
+		return t.readFrames(conn, to)
+	}
+
 	for {
+		if t.flow != nil {
+			// stop reading until the process handles its mailbox
+			if t.flow.wait(t.MetaProcess, to) == false {
+				return nil
+			}
+		}
 		if t.bufpool == nil {
 			buf = make([]byte, t.bufferSize)
 		} else {
@@ -115,7 +161,7 @@ func (t *tcpconnection) Start() error {
 		}
 
 	retry:
-		n, err := t.conn.Read(buf)
+		n, err := conn.Read(buf)
 		if err != nil {
 			if n == 0 {
 				// closed connection
@@ -141,23 +187,101 @@ func (t *tcpconnection) Start() error {
 	}
 }
 
-func (t *tcpconnection) HandleMessage(from gen.PID, message any) error {
-	switch m := message.(type) {
-	case MessageTCP:
-		l := len(m.Data)
-		lenD := l
+func (t *tcpconnection) readFrames(conn net.Conn, to any) error {
+	id := t.ID()
+	scanner := t.framing.scanner(conn, t.bufferSize)
+	for {
+		if t.flow != nil && t.flow.wait(t.MetaProcess, to) == false {
+			return nil
+		}
+		if scanner.Scan() == false {
+			break
+		}
+		frame := scanner.Bytes()
+		n := len(frame)
+
+		var buf []byte
+		if t.bufpool != nil {
+			buf = t.bufpool.Get().([]byte)
+		}
+		if cap(buf) < n {
+			if buf != nil {
+				t.bufpool.Put(buf)
+			}
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		copy(buf, frame)
+
+		message := MessageTCP{
+			ID:   id,
+			Data: buf,
+		}
+		atomic.AddUint64(&t.bytesIn, uint64(n))
+		if err := t.Send(to, message); err != nil {
+			t.Log().Error("unable to send MessageTCP: %s", err)
+			return err
+		}
+	}
+
+	switch err := scanner.Err(); err {
+	case nil:
+		// closed connection
+		return nil
+	case gen.ErrTooLarge, bufio.ErrTooLong:
+		t.Log().Error("frame exceeds the limit (%d bytes), close connection with %s",
+			t.framing.MaxFrameSize, conn.RemoteAddr())
+		return gen.ErrTooLarge
+	default:
+		// closed connection
+		return nil
+	}
+}
+
+func (t *tcpconnection) write(conn net.Conn, data []byte) error {
+	if t.framing.Mode == TCPFramingNone {
+		l := len(data)
 		for {
-			n, e := t.conn.Write(m.Data[lenD-l:])
+			n, e := conn.Write(data[len(data)-l:])
 			if e != nil {
 				return e
 			}
 			// check if something left
 			l -= n
 			if l == 0 {
-				break
+				return nil
 			}
 		}
-		atomic.AddUint64(&t.bytesOut, uint64(lenD))
+	}
+
+	buffers, err := t.framing.frame(data)
+	if err != nil {
+		t.Log().Error("unable to frame data (%d bytes): %s", len(data), err)
+		return nil
+	}
+	_, err = buffers.WriteTo(conn)
+	return err
+}
+
+func (t *tcpconnection) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case MessageTCP:
+		conn := t.conn
+		if t.reconnect.Enable {
+			if conn = t.connected(m.Data); conn == nil {
+				// buffered or dropped
+				return nil
+			}
+		}
+		if err := t.write(conn, m.Data); err != nil {
+			if t.reconnect.Enable {
+				t.Log().Warning("unable to write to tcp socket: %s", err)
+				t.lost(conn, m.Data)
+				return nil
+			}
+			return err
+		}
+		atomic.AddUint64(&t.bytesOut, uint64(len(m.Data)))
 		if t.bufpool != nil {
 			t.bufpool.Put(m.Data)
 		}
@@ -172,7 +296,20 @@ func (t *tcpconnection) HandleCall(from gen.PID, ref gen.Ref, request any) (any,
 }
 
 func (t *tcpconnection) Terminate(reason error) {
-	defer t.conn.Close()
+	if t.reconnect.Enable {
+		t.mutex.Lock()
+		t.terminated = true
+		close(t.stop)
+		if t.conn != nil {
+			t.conn.Close()
+		}
+		t.mutex.Unlock()
+	} else {
+		defer t.conn.Close()
+	}
+	if t.flow != nil {
+		t.flow.close()
+	}
 	if reason == nil || reason == gen.TerminateReasonNormal {
 		return
 	}
@@ -180,19 +317,20 @@ func (t *tcpconnection) Terminate(reason error) {
 }
 
 func (t *tcpconnection) HandleInspect(from gen.PID, item ...string) map[string]string {
-	var to any
 	bytesIn := atomic.LoadUint64(&t.bytesIn)
 	bytesOut := atomic.LoadUint64(&t.bytesOut)
-	if t.process == "" {
-		to = t.Parent()
-	} else {
-		to = t.process
+	if t.reconnect.Enable {
+		return t.inspectReconnect(bytesIn, bytesOut)
 	}
-	return map[string]string{
+	info := map[string]string{
 		"local":     t.conn.LocalAddr().String(),
 		"remote":    t.conn.RemoteAddr().String(),
-		"process":   fmt.Sprintf("%s", to),
+		"process":   fmt.Sprintf("%s", t.to()),
 		"bytes in":  fmt.Sprintf("%d", bytesIn),
 		"bytes out": fmt.Sprintf("%d", bytesOut),
 	}
+	if t.flow != nil {
+		t.flow.inspect(info)
+	}
+	return info
 }
@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	defaultTCPMaxFrameSize int = 1048576
+)
+
+func (f TCPFraming) validate() (TCPFraming, error) {
+	switch f.Mode {
+	case TCPFramingNone, TCPFramingLine:
+	case TCPFramingLength1, TCPFramingLength2, TCPFramingLength4:
+	case TCPFramingDelimiter:
+		if len(f.Delimiter) == 0 {
+			return f, errors.New("options.Framing.Delimiter must be defined")
+		}
+	case TCPFramingCustom:
+		if f.Split == nil {
+			return f, errors.New("options.Framing.Split must be defined")
+		}
+	default:
+		return f, errors.New("unknown options.Framing.Mode")
+	}
+	if f.MaxFrameSize < 1 {
+		f.MaxFrameSize = defaultTCPMaxFrameSize
+	}
+	return f, nil
+}
+
+func (f TCPFraming) byteOrder() binary.ByteOrder {
+	if f.LittleEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// scanner returns the scanner of the frames. The limit of the scanner buffer
+// takes into account the prefix/delimiter of the frame.
+func (f TCPFraming) scanner(conn net.Conn, bufferSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(conn)
+	max := f.MaxFrameSize
+	switch f.Mode {
+	case TCPFramingLength1, TCPFramingLength2, TCPFramingLength4:
+		max += int(f.Mode)
+		scanner.Split(f.splitLength)
+	case TCPFramingLine:
+		max += 2
+		scanner.Split(bufio.ScanLines)
+	case TCPFramingDelimiter:
+		max += len(f.Delimiter)
+		scanner.Split(f.splitDelimiter)
+	case TCPFramingCustom:
+		scanner.Split(f.Split)
+	}
+	if bufferSize > max {
+		bufferSize = max
+	}
+	scanner.Buffer(make([]byte, bufferSize), max)
+	return scanner
+}
+
+func (f TCPFraming) splitLength(data []byte, atEOF bool) (int, []byte, error) {
+	prefix := int(f.Mode)
+	if len(data) < prefix {
+		return 0, nil, nil
+	}
+
+	var l int
+	switch f.Mode {
+	case TCPFramingLength1:
+		l = int(data[0])
+	case TCPFramingLength2:
+		l = int(f.byteOrder().Uint16(data))
+	default:
+		l = int(f.byteOrder().Uint32(data))
+	}
+	if l > f.MaxFrameSize {
+		return 0, nil, gen.ErrTooLarge
+	}
+	if len(data) < prefix+l {
+		return 0, nil, nil
+	}
+	return prefix + l, data[prefix : prefix+l], nil
+}
+
+func (f TCPFraming) splitDelimiter(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.Index(data, f.Delimiter); i >= 0 {
+		return i + len(f.Delimiter), data[:i], nil
+	}
+	// the rest of the data (with no delimiter) is dropped on EOF
+	return 0, nil, nil
+}
+
+// frame returns the buffers to write the outgoing data with
+func (f TCPFraming) frame(data []byte) (net.Buffers, error) {
+	switch f.Mode {
+	case TCPFramingLength1, TCPFramingLength2, TCPFramingLength4:
+		prefix := make([]byte, f.Mode)
+		switch f.Mode {
+		case TCPFramingLength1:
+			if len(data) > 0xff {
+				return nil, gen.ErrTooLarge
+			}
+			prefix[0] = byte(len(data))
+		case TCPFramingLength2:
+			if len(data) > 0xffff {
+				return nil, gen.ErrTooLarge
+			}
+			f.byteOrder().PutUint16(prefix, uint16(len(data)))
+		default:
+			if uint64(len(data)) > 0xffffffff {
+				return nil, gen.ErrTooLarge
+			}
+			f.byteOrder().PutUint32(prefix, uint32(len(data)))
+		}
+		return net.Buffers{prefix, data}, nil
+
+	case TCPFramingLine:
+		return net.Buffers{data, []byte{'\n'}}, nil
+
+	case TCPFramingDelimiter:
+		return net.Buffers{data, f.Delimiter}, nil
+
+	case TCPFramingCustom:
+		if f.Encode != nil {
+			return net.Buffers{f.Encode(data)}, nil
+		}
+	}
+	return net.Buffers{data}, nil
+}
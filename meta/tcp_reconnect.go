@@ -0,0 +1,185 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+var (
+	errTCPTerminated = errors.New("terminated")
+)
+
+const (
+	defaultTCPReconnectMinBackoff  time.Duration = 100 * time.Millisecond
+	defaultTCPReconnectMaxBackoff  time.Duration = 30 * time.Second
+	defaultTCPReconnectDialTimeout time.Duration = 5 * time.Second
+)
+
+func (r TCPReconnect) normalize() TCPReconnect {
+	if r.MinBackoff <= 0 {
+		r.MinBackoff = defaultTCPReconnectMinBackoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = defaultTCPReconnectMaxBackoff
+	}
+	if r.MaxBackoff < r.MinBackoff {
+		r.MaxBackoff = r.MinBackoff
+	}
+	if r.BufferLimit < 1 {
+		r.BufferLimit = gen.DefaultTCPBufferSize
+	}
+	if r.DialTimeout <= 0 {
+		r.DialTimeout = defaultTCPReconnectDialTimeout
+	}
+	return r
+}
+
+// startReconnect keeps the connection established until the meta process
+// is terminated
+func (t *tcpconnection) startReconnect() error {
+	to := t.to()
+	id := t.ID()
+	backoff := t.reconnect.MinBackoff
+
+	for {
+		conn, err := t.dial()
+		if err == nil {
+			err = t.attach(conn)
+		}
+		if err == errTCPTerminated {
+			return nil
+		}
+		if err != nil {
+			t.Log().Debug("unable to connect: %s (retry in %s)", err, backoff)
+			select {
+			case <-t.stop:
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > t.reconnect.MaxBackoff {
+				backoff = t.reconnect.MaxBackoff
+			}
+			continue
+		}
+
+		backoff = t.reconnect.MinBackoff
+
+		t.serve(conn, to)
+
+		t.mutex.Lock()
+		t.conn = nil
+		terminated := t.terminated
+		t.mutex.Unlock()
+		conn.Close()
+
+		message := MessageTCPDisconnect{
+			ID: id,
+		}
+		if err := t.Send(to, message); err != nil {
+			t.Log().Error("unable to send MessageTCPDisconnect to %s: %s", to, err)
+			return err
+		}
+		if terminated {
+			return nil
+		}
+
+		select {
+		case <-t.stop:
+			return nil
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// attach writes the data buffered while the meta process was disconnected and
+// makes the connection current. The connection is closed on error.
+func (t *tcpconnection) attach(conn net.Conn) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.terminated {
+		conn.Close()
+		return errTCPTerminated
+	}
+
+	for len(t.pending) > 0 {
+		data := t.pending[0]
+		if err := t.write(conn, data); err != nil {
+			// keep the rest of the data for the next connection
+			conn.Close()
+			return err
+		}
+		atomic.AddUint64(&t.bytesOut, uint64(len(data)))
+		t.pendingSize -= len(data)
+		t.pending[0] = nil
+		t.pending = t.pending[1:]
+		if t.bufpool != nil {
+			t.bufpool.Put(data)
+		}
+	}
+	t.pending = nil
+
+	t.conn = conn
+	atomic.AddUint64(&t.connects, 1)
+	return nil
+}
+
+// lost handles the failed writing to the connection. The connection is closed
+// (it will be re-established) and the data is buffered to be sent over the
+// next one.
+func (t *tcpconnection) lost(conn net.Conn, data []byte) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.conn == conn {
+		t.conn = nil
+	}
+	conn.Close()
+	t.buffer(data)
+}
+
+// connected returns the current connection. If there is no connection, the data
+// is buffered (or dropped if the buffer is full) and nil is returned.
+func (t *tcpconnection) connected(data []byte) net.Conn {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.conn != nil {
+		return t.conn
+	}
+	t.buffer(data)
+	return nil
+}
+
+func (t *tcpconnection) buffer(data []byte) {
+	if t.pendingSize+len(data) > t.reconnect.BufferLimit {
+		t.Log().Warning("outgoing buffer is full (%d bytes), data dropped", t.reconnect.BufferLimit)
+		return
+	}
+	t.pending = append(t.pending, data)
+	t.pendingSize += len(data)
+}
+
+func (t *tcpconnection) inspectReconnect(bytesIn, bytesOut uint64) map[string]string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	info := map[string]string{
+		"process":   fmt.Sprintf("%s", t.to()),
+		"bytes in":  fmt.Sprintf("%d", bytesIn),
+		"bytes out": fmt.Sprintf("%d", bytesOut),
+		"connects":  fmt.Sprintf("%d", atomic.LoadUint64(&t.connects)),
+		"buffered":  fmt.Sprintf("%d", t.pendingSize),
+	}
+	if t.conn != nil {
+		info["local"] = t.conn.LocalAddr().String()
+		info["remote"] = t.conn.RemoteAddr().String()
+	}
+	return info
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"sync"
@@ -16,6 +17,15 @@ import (
 //
 
 func CreateTCPServer(options TCPServerOptions) (gen.MetaBehavior, error) {
+	framing, err := options.Framing.validate()
+	if err != nil {
+		return nil, err
+	}
+	flowcontrol, err := options.FlowControl.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	lc := net.ListenConfig{
 		KeepAlive: -1, // disabled
 	}
@@ -55,6 +65,8 @@ func CreateTCPServer(options TCPServerOptions) (gen.MetaBehavior, error) {
 		listener:   listener,
 		procpool:   options.ProcessPool,
 		bufferSize: options.BufferSize,
+		framing:    framing,
+		flow:       flowcontrol,
 	}
 
 	return s, nil
@@ -66,6 +78,8 @@ type tcpserver struct {
 	bufpool    *sync.Pool
 	listener   net.Listener
 	bufferSize int
+	framing    TCPFraming
+	flow       FlowControl
 }
 
 func (t *tcpserver) Init(process gen.MetaProcess) error {
@@ -86,6 +100,8 @@ func (t *tcpserver) Start() error {
 			conn:       conn,
 			bufpool:    t.bufpool,
 			bufferSize: t.bufferSize,
+			framing:    t.framing,
+			flow:       newFlow(t.flow),
 		}
 		if len(t.procpool) > 0 {
 			l := len(t.procpool)
@@ -125,7 +141,12 @@ func (t *tcpserver) Terminate(reason error) {
 }
 
 func (t *tcpserver) HandleInspect(from gen.PID, item ...string) map[string]string {
-	return map[string]string{
+	info := map[string]string{
 		"listener": t.listener.Addr().String(),
 	}
+	if t.flow.Enable {
+		info["flow high watermark"] = fmt.Sprintf("%d", t.flow.HighWatermark)
+		info["flow low watermark"] = fmt.Sprintf("%d", t.flow.LowWatermark)
+	}
+	return info
 }
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"bufio"
 	"github.com/sllt/sparrow/gen"
 	"net"
 	"sync"
@@ -31,7 +32,28 @@ type TCPConnectionOptions struct {
 	BufferPool         *sync.Pool
 	KeepAlivePeriod    time.Duration
 	InsecureSkipVerify bool
+	Framing            TCPFraming
+	Reconnect          TCPReconnect
 }
+
+// TCPReconnect enables the reconnecting mode of the TCP connection. The connection
+// is dialed by the meta process, so the meta process keeps running (and its
+// alias remains the same) while the connection is being re-established.
+// MessageTCPConnect and MessageTCPDisconnect are sent on every transition.
+type TCPReconnect struct {
+	Enable bool
+	// MinBackoff the delay before the attempt to reconnect. Default 100ms.
+	MinBackoff time.Duration
+	// MaxBackoff the delay is doubled after every failed attempt up to this
+	// limit. Default 30s.
+	MaxBackoff time.Duration
+	// BufferLimit the limit of the outgoing data (in bytes) buffered while
+	// disconnected. The data exceeding it is dropped. Default gen.DefaultTCPBufferSize.
+	BufferLimit int
+	// DialTimeout the timeout of the connection attempt. Default 5s.
+	DialTimeout time.Duration
+}
+
 type TCPServerOptions struct {
 	Host               string
 	Port               uint16
@@ -41,4 +63,43 @@ type TCPServerOptions struct {
 	BufferPool         *sync.Pool
 	KeepAlivePeriod    time.Duration
 	InsecureSkipVerify bool
+	Framing            TCPFraming
+	FlowControl        FlowControl
+}
+
+type TCPFramingMode int
+
+const (
+	// TCPFramingNone the data is delivered in chunks as it is read from the socket
+	TCPFramingNone TCPFramingMode = 0
+	// TCPFramingLength1, TCPFramingLength2, TCPFramingLength4 the frame is prefixed
+	// with its length (1, 2 or 4 bytes). The length doesn't include the prefix.
+	TCPFramingLength1 TCPFramingMode = 1
+	TCPFramingLength2 TCPFramingMode = 2
+	TCPFramingLength4 TCPFramingMode = 4
+	// TCPFramingLine the frame is terminated with "\n" (optional "\r" is stripped)
+	TCPFramingLine TCPFramingMode = 10
+	// TCPFramingDelimiter the frame is terminated with TCPFraming.Delimiter
+	TCPFramingDelimiter TCPFramingMode = 11
+	// TCPFramingCustom the frames are split by TCPFraming.Split
+	TCPFramingCustom TCPFramingMode = 12
+)
+
+// TCPFraming defines how the stream of the TCP connection is split into the
+// frames. Every frame is delivered as a MessageTCP with no prefix or delimiter.
+// The outgoing MessageTCP is framed the same way.
+type TCPFraming struct {
+	Mode TCPFramingMode
+	// LittleEndian the byte order of the length prefix. Big endian by default.
+	LittleEndian bool
+	// Delimiter terminates the frame for TCPFramingDelimiter mode
+	Delimiter []byte
+	// Split splits the stream into the frames for TCPFramingCustom mode
+	Split bufio.SplitFunc
+	// Encode frames the outgoing data for TCPFramingCustom mode. The data is
+	// written as is if it is nil.
+	Encode func(data []byte) []byte
+	// MaxFrameSize the connection is closed if the incoming frame exceeds
+	// this limit. Default is 1MB.
+	MaxFrameSize int
 }
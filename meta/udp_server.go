@@ -20,6 +20,11 @@ const (
 )
 
 func CreateUDPServer(options UDPServerOptions) (gen.MetaBehavior, error) {
+	flowcontrol, err := options.FlowControl.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	hp := net.JoinHostPort(options.Host, strconv.Itoa(int(options.Port)))
 	pc, err := net.ListenPacket("udp", hp)
 	if err != nil {
@@ -27,7 +32,8 @@ func CreateUDPServer(options UDPServerOptions) (gen.MetaBehavior, error) {
 	}
 
 	mb := &udpserver{
-		pc: pc,
+		pc:   pc,
+		flow: newFlow(flowcontrol),
 	}
 	if options.BufferSize < 1 {
 		options.BufferSize = defaultUDPBufferSize
@@ -57,6 +63,7 @@ type udpserver struct {
 	bufferSize int
 	process    gen.Atom
 	bufpool    *sync.Pool
+	flow       *flow
 
 	bytesIn  uint64
 	bytesOut uint64
@@ -87,6 +94,14 @@ func (u *udpserver) Start() error {
 			buf = b.([]byte)
 		}
 		n, addr, err := u.pc.ReadFrom(buf)
+		if n > 0 && u.flow != nil && u.flow.overloaded(u.MetaProcess, to) {
+			// the process is behind, drop the packet
+			u.flow.drop(n)
+			if u.bufpool != nil {
+				u.bufpool.Put(buf)
+			}
+			n = 0
+		}
 		if n > 0 {
 			packet := MessageUDP{
 				ID:   id,
@@ -138,10 +153,14 @@ func (u *udpserver) HandleInspect(from gen.PID, item ...string) map[string]strin
 	} else {
 		to = u.process
 	}
-	return map[string]string{
+	info := map[string]string{
 		"listener":  u.pc.LocalAddr().String(),
 		"process":   fmt.Sprintf("%s", to),
 		"bytes in":  fmt.Sprintf("%d", bytesIn),
 		"bytes out": fmt.Sprintf("%d", bytesOut),
 	}
+	if u.flow != nil {
+		u.flow.inspect(info)
+	}
+	return info
 }
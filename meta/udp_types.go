@@ -8,11 +8,12 @@ import (
 )
 
 type UDPServerOptions struct {
-	Host       string
-	Port       uint16
-	Process    gen.Atom
-	BufferSize int
-	BufferPool *sync.Pool
+	Host        string
+	Port        uint16
+	Process     gen.Atom
+	BufferSize  int
+	BufferPool  *sync.Pool
+	FlowControl FlowControl
 }
 
 type MessageUDP struct {
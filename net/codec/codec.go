@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+const (
+	SDF     string = "sdf"
+	JSON    string = "json"
+	MsgPack string = "msgpack"
+)
+
+// Codec encodes/decodes the messages sent over the network connection.
+// The codec is chosen during the handshake. Options are the encoding/decoding
+// caches of the connection, codecs other than SDF may ignore them.
+type Codec interface {
+	Name() string
+	Encode(message any, b *lib.Buffer, options sdf.Options) error
+	Decode(packet []byte, options sdf.Options) (any, []byte, error)
+}
+
+var codecs sync.Map
+
+func init() {
+	for _, c := range []Codec{codecSDF{}, codecJSON{}, codecMsgPack{}} {
+		if err := Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register registers the codec, so it can be chosen during the handshake
+func Register(c Codec) error {
+	if c.Name() == "" {
+		return fmt.Errorf("empty codec name")
+	}
+	if _, exist := codecs.LoadOrStore(c.Name(), c); exist {
+		return gen.ErrTaken
+	}
+	return nil
+}
+
+// Get returns the registered codec with the given name
+func Get(name string) (Codec, bool) {
+	v, found := codecs.Load(name)
+	if found == false {
+		return nil, false
+	}
+	return v.(Codec), true
+}
+
+type codecSDF struct{}
+
+func (codecSDF) Name() string {
+	return SDF
+}
+
+func (codecSDF) Encode(message any, b *lib.Buffer, options sdf.Options) error {
+	return sdf.Encode(message, b, options)
+}
+
+func (codecSDF) Decode(packet []byte, options sdf.Options) (any, []byte, error) {
+	return sdf.Decode(packet, options)
+}
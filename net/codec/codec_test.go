@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+type testCodecStruct struct {
+	A int
+	B string
+	C []byte
+	D map[int]string
+	E any
+	F *testCodecStruct
+	G []any
+	H error
+	I time.Time
+	J [2]uint16
+	K map[gen.Atom]float64
+}
+
+type testCodecUnregistered struct{}
+
+func init() {
+	if err := sdf.RegisterTypeOf(testCodecStruct{}); err != nil && err != gen.ErrTaken {
+		panic(err)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	pid := gen.PID{Node: "node@localhost", ID: 1000, Creation: 1}
+	values := []any{
+		"string",
+		true,
+		int8(-8),
+		int(-1),
+		uint64(1 << 63),
+		float32(1.5),
+		3.14,
+		[]byte{1, 2, 3},
+		gen.Atom("atom"),
+		pid,
+		gen.Ref{Node: "node@localhost", Creation: 1, ID: [3]uint64{1, 2, 3}},
+		gen.ProcessID{Name: "name", Node: "node@localhost"},
+		gen.Event{Name: "event", Node: "node@localhost"},
+		tm,
+		[]string{"a", "b"},
+		map[string]int{"a": 1},
+		[]any{1, "a", nil},
+		gen.ErrProcessUnknown,
+		gen.TerminateReasonNormal,
+		testCodecStruct{
+			A: 1,
+			B: "b",
+			C: []byte{},
+			D: map[int]string{1: "a", 2: "b"},
+			E: pid,
+			F: &testCodecStruct{A: 2, C: []byte{1}, I: tm},
+			G: []any{"a", 1.5, []int{1, 2}},
+			I: tm,
+			J: [2]uint16{1, 2},
+			K: map[gen.Atom]float64{"x": 0.5},
+		},
+	}
+
+	for _, name := range []string{SDF, JSON, MsgPack} {
+		c, found := Get(name)
+		if found == false {
+			t.Fatalf("codec %s is not registered", name)
+		}
+		for _, value := range values {
+			b := lib.TakeBuffer()
+			if err := c.Encode(value, b, sdf.Options{}); err != nil {
+				t.Fatalf("%s: %#v: %s", name, value, err)
+			}
+			decoded, tail, err := c.Decode(b.B, sdf.Options{})
+			lib.ReleaseBuffer(b)
+			if err != nil {
+				t.Fatalf("%s: %#v: %s", name, value, err)
+			}
+			if len(tail) > 0 {
+				t.Fatalf("%s: %#v: extra bytes %v", name, value, tail)
+			}
+			if reflect.DeepEqual(decoded, value) == false {
+				t.Fatalf("%s: incorrect value %#v (exp: %#v)", name, decoded, value)
+			}
+		}
+	}
+
+	// registered errors keep their identity
+	for _, name := range []string{JSON, MsgPack} {
+		c, _ := Get(name)
+		b := lib.TakeBuffer()
+		c.Encode(gen.ErrProcessUnknown, b, sdf.Options{})
+		decoded, _, err := c.Decode(b.B, sdf.Options{})
+		lib.ReleaseBuffer(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != gen.ErrProcessUnknown {
+			t.Fatalf("%s: registered error must be the same", name)
+		}
+
+		b = lib.TakeBuffer()
+		c.Encode(errors.New("custom"), b, sdf.Options{})
+		decoded, _, err = c.Decode(b.B, sdf.Options{})
+		lib.ReleaseBuffer(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, ok := decoded.(error); ok == false || e.Error() != "custom" {
+			t.Fatalf("%s: incorrect error %#v", name, decoded)
+		}
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	for _, name := range []string{JSON, MsgPack} {
+		c, _ := Get(name)
+		b := lib.TakeBuffer()
+		if err := c.Encode(testCodecUnregistered{}, b, sdf.Options{}); err == nil {
+			t.Fatalf("%s: unregistered type must not be encoded", name)
+		}
+		if err := c.Encode(nil, b, sdf.Options{}); err == nil {
+			t.Fatalf("%s: nil must not be encoded", name)
+		}
+		lib.ReleaseBuffer(b)
+	}
+
+	if _, _, err := (codecJSON{}).Decode([]byte(`{"t":"#unknown","v":1}`), sdf.Options{}); err == nil {
+		t.Fatal("unknown type must not be decoded")
+	}
+	if _, _, err := (codecJSON{}).Decode([]byte(`{"t":"int","v":"a"}`), sdf.Options{}); err == nil {
+		t.Fatal("mismatched value must not be decoded")
+	}
+	if _, _, err := (codecMsgPack{}).Decode([]byte{0x82, 0xa1}, sdf.Options{}); err == nil {
+		t.Fatal("truncated data must not be decoded")
+	}
+
+	if err := Register(codecJSON{}); err != gen.ErrTaken {
+		t.Fatal("codec must be registered once")
+	}
+}
+
+func TestCodecMsgPackFormat(t *testing.T) {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+	if err := (codecMsgPack{}).Encode("a", b, sdf.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	// {"t": "string", "v": "a"}
+	expected := []byte{0x82,
+		0xa1, 't', 0xa6, 's', 't', 'r', 'i', 'n', 'g',
+		0xa1, 'v', 0xa1, 'a',
+	}
+	if bytes.Equal(b.B, expected) == false {
+		t.Fatalf("incorrect encoding: %#v", b.B)
+	}
+}
+
+func Test_typeByName(t *testing.T) {
+	types := []reflect.Type{
+		reflect.TypeOf(map[string][]map[gen.Atom]int{}),
+		reflect.TypeOf([3]*testCodecStruct{}),
+		reflect.TypeOf(map[[2]int]string{}),
+		reflect.TypeOf([]any{}),
+	}
+	for _, typ := range types {
+		name, err := typeName(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tn, err := typeByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tn != typ {
+			t.Fatalf("%s: incorrect type %v (exp: %v)", name, tn, typ)
+		}
+	}
+}
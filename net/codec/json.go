@@ -0,0 +1,49 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+// codecJSON is intended for debugging. It ignores the encoding/decoding
+// caches of the connection.
+type codecJSON struct{}
+
+func (codecJSON) Name() string {
+	return JSON
+}
+
+func (codecJSON) Encode(message any, b *lib.Buffer, _ sdf.Options) error {
+	if message == nil {
+		return fmt.Errorf("nothing to encode")
+	}
+	tree, err := toTyped(reflect.ValueOf(message))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	b.Append(data)
+	return nil
+}
+
+func (codecJSON) Decode(packet []byte, _ sdf.Options) (any, []byte, error) {
+	var tree any
+	dec := json.NewDecoder(bytes.NewReader(packet))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, nil, err
+	}
+	v, err := fromTree(tree, typeAny)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.Interface(), packet[dec.InputOffset():], nil
+}
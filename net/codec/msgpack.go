@@ -0,0 +1,332 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+var (
+	errMsgPackEOD = fmt.Errorf("msgpack: end of data")
+)
+
+// codecMsgPack encodes messages with MessagePack, so they can be processed
+// by the services written in other languages. It ignores the encoding/decoding
+// caches of the connection.
+type codecMsgPack struct{}
+
+func (codecMsgPack) Name() string {
+	return MsgPack
+}
+
+func (codecMsgPack) Encode(message any, b *lib.Buffer, _ sdf.Options) error {
+	if message == nil {
+		return fmt.Errorf("nothing to encode")
+	}
+	tree, err := toTyped(reflect.ValueOf(message))
+	if err != nil {
+		return err
+	}
+	return msgpackEncode(tree, b)
+}
+
+func (codecMsgPack) Decode(packet []byte, _ sdf.Options) (any, []byte, error) {
+	tree, tail, err := msgpackDecode(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := fromTree(tree, typeAny)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.Interface(), tail, nil
+}
+
+func msgpackEncode(tree any, b *lib.Buffer) error {
+	switch v := tree.(type) {
+	case nil:
+		b.AppendByte(0xc0)
+
+	case bool:
+		if v {
+			b.AppendByte(0xc3)
+		} else {
+			b.AppendByte(0xc2)
+		}
+
+	case int64:
+		if v >= 0 {
+			return msgpackEncode(uint64(v), b)
+		}
+		switch {
+		case v >= -32:
+			b.AppendByte(byte(v))
+		case v >= math.MinInt8:
+			b.Append([]byte{0xd0, byte(v)})
+		case v >= math.MinInt16:
+			b.AppendByte(0xd1)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(v))
+		case v >= math.MinInt32:
+			b.AppendByte(0xd2)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(v))
+		default:
+			b.AppendByte(0xd3)
+			binary.BigEndian.PutUint64(b.Extend(8), uint64(v))
+		}
+
+	case uint64:
+		switch {
+		case v < 128:
+			b.AppendByte(byte(v))
+		case v <= math.MaxUint8:
+			b.Append([]byte{0xcc, byte(v)})
+		case v <= math.MaxUint16:
+			b.AppendByte(0xcd)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(v))
+		case v <= math.MaxUint32:
+			b.AppendByte(0xce)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(v))
+		default:
+			b.AppendByte(0xcf)
+			binary.BigEndian.PutUint64(b.Extend(8), v)
+		}
+
+	case float64:
+		b.AppendByte(0xcb)
+		binary.BigEndian.PutUint64(b.Extend(8), math.Float64bits(v))
+
+	case string:
+		l := len(v)
+		switch {
+		case l < 32:
+			b.AppendByte(0xa0 | byte(l))
+		case l <= math.MaxUint8:
+			b.Append([]byte{0xd9, byte(l)})
+		case l <= math.MaxUint16:
+			b.AppendByte(0xda)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(l))
+		default:
+			b.AppendByte(0xdb)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(l))
+		}
+		b.AppendString(v)
+
+	case []byte:
+		l := len(v)
+		switch {
+		case l <= math.MaxUint8:
+			b.Append([]byte{0xc4, byte(l)})
+		case l <= math.MaxUint16:
+			b.AppendByte(0xc5)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(l))
+		default:
+			b.AppendByte(0xc6)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(l))
+		}
+		b.Append(v)
+
+	case []any:
+		l := len(v)
+		switch {
+		case l < 16:
+			b.AppendByte(0x90 | byte(l))
+		case l <= math.MaxUint16:
+			b.AppendByte(0xdc)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(l))
+		default:
+			b.AppendByte(0xdd)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(l))
+		}
+		for _, item := range v {
+			if err := msgpackEncode(item, b); err != nil {
+				return err
+			}
+		}
+
+	case map[string]any:
+		l := len(v)
+		switch {
+		case l < 16:
+			b.AppendByte(0x80 | byte(l))
+		case l <= math.MaxUint16:
+			b.AppendByte(0xde)
+			binary.BigEndian.PutUint16(b.Extend(2), uint16(l))
+		default:
+			b.AppendByte(0xdf)
+			binary.BigEndian.PutUint32(b.Extend(4), uint32(l))
+		}
+		for _, k := range sortedKeys(v) {
+			msgpackEncode(k, b)
+			if err := msgpackEncode(v[k], b); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("msgpack: unsupported value %T", tree)
+	}
+	return nil
+}
+
+func msgpackDecode(packet []byte) (any, []byte, error) {
+	if len(packet) == 0 {
+		return nil, nil, errMsgPackEOD
+	}
+	t := packet[0]
+	packet = packet[1:]
+
+	// fixed formats
+	switch {
+	case t < 0x80:
+		return uint64(t), packet, nil
+	case t >= 0xe0:
+		return int64(int8(t)), packet, nil
+	case t&0xf0 == 0x80:
+		return msgpackDecodeMap(int(t&0x0f), packet)
+	case t&0xf0 == 0x90:
+		return msgpackDecodeArray(int(t&0x0f), packet)
+	case t&0xe0 == 0xa0:
+		return msgpackDecodeString(int(t&0x1f), packet)
+	}
+
+	// length (or value) of the given size
+	size := func(n int) (uint64, []byte, error) {
+		if len(packet) < n {
+			return 0, nil, errMsgPackEOD
+		}
+		switch n {
+		case 1:
+			return uint64(packet[0]), packet[1:], nil
+		case 2:
+			return uint64(binary.BigEndian.Uint16(packet)), packet[2:], nil
+		case 4:
+			return uint64(binary.BigEndian.Uint32(packet)), packet[4:], nil
+		}
+		return binary.BigEndian.Uint64(packet), packet[8:], nil
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, packet, nil
+	case 0xc2:
+		return false, packet, nil
+	case 0xc3:
+		return true, packet, nil
+
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint 8/16/32/64
+		return size(1 << (t - 0xcc))
+
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int 8/16/32/64
+		n, tail, err := size(1 << (t - 0xd0))
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t {
+		case 0xd0:
+			return int64(int8(n)), tail, nil
+		case 0xd1:
+			return int64(int16(n)), tail, nil
+		case 0xd2:
+			return int64(int32(n)), tail, nil
+		}
+		return int64(n), tail, nil
+
+	case 0xca:
+		n, tail, err := size(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return float64(math.Float32frombits(uint32(n))), tail, nil
+	case 0xcb:
+		n, tail, err := size(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(n), tail, nil
+
+	case 0xd9, 0xda, 0xdb: // str 8/16/32
+		l, tail, err := size(1 << (t - 0xd9))
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackDecodeString(int(l), tail)
+
+	case 0xc4, 0xc5, 0xc6: // bin 8/16/32
+		l, tail, err := size(1 << (t - 0xc4))
+		if err != nil {
+			return nil, nil, err
+		}
+		if uint64(len(tail)) < l {
+			return nil, nil, errMsgPackEOD
+		}
+		return append([]byte{}, tail[:l]...), tail[l:], nil
+
+	case 0xdc, 0xdd: // array 16/32
+		l, tail, err := size(2 << (t - 0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackDecodeArray(int(l), tail)
+
+	case 0xde, 0xdf: // map 16/32
+		l, tail, err := size(2 << (t - 0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return msgpackDecodeMap(int(l), tail)
+	}
+
+	return nil, nil, fmt.Errorf("msgpack: unsupported type 0x%02x", t)
+}
+
+func msgpackDecodeString(l int, packet []byte) (any, []byte, error) {
+	if len(packet) < l {
+		return nil, nil, errMsgPackEOD
+	}
+	return string(packet[:l]), packet[l:], nil
+}
+
+func msgpackDecodeArray(l int, packet []byte) (any, []byte, error) {
+	if l > len(packet) {
+		// every item takes one byte at least
+		return nil, nil, errMsgPackEOD
+	}
+	list := make([]any, l)
+	for i := range list {
+		item, tail, err := msgpackDecode(packet)
+		if err != nil {
+			return nil, nil, err
+		}
+		list[i] = item
+		packet = tail
+	}
+	return list, packet, nil
+}
+
+func msgpackDecodeMap(l int, packet []byte) (any, []byte, error) {
+	if l*2 > len(packet) {
+		return nil, nil, errMsgPackEOD
+	}
+	m := make(map[string]any, l)
+	for i := 0; i < l; i++ {
+		key, tail, err := msgpackDecode(packet)
+		if err != nil {
+			return nil, nil, err
+		}
+		k, ok := key.(string)
+		if ok == false {
+			return nil, nil, fmt.Errorf("msgpack: unsupported map key %T", key)
+		}
+		item, tail, err := msgpackDecode(tail)
+		if err != nil {
+			return nil, nil, err
+		}
+		m[k] = item
+		packet = tail
+	}
+	return m, packet, nil
+}
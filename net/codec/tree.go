@@ -0,0 +1,581 @@
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+// JSON and MessagePack codecs encode the message as a tree of the generic
+// values: nil, bool, int64, uint64, float64, string, []byte, []any and
+// map[string]any. Structs are encoded as maps of the exported fields, maps
+// with non-string keys as lists of the [key, value] pairs. Values of the
+// interface types (including the message itself) are encoded along with
+// the name of their type as {"t": <name>, "v": <value>}. Registered types
+// have the names given by sdf.RegisterTypeOf, so the remote side must
+// register them as well.
+
+const (
+	typeNameError = "error"
+)
+
+var (
+	typeAny   = reflect.TypeOf((*any)(nil)).Elem()
+	typeError = reflect.TypeOf((*error)(nil)).Elem()
+	typeTime  = reflect.TypeOf(time.Time{})
+
+	builtinTypes = map[string]reflect.Type{
+		"any":           typeAny,
+		typeNameError:   typeError,
+		"bool":          reflect.TypeOf(false),
+		"string":        reflect.TypeOf(""),
+		"int":           reflect.TypeOf(int(0)),
+		"int8":          reflect.TypeOf(int8(0)),
+		"int16":         reflect.TypeOf(int16(0)),
+		"int32":         reflect.TypeOf(int32(0)),
+		"int64":         reflect.TypeOf(int64(0)),
+		"uint":          reflect.TypeOf(uint(0)),
+		"uint8":         reflect.TypeOf(uint8(0)),
+		"uint16":        reflect.TypeOf(uint16(0)),
+		"uint32":        reflect.TypeOf(uint32(0)),
+		"uint64":        reflect.TypeOf(uint64(0)),
+		"float32":       reflect.TypeOf(float32(0)),
+		"float64":       reflect.TypeOf(float64(0)),
+		"[]byte":        reflect.TypeOf([]byte{}),
+		"time.Time":     typeTime,
+		"gen.Atom":      reflect.TypeOf(gen.Atom("")),
+		"gen.PID":       reflect.TypeOf(gen.PID{}),
+		"gen.ProcessID": reflect.TypeOf(gen.ProcessID{}),
+		"gen.Ref":       reflect.TypeOf(gen.Ref{}),
+		"gen.Alias":     reflect.TypeOf(gen.Alias{}),
+		"gen.Event":     reflect.TypeOf(gen.Event{}),
+	}
+	builtinNames = map[reflect.Type]string{}
+)
+
+func init() {
+	for name, t := range builtinTypes {
+		builtinNames[t] = name
+	}
+}
+
+func typeName(t reflect.Type) (string, error) {
+	if name, found := builtinNames[t]; found {
+		return name, nil
+	}
+	if name, found := sdf.RegisteredTypeName(t); found {
+		return name, nil
+	}
+	if t.Implements(typeError) {
+		return typeNameError, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		elem, err := typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case reflect.Array:
+		elem, err := typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), nil
+	case reflect.Map:
+		key, err := typeName(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "map[" + key + "]" + elem, nil
+	case reflect.Pointer:
+		elem, err := typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "*" + elem, nil
+	}
+	return "", fmt.Errorf("type %v must be registered", t)
+}
+
+func typeByName(name string) (reflect.Type, error) {
+	if t, found := builtinTypes[name]; found {
+		return t, nil
+	}
+	if strings.HasPrefix(name, "#") {
+		if t, found := sdf.RegisteredType(name); found {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown type %s", name)
+	}
+
+	switch {
+	case strings.HasPrefix(name, "[]"):
+		elem, err := typeByName(name[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+
+	case strings.HasPrefix(name, "["):
+		i := strings.IndexByte(name, ']')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(name[1:i])
+		if err != nil {
+			break
+		}
+		elem, err := typeByName(name[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(n, elem), nil
+
+	case strings.HasPrefix(name, "map["):
+		// key may have the brackets as well
+		depth := 1
+		for i := 4; i < len(name); i++ {
+			switch name[i] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+			if depth > 0 {
+				continue
+			}
+			key, err := typeByName(name[4:i])
+			if err != nil {
+				return nil, err
+			}
+			elem, err := typeByName(name[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			return reflect.MapOf(key, elem), nil
+		}
+
+	case strings.HasPrefix(name, "*"):
+		elem, err := typeByName(name[1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	}
+	return nil, fmt.Errorf("unknown type %s", name)
+}
+
+// toTyped returns the tree of the value along with the name of its type
+func toTyped(v reflect.Value) (any, error) {
+	name, err := typeName(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if name == typeNameError {
+		value = v.Interface().(error).Error()
+	} else {
+		value, err = toTree(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{"t": name, "v": value}, nil
+}
+
+func toTree(v reflect.Value) (any, error) {
+	t := v.Type()
+	switch t {
+	case typeTime:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case typeError:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface().(error).Error(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toTyped(v.Elem())
+
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+		fallthrough
+
+	case reflect.Array:
+		list := make([]any, v.Len())
+		for i := range list {
+			item, err := toTree(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Key().Kind() == reflect.String {
+			m := make(map[string]any, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				item, err := toTree(iter.Value())
+				if err != nil {
+					return nil, err
+				}
+				m[iter.Key().String()] = item
+			}
+			return m, nil
+		}
+		pairs := make([]any, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toTree(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			item, err := toTree(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, []any{key, item})
+		}
+		return pairs, nil
+
+	case reflect.Struct:
+		m := make(map[string]any, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.IsExported() == false {
+				continue
+			}
+			item, err := toTree(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t, sf.Name, err)
+			}
+			m[sf.Name] = item
+		}
+		return m, nil
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toTree(v.Elem())
+	}
+
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// fromTree returns the value of the given type decoded from the tree
+func fromTree(tree any, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if tree == nil {
+		return v, nil
+	}
+
+	switch t {
+	case typeTime:
+		s, ok := tree.(string)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		tm, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+
+	case typeError:
+		s, ok := tree.(string)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		err, found := sdf.RegisteredError(s)
+		if found == false {
+			err = errors.New(s)
+		}
+		v.Set(reflect.ValueOf(err))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		m, ok := tree.(map[string]any)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		name, _ := m["t"].(string)
+		vt, err := typeByName(name)
+		if err != nil {
+			return v, err
+		}
+		if vt.AssignableTo(t) == false {
+			return v, fmt.Errorf("type %s can not be used as %v", name, t)
+		}
+		value, err := fromTree(m["v"], vt)
+		if err != nil {
+			return v, err
+		}
+		v.Set(value)
+
+	case reflect.Bool:
+		b, ok := tree.(bool)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(tree)
+		if err != nil {
+			return v, err
+		}
+		if v.OverflowInt(n) {
+			return v, fmt.Errorf("value %d overflows %v", n, t)
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toUint64(tree)
+		if err != nil {
+			return v, err
+		}
+		if v.OverflowUint(n) {
+			return v, fmt.Errorf("value %d overflows %v", n, t)
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(tree)
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+
+	case reflect.String:
+		s, ok := tree.(string)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		v.SetString(s)
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch b := tree.(type) {
+			case []byte:
+				v.SetBytes(append([]byte{}, b...))
+				return v, nil
+			case string:
+				// JSON encodes binaries with base64
+				bin, err := base64.StdEncoding.DecodeString(b)
+				if err != nil {
+					return v, err
+				}
+				v.SetBytes(bin)
+				return v, nil
+			}
+		}
+		list, ok := tree.([]any)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		v.Set(reflect.MakeSlice(t, len(list), len(list)))
+		for i := range list {
+			item, err := fromTree(list[i], t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(i).Set(item)
+		}
+
+	case reflect.Array:
+		list, ok := tree.([]any)
+		if ok == false || len(list) != t.Len() {
+			return v, errMismatch(tree, t)
+		}
+		for i := range list {
+			item, err := fromTree(list[i], t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(i).Set(item)
+		}
+
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		if t.Key().Kind() == reflect.String {
+			m, ok := tree.(map[string]any)
+			if ok == false {
+				return v, errMismatch(tree, t)
+			}
+			for k, x := range m {
+				item, err := fromTree(x, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), item)
+			}
+			return v, nil
+		}
+		pairs, ok := tree.([]any)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		for _, p := range pairs {
+			pair, ok := p.([]any)
+			if ok == false || len(pair) != 2 {
+				return v, errMismatch(p, t)
+			}
+			key, err := fromTree(pair[0], t.Key())
+			if err != nil {
+				return v, err
+			}
+			item, err := fromTree(pair[1], t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.SetMapIndex(key, item)
+		}
+
+	case reflect.Struct:
+		m, ok := tree.(map[string]any)
+		if ok == false {
+			return v, errMismatch(tree, t)
+		}
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.IsExported() == false {
+				continue
+			}
+			x, found := m[sf.Name]
+			if found == false {
+				continue
+			}
+			item, err := fromTree(x, sf.Type)
+			if err != nil {
+				return v, fmt.Errorf("%s.%s: %w", t, sf.Name, err)
+			}
+			v.Field(i).Set(item)
+		}
+
+	case reflect.Pointer:
+		item, err := fromTree(tree, t.Elem())
+		if err != nil {
+			return v, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(item)
+		v.Set(p)
+
+	default:
+		return v, fmt.Errorf("unsupported type %v", t)
+	}
+
+	return v, nil
+}
+
+func errMismatch(tree any, t reflect.Type) error {
+	return fmt.Errorf("value of type %T can not be decoded as %v", tree, t)
+}
+
+func toInt64(tree any) (int64, error) {
+	switch n := tree.(type) {
+	case int64:
+		return n, nil
+	case uint64:
+		if int64(n) < 0 {
+			return 0, fmt.Errorf("value %d overflows int64", n)
+		}
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case json.Number:
+		return strconv.ParseInt(string(n), 10, 64)
+	}
+	return 0, fmt.Errorf("value of type %T is not a number", tree)
+}
+
+func toUint64(tree any) (uint64, error) {
+	switch n := tree.(type) {
+	case int64:
+		if n < 0 {
+			return 0, fmt.Errorf("negative value %d for the unsigned type", n)
+		}
+		return uint64(n), nil
+	case uint64:
+		return n, nil
+	case float64:
+		return uint64(n), nil
+	case json.Number:
+		return strconv.ParseUint(string(n), 10, 64)
+	}
+	return 0, fmt.Errorf("value of type %T is not a number", tree)
+}
+
+func toFloat64(tree any) (float64, error) {
+	switch n := tree.(type) {
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("value of type %T is not a number", tree)
+}
+
+// sortedKeys is used to make the encoding of maps deterministic
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -16,23 +16,29 @@ import (
 func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.HandshakeOptions) (gen.HandshakeResult, error) {
 	var result gen.HandshakeResult
 	var salt string
+	version := handshakeVersion1
 	result.HandshakeVersion = h.Version()
 
-	v, tail, err := h.readMessage(conn, time.Second, nil)
+	v, _, tail, err := h.readMessage(conn, time.Second, nil)
 	if err != nil {
 		return result, err
 	}
 	switch m := v.(type) {
 	case MessageHello:
-		hash := sha256.New()
-		hash.Write([]byte(fmt.Sprintf("%s:%s", m.Salt, options.Cookie)))
-
-		if m.Digest != fmt.Sprintf("%x", hash.Sum(nil)) {
+		cookie, generation, found := matchCookie(options, m.Digest, func(cookie string) string {
+			hash := sha256.New()
+			hash.Write([]byte(fmt.Sprintf("%s:%s", m.Salt, cookie)))
+			return fmt.Sprintf("%x", hash.Sum(nil))
+		})
+		if found == false {
 			return result, fmt.Errorf("incorrect digest (accept stage 'hello')")
 		}
+		// the rest of the handshake goes with the cookie the peer has
+		options.Cookie = cookie
+		result.CookieGeneration = generation
 
 		salt = lib.RandomString(64)
-		hash = sha256.New()
+		hash := sha256.New()
 		hash.Write([]byte(fmt.Sprintf("%s:%s:%s", salt, m.Digest, options.Cookie)))
 
 		hello := MessageHello{
@@ -47,21 +53,34 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 			hello.DigestCert = fmt.Sprintf("%x", hash.Sum(nil))
 		}
 
-		if err := h.writeMessage(conn, hello); err != nil {
+		if m.DigestCert == helloVersionMark {
+			version = handshakeVersion
+		}
+		if err := h.writeMessage(conn, version, hello); err != nil {
 			return result, err
 		}
 
 	case MessageJoin:
 		result.Peer = m.Node
-		hash := sha256.New()
-		hash.Write([]byte(fmt.Sprintf("%s:%s:%s", m.ConnectionID, m.Salt, options.Cookie)))
-		if m.Digest != fmt.Sprintf("%x", hash.Sum(nil)) {
+		cookie, generation, found := matchCookie(options, m.Digest, func(cookie string) string {
+			hash := sha256.New()
+			hash.Write([]byte(fmt.Sprintf("%s:%s:%s", m.ConnectionID, m.Salt, cookie)))
+			return fmt.Sprintf("%x", hash.Sum(nil))
+		})
+		if found == false {
 			return result, fmt.Errorf("incorrect join digest")
 		}
+		options.Cookie = cookie
+		result.CookieGeneration = generation
+		if options.Authorize != nil {
+			if _, err := options.Authorize(conn, m.Node, options.Flags); err != nil {
+				return result, err
+			}
+		}
 		result.ConnectionID = m.ConnectionID
 		result.Custom = ConnectionOptions{}
 
-		hash = sha256.New()
+		hash := sha256.New()
 		hash.Write([]byte(fmt.Sprintf("%s:%s", m.Digest, options.Cookie)))
 		accept := MessageAccept{
 			Digest: fmt.Sprintf("%x", hash.Sum(nil)),
@@ -72,7 +91,7 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 			hash.Write(fp)
 			accept.DigestCert = fmt.Sprintf("%x", hash.Sum(nil))
 		}
-		if err := h.writeMessage(conn, accept); err != nil {
+		if err := h.writeMessage(conn, version, accept); err != nil {
 			return result, err
 		}
 		if len(h.atom_mapping) > 0 {
@@ -88,12 +107,12 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 	}
 
 	// wait for the introduce message
-	v, tail, err = h.readMessage(conn, time.Second, nil)
+	v, _, tail, err = h.readMessage(conn, time.Second, nil)
 	if err != nil {
 		return result, err
 	}
 
-	intro, ok := v.(MessageIntroduce)
+	intro, ok := introduce(v)
 	if ok == false {
 		return result, fmt.Errorf("malformed handshake Introduce message")
 	}
@@ -107,18 +126,36 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 		return result, fmt.Errorf("incorrect digest (accept stage 'introduce')")
 	}
 
+	if err := sdf.CheckTypeFingerprints(intro.RegCache, intro.Types); err != nil {
+		return result, err
+	}
+
+	codec, err := h.chooseCodec(intro.Codecs)
+	if err != nil {
+		return result, err
+	}
+	dictionaries := h.chooseDictionaries(intro.Dictionaries)
+
+	flags := options.Flags
+	if options.Authorize != nil {
+		flags, err = options.Authorize(conn, intro.Node, flags)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	accept := MessageAccept{}
 	accept.ID = lib.RandomString(32)
 	accept.PoolSize = h.poolsize
 	accept.PoolDSN = append(accept.PoolDSN, conn.LocalAddr().String())
-	if err := h.writeMessage(conn, accept); err != nil {
+	if err := h.writeMessage(conn, version, accept); err != nil {
 		return result, err
 	}
 
-	intro2 := MessageIntroduce{
+	intro2 := MessageIntroduceV2{
 		Node:     node.Name(),
 		Version:  node.Version(),
-		Flags:    options.Flags,
+		Flags:    flags,
 		Creation: node.Creation(),
 
 		MaxMessageSize: options.MaxMessageSize,
@@ -126,13 +163,24 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 		AtomCache: sdf.GetAtomCache(),
 		RegCache:  sdf.GetRegCache(),
 		ErrCache:  sdf.GetErrCache(),
+		Types:     sdf.GetTypeFingerprints(),
+		Codecs:    []string{codec},
+		Heartbeat: true,
+		PoolSync:  true,
+	}
+	for _, dict := range dictionaries {
+		intro2.Dictionaries = append(intro2.Dictionaries, lib.DictionaryID(dict))
 	}
-	if err := h.writeMessage(conn, intro2); err != nil {
+	var message any = intro2
+	if version == handshakeVersion1 {
+		message = intro2.v1()
+	}
+	if err := h.writeMessage(conn, version, message); err != nil {
 		return result, err
 	}
 
 	// wait for the accept message
-	v, tail, err = h.readMessage(conn, time.Second, tail)
+	v, _, tail, err = h.readMessage(conn, time.Second, tail)
 	if err != nil {
 		return result, err
 	}
@@ -147,12 +195,17 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 	result.PeerCreation = intro.Creation
 	result.PeerFlags = intro.Flags
 	result.PeerMaxMessageSize = intro.MaxMessageSize
-	result.NodeFlags = options.Flags
+	result.NodeFlags = flags
 	result.NodeMaxMessageSize = options.MaxMessageSize
 	result.Tail = tail
 
 	custom := ConnectionOptions{
 		PoolSize:        h.poolsize,
+		Codec:           codec,
+		Heartbeat:       intro.Heartbeat,
+		PoolSync:        intro.PoolSync,
+		Dictionaries:    dictionaries,
+		Sampler:         h.sampler,
 		EncodeAtomCache: h.makeEncodeAtomCache(intro2.AtomCache),
 		EncodeRegCache:  h.makeEncodeRegCache(intro2.RegCache),
 		EncodeErrCache:  h.makeEncodeErrCache(intro2.ErrCache),
@@ -165,6 +218,20 @@ func (h *handshake) Accept(node gen.NodeHandshake, conn net.Conn, options gen.Ha
 	return result, nil
 }
 
+// matchCookie returns the cookie (the current one or one of the previous ones)
+// the peer made the digest with
+func matchCookie(options gen.HandshakeOptions, digest string, makeDigest func(string) string) (string, int, bool) {
+	if makeDigest(options.Cookie) == digest {
+		return options.Cookie, options.CookieGeneration, true
+	}
+	for _, c := range options.PreviousCookies {
+		if makeDigest(c.Cookie) == digest {
+			return c.Cookie, c.Generation, true
+		}
+	}
+	return "", 0, false
+}
+
 func (h *handshake) getLocalTLSFingerprint(conn net.Conn, cm gen.CertManager) []byte {
 	if _, tls := conn.(*tls.Conn); tls == false {
 		return nil
@@ -3,6 +3,7 @@ package handshake
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/sllt/sparrow/net/codec"
 	"github.com/sllt/sparrow/net/sdf"
 	"math"
 	"net"
@@ -18,6 +19,9 @@ type handshake struct {
 	disable_fingerprint bool
 	flags               gen.NetworkFlags
 	atom_mapping        map[gen.Atom]gen.Atom
+	codecs              []string
+	dictionaries        [][]byte
+	sampler             *lib.DictionarySampler
 }
 
 type Options struct {
@@ -25,6 +29,19 @@ type Options struct {
 	DisableTLSFingerprint bool
 	NetworkFlags          gen.NetworkFlags
 	AtomMapping           map[gen.Atom]gen.Atom
+	// Codecs the names of the codecs (see net/codec) this node supports in
+	// order of preference. The first codec of the dialing node supported by
+	// the accepting one is used for the connection. Default: SDF only.
+	Codecs []string
+	// Dictionaries the shared dictionaries for the message compression in
+	// order of preference (see lib.TrainDictionary). The dictionaries both
+	// nodes have are negotiated by their identifiers (lib.DictionaryID).
+	// The first common one of the dialing node is used for the compression
+	// if the compression type supports it (see lib.DictCompressor).
+	Dictionaries [][]byte
+	// DictionarySampler collects the samples of the outgoing messages of
+	// every connection to train a new dictionary.
+	DictionarySampler *lib.DictionarySampler
 }
 
 func Create(options Options) gen.NetworkHandshake {
@@ -38,12 +55,63 @@ func Create(options Options) gen.NetworkHandshake {
 			mapping[k] = v
 		}
 	}
+	codecs := []string{codec.SDF}
+	if len(options.Codecs) > 0 {
+		codecs = append([]string{}, options.Codecs...)
+	}
 	return &handshake{
 		poolsize:            options.PoolSize,
 		disable_fingerprint: options.DisableTLSFingerprint,
 		flags:               options.NetworkFlags,
 		atom_mapping:        mapping,
+		codecs:              codecs,
+		dictionaries:        options.Dictionaries,
+		sampler:             options.DictionarySampler,
+	}
+}
+
+// chooseCodec returns the first codec of the given list (peer's preference)
+// supported by this node
+func (h *handshake) chooseCodec(codecs []string) (string, error) {
+	if len(codecs) == 0 {
+		// peer has no preferences
+		codecs = []string{codec.SDF}
+	}
+	for _, name := range codecs {
+		for _, n := range h.codecs {
+			if n != name {
+				continue
+			}
+			if _, found := codec.Get(name); found {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no common codec (peer: %v, node: %v)", codecs, h.codecs)
+}
+
+// dictionaryIDs returns the identifiers of the dictionaries of this node
+func (h *handshake) dictionaryIDs() []uint32 {
+	var ids []uint32
+	for _, dict := range h.dictionaries {
+		ids = append(ids, lib.DictionaryID(dict))
+	}
+	return ids
+}
+
+// chooseDictionaries returns the dictionaries of this node with the given
+// identifiers in the order of the given list
+func (h *handshake) chooseDictionaries(ids []uint32) [][]byte {
+	var dicts [][]byte
+	for _, id := range ids {
+		for _, dict := range h.dictionaries {
+			if lib.DictionaryID(dict) == id {
+				dicts = append(dicts, dict)
+				break
+			}
+		}
 	}
+	return dicts
 }
 
 func (h *handshake) NetworkFlags() gen.NetworkFlags {
@@ -57,13 +125,13 @@ func (h *handshake) Version() gen.Version {
 	}
 }
 
-func (h *handshake) writeMessage(conn net.Conn, message any) error {
+func (h *handshake) writeMessage(conn net.Conn, version byte, message any) error {
 	buf := lib.TakeBuffer()
 	defer lib.ReleaseBuffer(buf)
 
 	buf.Allocate(6)
 	buf.B[0] = handshakeMagic
-	buf.B[1] = handshakeVersion
+	buf.B[1] = version
 
 	if err := sdf.Encode(message, buf, sdf.Options{}); err != nil {
 		return err
@@ -86,7 +154,9 @@ func (h *handshake) writeMessage(conn net.Conn, message any) error {
 	return nil
 }
 
-func (h *handshake) readMessage(conn net.Conn, timeout time.Duration, chunk []byte) (any, []byte, error) {
+// readMessage reads the message of any supported handshake version. Returns
+// the version of the message along with it.
+func (h *handshake) readMessage(conn net.Conn, timeout time.Duration, chunk []byte) (any, byte, []byte, error) {
 	var b [4096]byte
 
 	if timeout == 0 {
@@ -102,7 +172,7 @@ func (h *handshake) readMessage(conn net.Conn, timeout time.Duration, chunk []by
 
 			n, err := conn.Read(b[:])
 			if err != nil {
-				return nil, nil, err
+				return nil, 0, nil, err
 			}
 
 			chunk = append(chunk, b[:n]...)
@@ -110,15 +180,16 @@ func (h *handshake) readMessage(conn net.Conn, timeout time.Duration, chunk []by
 		}
 
 		if chunk[0] != handshakeMagic {
-			return nil, nil, fmt.Errorf("malformed handshake packet")
+			return nil, 0, nil, fmt.Errorf("malformed handshake packet")
 		}
-		if chunk[1] != handshakeVersion {
-			return nil, nil, fmt.Errorf("mismatch handshake version")
+		version := chunk[1]
+		if version != handshakeVersion && version != handshakeVersion1 {
+			return nil, 0, nil, fmt.Errorf("mismatch handshake version")
 		}
 
 		l := int(binary.BigEndian.Uint32(chunk[2:6]))
 		if l > math.MaxUint16 {
-			return nil, nil, fmt.Errorf("too long handshake message")
+			return nil, 0, nil, fmt.Errorf("too long handshake message")
 		}
 
 		if len(chunk) < 6+l {
@@ -126,7 +197,8 @@ func (h *handshake) readMessage(conn net.Conn, timeout time.Duration, chunk []by
 			continue
 		}
 
-		return sdf.Decode(chunk[6:], sdf.Options{})
+		v, tail, err := sdf.Decode(chunk[6:], sdf.Options{})
+		return v, version, tail, err
 	}
 }
 
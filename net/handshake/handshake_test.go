@@ -0,0 +1,176 @@
+package handshake
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+type testNode struct {
+	name gen.Atom
+}
+
+func (n testNode) Name() gen.Atom       { return n.name }
+func (n testNode) Creation() int64      { return 1 }
+func (n testNode) Version() gen.Version { return gen.Version{Name: "test"} }
+
+func testDigest(format string, args ...any) string {
+	hash := sha256.New()
+	hash.Write([]byte(fmt.Sprintf(format, args...)))
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func TestHandshakeVersion(t *testing.T) {
+	cookie := "123"
+	options := gen.HandshakeOptions{Cookie: cookie}
+	h := Create(Options{}).(*handshake)
+
+	t.Run("current", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := h.Accept(testNode{"acceptor@localhost"}, c2, options)
+			errs <- err
+		}()
+		result, err := h.Start(testNode{"dialer@localhost"}, c1, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		if result.Peer != "acceptor@localhost" {
+			t.Fatalf("incorrect peer %s", result.Peer)
+		}
+		opts := result.Custom.(ConnectionOptions)
+		if opts.Heartbeat == false || opts.PoolSync == false {
+			t.Fatalf("heartbeat and pool sync must be supported by the peer: %#v", opts)
+		}
+	})
+
+	// the node of the previous version speaks handshakeVersion1 only
+	t.Run("previous dialer", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := h.Accept(testNode{"acceptor@localhost"}, c2, options)
+			errs <- err
+		}()
+
+		salt := lib.RandomString(64)
+		hello := MessageHello{Salt: salt, Digest: testDigest("%s:%s", salt, cookie)}
+		if err := h.writeMessage(c1, handshakeVersion1, hello); err != nil {
+			t.Fatal(err)
+		}
+		v, version, _, err := h.readMessage(c1, time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hello2, ok := v.(MessageHello)
+		if ok == false || version != handshakeVersion1 {
+			t.Fatalf("expected hello of version %d, got %d %#v", handshakeVersion1, version, v)
+		}
+
+		intro := MessageIntroduce{
+			Node:   "dialer@localhost",
+			Digest: testDigest("%s:%s", hello2.Salt, cookie),
+		}
+		if err := h.writeMessage(c1, handshakeVersion1, intro); err != nil {
+			t.Fatal(err)
+		}
+		if v, _, _, err := h.readMessage(c1, time.Second, nil); err != nil {
+			t.Fatal(err)
+		} else if _, ok := v.(MessageAccept); ok == false {
+			t.Fatalf("expected accept, got %#v", v)
+		}
+		v, version, _, err = h.readMessage(c1, time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v.(MessageIntroduce); ok == false || version != handshakeVersion1 {
+			t.Fatalf("expected introduce of version %d, got %d %#v", handshakeVersion1, version, v)
+		}
+		if err := h.writeMessage(c1, handshakeVersion1, MessageAccept{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("previous acceptor", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		type start struct {
+			result gen.HandshakeResult
+			err    error
+		}
+		starts := make(chan start, 1)
+		go func() {
+			result, err := h.Start(testNode{"dialer@localhost"}, c1, options)
+			starts <- start{result, err}
+		}()
+
+		v, _, _, err := h.readMessage(c2, time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hello, ok := v.(MessageHello)
+		if ok == false {
+			t.Fatalf("expected hello, got %#v", v)
+		}
+		salt := lib.RandomString(64)
+		hello2 := MessageHello{
+			Salt:   salt,
+			Digest: testDigest("%s:%s:%s", salt, hello.Digest, cookie),
+		}
+		if err := h.writeMessage(c2, handshakeVersion1, hello2); err != nil {
+			t.Fatal(err)
+		}
+
+		v, version, _, err := h.readMessage(c2, time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v.(MessageIntroduce); ok == false || version != handshakeVersion1 {
+			t.Fatalf("expected introduce of version %d, got %d %#v", handshakeVersion1, version, v)
+		}
+		if err := h.writeMessage(c2, handshakeVersion1, MessageAccept{ID: "id", PoolSize: 1}); err != nil {
+			t.Fatal(err)
+		}
+		intro := MessageIntroduce{Node: "acceptor@localhost"}
+		if err := h.writeMessage(c2, handshakeVersion1, intro); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := h.readMessage(c2, time.Second, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		s := <-starts
+		if s.err != nil {
+			t.Fatal(s.err)
+		}
+		if s.result.Peer != "acceptor@localhost" || s.result.ConnectionID != "id" {
+			t.Fatalf("incorrect result %#v", s.result)
+		}
+		// the node of the previous version doesn't reply to the pings and
+		// doesn't confirm the sync of the pool links
+		opts := s.result.Custom.(ConnectionOptions)
+		if opts.Heartbeat || opts.PoolSync {
+			t.Fatalf("heartbeat and pool sync must not be supported by the peer: %#v", opts)
+		}
+	})
+}
@@ -22,12 +22,12 @@ func (h *handshake) Join(node gen.NodeHandshake, conn net.Conn, id string, optio
 		Digest:       digest,
 	}
 
-	if err := h.writeMessage(conn, message); err != nil {
+	if err := h.writeMessage(conn, handshakeVersion1, message); err != nil {
 		conn.Close()
 		return nil, err
 	}
 
-	v, tail, err := h.readMessage(conn, time.Second, nil)
+	v, _, tail, err := h.readMessage(conn, time.Second, nil)
 	if err != nil {
 		conn.Close()
 		return nil, err
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"github.com/sllt/sparrow/net/sdf"
+	"io"
 	"net"
 	"time"
 
@@ -24,16 +25,22 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 	digest := fmt.Sprintf("%x", hash.Sum(nil))
 
 	hello := MessageHello{
-		Salt:   salt,
-		Digest: digest,
+		Salt:       salt,
+		Digest:     digest,
+		DigestCert: helloVersionMark,
 	}
 
-	if err := h.writeMessage(conn, hello); err != nil {
+	if err := h.writeMessage(conn, handshakeVersion1, hello); err != nil {
 		return result, err
 	}
 
-	v, tail, err := h.readMessage(conn, time.Second, nil)
+	// the accepting node of the previous version replies with handshakeVersion1
+	v, version, tail, err := h.readMessage(conn, time.Second, nil)
 	if err != nil {
+		if err == io.EOF {
+			// the peer closes the connection if it doesn't accept the digest
+			return result, gen.ErrDigestMismatch
+		}
 		return result, err
 	}
 
@@ -45,7 +52,7 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 	hash.Write([]byte(fmt.Sprintf("%s:%s:%s", hello2.Salt, hello.Digest, options.Cookie)))
 
 	if hello2.Digest != fmt.Sprintf("%x", hash.Sum(nil)) {
-		return result, fmt.Errorf("incorrect digest")
+		return result, gen.ErrDigestMismatch
 	}
 
 	if fp := h.getRemoteTLSFingerprint(conn); fp != nil {
@@ -57,7 +64,7 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 		}
 	}
 
-	intro := MessageIntroduce{
+	intro := MessageIntroduceV2{
 		Node:     node.Name(),
 		Version:  node.Version(),
 		Flags:    options.Flags,
@@ -68,18 +75,28 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 		AtomCache: sdf.GetAtomCache(),
 		RegCache:  sdf.GetRegCache(),
 		ErrCache:  sdf.GetErrCache(),
+		Types:     sdf.GetTypeFingerprints(),
+		Codecs:    h.codecs,
+
+		Dictionaries: h.dictionaryIDs(),
+		Heartbeat:    true,
+		PoolSync:     true,
 	}
 
 	hash = sha256.New()
 	hash.Write([]byte(fmt.Sprintf("%s:%s", hello2.Salt, options.Cookie)))
 	intro.Digest = fmt.Sprintf("%x", hash.Sum(nil))
 
-	if err := h.writeMessage(conn, intro); err != nil {
+	var message any = intro
+	if version == handshakeVersion1 {
+		message = intro.v1()
+	}
+	if err := h.writeMessage(conn, version, message); err != nil {
 		return result, err
 	}
 
 	// waiting for Accept message
-	v, tail, err = h.readMessage(conn, time.Second, tail)
+	v, _, tail, err = h.readMessage(conn, time.Second, tail)
 	if err != nil {
 		return result, err
 	}
@@ -90,12 +107,12 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 	}
 
 	// waiting for Intro message
-	v, tail, err = h.readMessage(conn, time.Second, tail)
+	v, _, tail, err = h.readMessage(conn, time.Second, tail)
 	if err != nil {
 		return result, err
 	}
 
-	intro2, ok := v.(MessageIntroduce)
+	intro2, ok := introduce(v)
 	if ok == false {
 		return result, fmt.Errorf("malformed handshake Introduce message")
 	}
@@ -104,8 +121,25 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 		return result, fmt.Errorf("malformed handshake Introduce message (same name)")
 	}
 
+	if err := sdf.CheckTypeFingerprints(intro2.RegCache, intro2.Types); err != nil {
+		return result, err
+	}
+
+	codec, err := h.chooseCodec(intro2.Codecs)
+	if err != nil {
+		return result, err
+	}
+
+	flags := options.Flags
+	if options.Authorize != nil {
+		flags, err = options.Authorize(conn, intro2.Node, flags)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	// everything looks good. just send an Accept message
-	if err := h.writeMessage(conn, MessageAccept{}); err != nil {
+	if err := h.writeMessage(conn, version, MessageAccept{}); err != nil {
 		return result, err
 	}
 
@@ -115,13 +149,19 @@ func (h *handshake) Start(node gen.NodeHandshake, conn net.Conn, options gen.Han
 	result.PeerCreation = intro2.Creation
 	result.PeerFlags = intro2.Flags
 	result.PeerMaxMessageSize = intro2.MaxMessageSize
-	result.NodeFlags = options.Flags
+	result.NodeFlags = flags
+	result.CookieGeneration = options.CookieGeneration
 	result.NodeMaxMessageSize = options.MaxMessageSize
 	result.Tail = tail
 
 	custom := ConnectionOptions{
 		PoolSize:        accept.PoolSize,
 		PoolDSN:         accept.PoolDSN,
+		Codec:           codec,
+		Heartbeat:       intro2.Heartbeat,
+		PoolSync:        intro2.PoolSync,
+		Dictionaries:    h.chooseDictionaries(intro2.Dictionaries),
+		Sampler:         h.sampler,
 		EncodeAtomCache: h.makeEncodeAtomCache(intro.AtomCache),
 		EncodeRegCache:  h.makeEncodeRegCache(intro.RegCache),
 		EncodeErrCache:  h.makeEncodeErrCache(intro.ErrCache),
@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
 	"github.com/sllt/sparrow/net/sdf"
 	"sync"
 )
@@ -10,8 +11,18 @@ const (
 	handshakeName    string = "EHS"
 	handshakeRelease string = "R1" // Sparrow Handshake (Rev.1)
 
-	handshakeMagic   byte = 87
-	handshakeVersion byte = 1
+	handshakeMagic byte = 87
+	// handshakeVersion the nodes are introduced with MessageIntroduceV2
+	handshakeVersion byte = 2
+	// handshakeVersion1 the nodes are introduced with MessageIntroduce. Used
+	// with the nodes of the previous version.
+	handshakeVersion1 byte = 1
+
+	// helloVersionMark is sent by the dialing node in MessageHello.DigestCert
+	// (it has no certificate digest to send) to let the accepting node know
+	// it supports handshakeVersion. The nodes of the previous version ignore it.
+	// The accepting node replies with the Hello message of handshakeVersion.
+	helloVersionMark string = "v2"
 
 	defaultPoolSize int = 3
 )
@@ -20,6 +31,8 @@ var (
 	DefaultPoolSize int = 1
 )
 
+// MessageHello starts the handshake. The accepting node sends DigestCert
+// if the connection is TLS, the dialing node sends helloVersionMark there.
 type MessageHello struct {
 	Salt       string
 	Digest     string
@@ -33,6 +46,7 @@ type MessageJoin struct {
 	Digest       string
 }
 
+// MessageIntroduce introduces the node of handshakeVersion1
 type MessageIntroduce struct {
 	Node     gen.Atom
 	Version  gen.Version
@@ -47,6 +61,73 @@ type MessageIntroduce struct {
 	Digest    string
 }
 
+// MessageIntroduceV2 introduces the node of handshakeVersion. It is encoded
+// as a tagged struct, so the new fields are added with no need to change the
+// handshake version: the node skips the fields unknown to it.
+type MessageIntroduceV2 struct {
+	Node     gen.Atom         `sdf:"1"`
+	Version  gen.Version      `sdf:"2"`
+	Flags    gen.NetworkFlags `sdf:"3"`
+	Creation int64            `sdf:"4"`
+
+	MaxMessageSize int `sdf:"5"`
+
+	AtomCache map[uint16]gen.Atom `sdf:"6"`
+	RegCache  map[uint16]string   `sdf:"7"`
+	ErrCache  map[uint16]error    `sdf:"8"`
+	Digest    string              `sdf:"9"`
+	// Types fingerprints of the registered types (RegCache id => fingerprint)
+	Types map[uint16]string `sdf:"10"`
+	// Codecs supported by the dialing node in order of preference,
+	// the accepting node replies with the chosen one
+	Codecs []string `sdf:"11"`
+	// Dictionaries identifiers of the compression dictionaries of the dialing
+	// node, the accepting node replies with the ones it has as well
+	Dictionaries []uint32 `sdf:"12"`
+	// Heartbeat the node replies to the heartbeat pings (see gen.NetworkHeartbeat)
+	Heartbeat bool `sdf:"13"`
+	// PoolSync the node confirms the sync of the pool links, so the pool is
+	// resized with no risk the ordered messages overtake each other
+	PoolSync bool `sdf:"14"`
+}
+
+// v1 returns the introduce message for the node of handshakeVersion1
+func (m MessageIntroduceV2) v1() MessageIntroduce {
+	return MessageIntroduce{
+		Node:           m.Node,
+		Version:        m.Version,
+		Flags:          m.Flags,
+		Creation:       m.Creation,
+		MaxMessageSize: m.MaxMessageSize,
+		AtomCache:      m.AtomCache,
+		RegCache:       m.RegCache,
+		ErrCache:       m.ErrCache,
+		Digest:         m.Digest,
+	}
+}
+
+// introduce returns the introduce message of any handshake version as
+// MessageIntroduceV2
+func introduce(v any) (MessageIntroduceV2, bool) {
+	switch m := v.(type) {
+	case MessageIntroduceV2:
+		return m, true
+	case MessageIntroduce:
+		return MessageIntroduceV2{
+			Node:           m.Node,
+			Version:        m.Version,
+			Flags:          m.Flags,
+			Creation:       m.Creation,
+			MaxMessageSize: m.MaxMessageSize,
+			AtomCache:      m.AtomCache,
+			RegCache:       m.RegCache,
+			ErrCache:       m.ErrCache,
+			Digest:         m.Digest,
+		}, true
+	}
+	return MessageIntroduceV2{}, false
+}
+
 type MessageAccept struct {
 	ID         string
 	PoolSize   int
@@ -58,6 +139,17 @@ type MessageAccept struct {
 type ConnectionOptions struct {
 	PoolSize int
 	PoolDSN  []string
+	Codec    string
+	// Heartbeat the peer replies to the heartbeat pings. The nodes of the
+	// previous version don't, so the heartbeat is disabled for them.
+	Heartbeat bool
+	// PoolSync the peer confirms the sync of the pool links
+	PoolSync bool
+
+	// Dictionaries shared compression dictionaries (the first one is used
+	// for the compression)
+	Dictionaries [][]byte
+	Sampler      *lib.DictionarySampler
 
 	EncodeAtomCache *sync.Map
 	EncodeRegCache  *sync.Map
@@ -73,6 +165,7 @@ func init() {
 		MessageHello{},
 		MessageJoin{},
 		MessageIntroduce{},
+		MessageIntroduceV2{},
 		MessageAccept{},
 	}
 
@@ -0,0 +1,205 @@
+package proto
+
+import (
+	"math/bits"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+// adaptive compression keeps the observed compression ratio and cost of the
+// candidates (compression type and level) for every message type and size
+// class (power of two), and picks the one that takes less time to send the
+// message: compressed size / bandwidth + compression time. If sending the
+// message uncompressed takes less time, the message is not compressed.
+
+const (
+	adaptiveMinSize = 64  // smaller messages are never compressed
+	adaptiveWarmup  = 3   // measurements of every candidate before choosing
+	adaptiveProbe   = 32  // every N-th message measures the next candidate
+	adaptiveAlpha   = 0.2 // weight of the new measurement
+)
+
+type adaptiveKey struct {
+	t    reflect.Type
+	size int
+}
+
+type adaptiveCandidate struct {
+	ctype gen.CompressionType
+	level gen.CompressionLevel
+
+	n     int
+	ratio float64 // compressed size / original size
+	cost  float64 // nanoseconds per original byte
+}
+
+type adaptiveClass struct {
+	candidates []*adaptiveCandidate
+	messages   uint64
+	probe      int
+}
+
+type adaptiveCompression struct {
+	sync.Mutex
+	nsPerByte float64 // time of sending a byte
+	classes   map[adaptiveKey]*adaptiveClass
+	skipped   uint64
+}
+
+func newAdaptiveCompression() *adaptiveCompression {
+	bandwidth := gen.DefaultAdaptiveCompressionBandwidth
+	if bandwidth < 1 {
+		bandwidth = 125_000_000
+	}
+	return &adaptiveCompression{
+		nsPerByte: float64(time.Second) / float64(bandwidth),
+		classes:   make(map[adaptiveKey]*adaptiveClass),
+	}
+}
+
+func adaptiveCandidates(compression gen.Compression) []*adaptiveCandidate {
+	ctype := compression.Type
+	if _, found := lib.CompressorByName(string(ctype)); found == false {
+		ctype = gen.CompressionTypeGZIP
+	}
+
+	candidates := []*adaptiveCandidate{{ctype: ctype, level: compression.Level}}
+	switch ctype {
+	case gen.CompressionTypeGZIP, gen.CompressionTypeZLIB, gen.CompressionTypeDeflate:
+		// levels make sense for DEFLATE-based algorithms only
+		for _, level := range []gen.CompressionLevel{
+			gen.CompressionDefault,
+			gen.CompressionBestSpeed,
+			gen.CompressionBestSize,
+		} {
+			if level == compression.Level {
+				continue
+			}
+			candidates = append(candidates, &adaptiveCandidate{ctype: ctype, level: level})
+		}
+	}
+	if ctype != gen.CompressionTypeSnappy {
+		candidates = append(candidates, &adaptiveCandidate{ctype: gen.CompressionTypeSnappy})
+	}
+	return candidates
+}
+
+// choose returns the candidate for compressing the message of the given size.
+// Returns nil if the message must be sent uncompressed.
+func (a *adaptiveCompression) choose(message any, size int,
+	compression gen.Compression) (*adaptiveClass, *adaptiveCandidate) {
+
+	a.Lock()
+	defer a.Unlock()
+
+	if size < adaptiveMinSize {
+		a.skipped++
+		return nil, nil
+	}
+
+	key := adaptiveKey{t: reflect.TypeOf(message), size: 1 << (bits.Len(uint(size)) - 1)}
+	class, found := a.classes[key]
+	if found == false {
+		class = &adaptiveClass{candidates: adaptiveCandidates(compression)}
+		a.classes[key] = class
+	}
+	class.messages++
+
+	for _, c := range class.candidates {
+		if c.n < adaptiveWarmup {
+			return class, c
+		}
+	}
+
+	if class.messages%adaptiveProbe == 0 {
+		// keep the measurements up to date
+		class.probe = (class.probe + 1) % len(class.candidates)
+		return class, class.candidates[class.probe]
+	}
+
+	best := a.best(class)
+	if best == nil {
+		a.skipped++
+	}
+	return class, best
+}
+
+// best returns the candidate that takes less time to send a byte. Returns nil
+// if the compression doesn't pay off.
+func (a *adaptiveCompression) best(class *adaptiveClass) *adaptiveCandidate {
+	var best *adaptiveCandidate
+	min := a.nsPerByte // uncompressed
+	for _, c := range class.candidates {
+		if c.n == 0 {
+			continue
+		}
+		if t := c.ratio*a.nsPerByte + c.cost; t < min {
+			min = t
+			best = c
+		}
+	}
+	return best
+}
+
+// update keeps the result of the compression
+func (a *adaptiveCompression) update(c *adaptiveCandidate, in int, out int, elapsed time.Duration) {
+	if in == 0 {
+		return
+	}
+	ratio := float64(out) / float64(in)
+	cost := float64(elapsed) / float64(in)
+
+	a.Lock()
+	defer a.Unlock()
+
+	if c.n == 0 {
+		c.ratio = ratio
+		c.cost = cost
+	} else {
+		c.ratio += adaptiveAlpha * (ratio - c.ratio)
+		c.cost += adaptiveAlpha * (cost - c.cost)
+	}
+	c.n++
+}
+
+func (a *adaptiveCompression) info(info *gen.CompressionInfo) {
+	a.Lock()
+	defer a.Unlock()
+
+	info.Skipped = a.skipped
+	for key, class := range a.classes {
+		decision := gen.CompressionDecision{
+			MessageType: "nil",
+			Size:        key.size,
+			Messages:    class.messages,
+		}
+		if key.t != nil {
+			decision.MessageType = key.t.String()
+		}
+
+		c := a.best(class)
+		if c != nil {
+			decision.Enable = true
+		} else {
+			// report the measurements of the configured compression
+			c = class.candidates[0]
+		}
+		decision.Type = c.ctype
+		decision.Level = c.level
+		decision.Ratio = c.ratio
+		decision.Cost = int64(c.cost * 1024)
+		info.Decisions = append(info.Decisions, decision)
+	}
+
+	sort.Slice(info.Decisions, func(i, j int) bool {
+		if info.Decisions[i].MessageType == info.Decisions[j].MessageType {
+			return info.Decisions[i].Size < info.Decisions[j].Size
+		}
+		return info.Decisions[i].MessageType < info.Decisions[j].MessageType
+	})
+}
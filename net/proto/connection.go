@@ -3,6 +3,7 @@ package proto
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/sllt/sparrow/net/codec"
 	"github.com/sllt/sparrow/net/sdf"
 	"io"
 	"math"
@@ -41,19 +42,36 @@ type connection struct {
 
 	handshakeVersion gen.Version
 	protoVersion     gen.Version
+	cookieGeneration int
 
 	pool_dsn  []string
 	pool_size int
+	pool_sync bool // the peer confirms the sync of the pool links
 
 	pool_mutex sync.RWMutex
 	pool       []*pool_item
 
+	dial     gen.NetworkDial // set if the connection was established by this node
+	layout   sync.RWMutex    // held by the senders, locked while the pool is changing
+	poolSync poolSync
+	resizing sync.Mutex
+	scaling  gen.NetworkPoolScaling
+
 	recvQueues        []lib.QueueMPSC
 	allocatedInQueues int64
 
+	codec         codec.Codec
 	encodeOptions sdf.Options
 	decodeOptions sdf.Options
 
+	// shared compression dictionaries
+	dictionary   []byte
+	dictionaryID uint32
+	dictionaries map[uint32][]byte
+	sampler      *lib.DictionarySampler
+
+	adaptive *adaptiveCompression
+
 	requestsMutex sync.RWMutex
 	requests      map[gen.Ref]chan MessageResult
 
@@ -64,9 +82,23 @@ type connection struct {
 	transitIn   uint64
 	transitOut  uint64
 
+	compressedMessages uint64
+	compressedBytesIn  uint64
+	compressedBytesOut uint64
+
+	faults         faultsStats
+	heartbeat      *heartbeat
+	bandwidth      bandwidth
+	queuePeak      int64
+	queueDropped   uint64
+	sendQueueLimit int
+
 	order      uint32
 	terminated bool
 	wg         sync.WaitGroup
+
+	reasonMutex sync.Mutex
+	reason      error // the reason the connection is terminated by itself
 }
 
 type pool_item struct {
@@ -74,6 +106,10 @@ type pool_item struct {
 	fl         io.Writer
 	timer      *time.Timer
 	handling   atomic.Bool
+	queue      sendQueue
+	delayed    delayLine // fault injection
+	retired    atomic.Bool
+	left       chan struct{} // closed once the peer stopped using the retired link
 }
 
 //
@@ -105,11 +141,12 @@ func (c *connection) Info() gen.RemoteNodeInfo {
 
 		HandshakeVersion: c.handshakeVersion,
 		ProtoVersion:     c.protoVersion,
+		Codec:            c.codec.Name(),
+		CookieGeneration: c.cookieGeneration,
 
 		NetworkFlags: c.peer_flags,
 
-		PoolSize: c.pool_size,
-		PoolDSN:  c.pool_dsn,
+		PoolDSN: c.pool_dsn,
 
 		MaxMessageSize: c.peer_maxmessagesize,
 		MessagesIn:     atomic.LoadUint64(&c.messagesIn),
@@ -120,7 +157,21 @@ func (c *connection) Info() gen.RemoteNodeInfo {
 
 		TransitBytesIn:  atomic.LoadUint64(&c.transitIn),
 		TransitBytesOut: atomic.LoadUint64(&c.transitOut),
+
+		Compression: gen.CompressionInfo{
+			Messages: atomic.LoadUint64(&c.compressedMessages),
+			BytesIn:  atomic.LoadUint64(&c.compressedBytesIn),
+			BytesOut: atomic.LoadUint64(&c.compressedBytesOut),
+		},
 	}
+	c.adaptive.info(&info.Compression)
+	info.Faults = c.faults.info()
+	c.heartbeat.info(&info.Heartbeat)
+	info.SendQueue = c.sendQueueInfo()
+	c.pool_mutex.RLock()
+	info.PoolSize = c.pool_size
+	info.PoolLinks = len(c.pool)
+	c.pool_mutex.RUnlock()
 	return info
 }
 
@@ -277,7 +328,7 @@ func (c *connection) SendPID(from gen.PID, to gen.PID, options gen.MessageOption
 	// 8 (header) + 8 (process id from) + 1 priority +8 (message id) + 8 (process id to)
 	buf.Allocate(8 + 8 + 1 + 8 + 8)
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -305,7 +356,7 @@ func (c *connection) SendPID(from gen.PID, to gen.PID, options gen.MessageOption
 
 	binary.BigEndian.PutUint64(buf.B[25:33], to.ID)
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) SendProcessID(from gen.PID, to gen.ProcessID, options gen.MessageOptions, message any) error {
@@ -342,7 +393,7 @@ func (c *connection) SendProcessID(from gen.PID, to gen.ProcessID, options gen.M
 		buf.Allocate(8 + 8 + 1 + 8 + 1 + len(bname))
 	}
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -380,7 +431,7 @@ func (c *connection) SendProcessID(from gen.PID, to gen.ProcessID, options gen.M
 		copy(buf.B[26:], bname)
 	}
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) SendAlias(from gen.PID, to gen.Alias, options gen.MessageOptions, message any) error {
@@ -399,7 +450,7 @@ func (c *connection) SendAlias(from gen.PID, to gen.Alias, options gen.MessageOp
 	// 8 (header) + 8 (process id from) + 1 priority + 8 (message id) + 24 (alias id [3]uint64)
 	buf.Allocate(8 + 8 + 1 + 8 + 24)
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -433,7 +484,7 @@ func (c *connection) SendAlias(from gen.PID, to gen.Alias, options gen.MessageOp
 	binary.BigEndian.PutUint64(buf.B[33:41], to.ID[1])
 	binary.BigEndian.PutUint64(buf.B[41:49], to.ID[2])
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) SendEvent(from gen.PID, options gen.MessageOptions, message gen.MessageEvent) error {
@@ -471,7 +522,7 @@ func (c *connection) SendEvent(from gen.PID, options gen.MessageOptions, message
 		buf.Allocate(8 + 8 + 1 + 8 + 1 + len(bname))
 	}
 
-	if err := sdf.Encode(message.Message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message.Message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -500,7 +551,7 @@ func (c *connection) SendEvent(from gen.PID, options gen.MessageOptions, message
 		copy(buf.B[26:], bname)
 	}
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message.Message)
 }
 
 func (c *connection) SendExit(from gen.PID, to gen.PID, reason error) error {
@@ -512,7 +563,7 @@ func (c *connection) SendExit(from gen.PID, to gen.PID, reason error) error {
 	// 8 (header) + 8 (process id from) + 1 priority + 8 (process id to)
 	buf.Allocate(8 + 8 + 1 + 8)
 
-	if err := sdf.Encode(reason, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(reason, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -528,7 +579,7 @@ func (c *connection) SendExit(from gen.PID, to gen.PID, reason error) error {
 	buf.B[16] = byte(gen.MessagePriorityMax)
 	binary.BigEndian.PutUint64(buf.B[17:25], to.ID)
 
-	return c.send(buf, order, gen.Compression{})
+	return c.send(buf, order, priorityControl, gen.Compression{}, nil)
 }
 
 func (c *connection) SendResponse(from gen.PID, to gen.PID, options gen.MessageOptions, response any) error {
@@ -546,7 +597,7 @@ func (c *connection) SendResponse(from gen.PID, to gen.PID, options gen.MessageO
 	// 8 (header) + 8 (process id from) + 1 priority + 8 (process id to) + 24 (ref [3]uint64)
 	buf.Allocate(8 + 8 + 1 + 8 + 24)
 
-	if err := sdf.Encode(response, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(response, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -570,7 +621,7 @@ func (c *connection) SendResponse(from gen.PID, to gen.PID, options gen.MessageO
 	binary.BigEndian.PutUint64(buf.B[33:41], options.Ref.ID[1])
 	binary.BigEndian.PutUint64(buf.B[41:49], options.Ref.ID[2])
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, response)
 }
 
 func (c *connection) SendResponseError(from gen.PID, to gen.PID, options gen.MessageOptions, err error) error {
@@ -599,7 +650,7 @@ func (c *connection) SendResponseError(from gen.PID, to gen.PID, options gen.Mes
 		buf.B[49] = 3
 	default:
 		buf.B[49] = 255
-		if e := sdf.Encode(err, buf, c.encodeOptions); e != nil {
+		if e := c.codec.Encode(err, buf, c.encodeOptions); e != nil {
 			return e
 		}
 	}
@@ -616,7 +667,7 @@ func (c *connection) SendResponseError(from gen.PID, to gen.PID, options gen.Mes
 	binary.BigEndian.PutUint64(buf.B[33:41], options.Ref.ID[1])
 	binary.BigEndian.PutUint64(buf.B[41:49], options.Ref.ID[2])
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, err)
 }
 
 func (c *connection) SendTerminatePID(target gen.PID, reason error) error {
@@ -627,7 +678,7 @@ func (c *connection) SendTerminatePID(target gen.PID, reason error) error {
 	// 8 (header) + 1 priority + 8 (target process id)
 	buf.Allocate(8 + 1 + 8)
 
-	if err := sdf.Encode(reason, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(reason, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -639,7 +690,7 @@ func (c *connection) SendTerminatePID(target gen.PID, reason error) error {
 	buf.B[8] = byte(gen.MessagePriorityHigh)
 	binary.BigEndian.PutUint64(buf.B[9:17], target.ID)
 
-	return c.send(buf, 0, gen.Compression{})
+	return c.send(buf, 0, priorityControl, gen.Compression{}, nil)
 }
 
 func (c *connection) SendTerminateProcessID(target gen.ProcessID, reason error) error {
@@ -671,7 +722,7 @@ func (c *connection) SendTerminateProcessID(target gen.ProcessID, reason error)
 		buf.Allocate(8 + 1 + 1 + len(bname))
 	}
 
-	if err := sdf.Encode(reason, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(reason, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -689,7 +740,7 @@ func (c *connection) SendTerminateProcessID(target gen.ProcessID, reason error)
 		copy(buf.B[10:], bname)
 	}
 
-	return c.send(buf, 0, gen.Compression{})
+	return c.send(buf, 0, priorityControl, gen.Compression{}, nil)
 }
 
 func (c *connection) SendTerminateAlias(target gen.Alias, reason error) error {
@@ -700,7 +751,7 @@ func (c *connection) SendTerminateAlias(target gen.Alias, reason error) error {
 	// 8 (header) + 1 priority + 24 (target alias id [3]uint64)
 	buf.Allocate(8 + 1 + 24)
 
-	if err := sdf.Encode(reason, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(reason, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -714,7 +765,7 @@ func (c *connection) SendTerminateAlias(target gen.Alias, reason error) error {
 	binary.BigEndian.PutUint64(buf.B[17:25], target.ID[1])
 	binary.BigEndian.PutUint64(buf.B[25:33], target.ID[2])
 
-	return c.send(buf, 0, gen.Compression{})
+	return c.send(buf, 0, priorityControl, gen.Compression{}, nil)
 }
 
 func (c *connection) SendTerminateEvent(target gen.Event, reason error) error {
@@ -747,7 +798,7 @@ func (c *connection) SendTerminateEvent(target gen.Event, reason error) error {
 		buf.Allocate(8 + 1 + +1 + len(bname))
 	}
 
-	if err := sdf.Encode(reason, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(reason, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -766,7 +817,7 @@ func (c *connection) SendTerminateEvent(target gen.Event, reason error) error {
 		copy(buf.B[10:], bname)
 	}
 
-	return c.send(buf, 0, gen.Compression{})
+	return c.send(buf, 0, priorityControl, gen.Compression{}, nil)
 }
 
 func (c *connection) CallPID(from gen.PID, to gen.PID, options gen.MessageOptions, message any) error {
@@ -785,7 +836,7 @@ func (c *connection) CallPID(from gen.PID, to gen.PID, options gen.MessageOption
 	// 8 (header) + 8 (process id from) + 1 priority + 24 (request ref) + 8 (process id to)
 	buf.Allocate(8 + 8 + 1 + 24 + 8)
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 	if buf.Len() > math.MaxUint32 {
@@ -814,7 +865,7 @@ func (c *connection) CallPID(from gen.PID, to gen.PID, options gen.MessageOption
 	binary.BigEndian.PutUint64(buf.B[33:41], options.Ref.ID[2])
 	binary.BigEndian.PutUint64(buf.B[41:49], to.ID)
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) CallProcessID(from gen.PID, to gen.ProcessID, options gen.MessageOptions, message any) error {
@@ -851,7 +902,7 @@ func (c *connection) CallProcessID(from gen.PID, to gen.ProcessID, options gen.M
 		buf.Allocate(8 + 8 + 1 + 24 + 1 + len(bname))
 	}
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -888,7 +939,7 @@ func (c *connection) CallProcessID(from gen.PID, to gen.ProcessID, options gen.M
 		copy(buf.B[42:], bname)
 	}
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) CallAlias(from gen.PID, to gen.Alias, options gen.MessageOptions, message any) error {
@@ -908,7 +959,7 @@ func (c *connection) CallAlias(from gen.PID, to gen.Alias, options gen.MessageOp
 	// 8 (header) + 8 (process id from) + 1 priority + 24 (request ref) + 24 (alias id to)
 	buf.Allocate(8 + 8 + 1 + 24 + 24)
 
-	if err := sdf.Encode(message, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(message, buf, c.encodeOptions); err != nil {
 		return err
 	}
 
@@ -940,7 +991,7 @@ func (c *connection) CallAlias(from gen.PID, to gen.Alias, options gen.MessageOp
 	binary.BigEndian.PutUint64(buf.B[49:57], to.ID[1])
 	binary.BigEndian.PutUint64(buf.B[57:65], to.ID[2])
 
-	return c.send(buf, order, options.Compression)
+	return c.send(buf, order, options.Priority, options.Compression, message)
 }
 
 func (c *connection) LinkPID(pid gen.PID, target gen.PID) error {
@@ -1391,20 +1442,40 @@ func (c *connection) Join(conn net.Conn, id string, dial gen.NetworkDial, tail [
 		return fmt.Errorf("connection terminated")
 	}
 
-	c.pool_mutex.Lock()
-	if c.pool_size+1 < len(c.pool) {
-		c.pool_mutex.Unlock()
-		return fmt.Errorf("pool size limit")
-	}
 	pi := &pool_item{
 		connection: conn,
 		fl:         lib.NewFlusher(conn),
+		left:       make(chan struct{}),
 	}
-	c.pool = append(c.pool, pi)
-	c.pool_mutex.Unlock()
+	pi.queue.init(c.sendQueueLimit)
+
+	// adding the link changes the links the ordered messages are sent over
+	joined, err := c.switchLinks(pi, func() bool {
+		c.pool_mutex.Lock()
+		defer c.pool_mutex.Unlock()
+		if c.pool_size+1 < len(c.pool) {
+			return false
+		}
+		c.poolSync.link(pi, true)
+		c.pool = append(c.pool, pi)
+		if c.dial == nil {
+			c.dial = dial
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if joined == false {
+		return fmt.Errorf("pool size limit")
+	}
+
+	go c.writer(pi)
 
 	c.wg.Add(1)
 	go func() {
+		defer pi.queue.close()
+
 		if lib.Trace() {
 			defer c.log.Trace("connection %s left the pool", conn.RemoteAddr().String())
 		}
@@ -1414,7 +1485,14 @@ func (c *connection) Join(conn net.Conn, id string, dial gen.NetworkDial, tail [
 			c.log.Trace("joined new connection %s to the pool", conn.RemoteAddr().String())
 		}
 
-		c.serve(pi.connection, tail)
+		c.serve(pi, tail)
+		c.poolSync.link(pi, false)
+
+		if pi.retired.Load() {
+			// removed from the pool by resizing
+			c.wg.Done()
+			return
+		}
 
 		if dial != nil {
 			pool_dsn := []string{}
@@ -1434,6 +1512,7 @@ func (c *connection) Join(conn net.Conn, id string, dial gen.NetworkDial, tail [
 				}
 				pi.connection = nc
 				tail = t
+				c.poolSync.link(pi, true)
 
 				goto re
 			}
@@ -1465,6 +1544,22 @@ func (c *connection) Join(conn net.Conn, id string, dial gen.NetworkDial, tail [
 	return nil
 }
 
+// down terminates the connection by itself with the given reason
+func (c *connection) down(reason error) {
+	c.reasonMutex.Lock()
+	if c.reason == nil {
+		c.reason = reason
+	}
+	c.reasonMutex.Unlock()
+	c.Terminate(reason)
+}
+
+func (c *connection) terminateReason() error {
+	c.reasonMutex.Lock()
+	defer c.reasonMutex.Unlock()
+	return c.reason
+}
+
 func (c *connection) Terminate(reason error) {
 	c.terminated = true
 
@@ -1475,7 +1570,8 @@ func (c *connection) Terminate(reason error) {
 	}
 }
 
-func (c *connection) serve(conn net.Conn, tail []byte) {
+func (c *connection) serve(pi *pool_item, tail []byte) {
+	conn := pi.connection
 
 	recvN := 0
 	recvNQ := len(c.recvQueues)
@@ -1512,6 +1608,11 @@ func (c *connection) serve(conn net.Conn, tail []byte) {
 			return
 		}
 
+		if c.handleService(pi, buf) {
+			buf = buftail
+			continue
+		}
+
 		recvN++
 
 		atomic.AddUint64(&c.messagesIn, 1)
@@ -1595,7 +1696,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			important := (buf.B[16] & 128) > 0
 			idTO := binary.BigEndian.Uint64(buf.B[25:33])
 
-			msg, tail, err := sdf.Decode(buf.B[33:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[33:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1681,7 +1782,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			priority := gen.MessagePriority(buf.B[16] & 3)
 			important := (buf.B[16] & 128) > 0
 
-			msg, tail, err := sdf.Decode(data, c.decodeOptions)
+			msg, tail, err := c.codec.Decode(data, c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1741,7 +1842,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				binary.BigEndian.Uint64(buf.B[41:49]),
 			}
 
-			msg, tail, err := sdf.Decode(buf.B[49:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[49:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1801,7 +1902,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			ref.ID[2] = binary.BigEndian.Uint64(buf.B[33:41])
 			idTO := binary.BigEndian.Uint64(buf.B[41:49])
 
-			msg, tail, err := sdf.Decode(buf.B[49:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[49:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1836,12 +1937,15 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				continue
 			}
 
-			if important == false {
-				continue
-			}
+			// denied request must not wait for the timeout
+			if err != gen.ErrAccessDenied {
+				if important == false {
+					continue
+				}
 
-			if c.node_flags.EnableImportantDelivery == false {
-				continue
+				if c.node_flags.EnableImportantDelivery == false {
+					continue
+				}
 			}
 
 			c.SendResponseError(to, from, opts, err)
@@ -1901,7 +2005,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				data = buf.B[43:]
 			}
 
-			msg, tail, err := sdf.Decode(data, c.decodeOptions)
+			msg, tail, err := c.codec.Decode(data, c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1930,12 +2034,15 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				continue
 			}
 
-			if important == false {
-				continue
-			}
+			// denied request must not wait for the timeout
+			if err != gen.ErrAccessDenied {
+				if important == false {
+					continue
+				}
 
-			if c.node_flags.EnableImportantDelivery == false {
-				continue
+				if c.node_flags.EnableImportantDelivery == false {
+					continue
+				}
 			}
 
 			c.SendResponseError(gen.PID{}, from, opts, err)
@@ -1965,7 +2072,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			to.ID[1] = binary.BigEndian.Uint64(buf.B[49:57])
 			to.ID[2] = binary.BigEndian.Uint64(buf.B[57:65])
 
-			msg, tail, err := sdf.Decode(buf.B[65:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[65:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -1994,12 +2101,15 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				continue
 			}
 
-			if important == false {
-				continue
-			}
+			// denied request must not wait for the timeout
+			if err != gen.ErrAccessDenied {
+				if important == false {
+					continue
+				}
 
-			if c.node_flags.EnableImportantDelivery == false {
-				continue
+				if c.node_flags.EnableImportantDelivery == false {
+					continue
+				}
 			}
 
 			c.SendResponseError(gen.PID{}, from, opts, err)
@@ -2055,7 +2165,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				}
 			}
 
-			msg, tail, err := sdf.Decode(data, c.decodeOptions)
+			msg, tail, err := c.codec.Decode(data, c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2082,7 +2192,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			// priority := gen.MessagePriority(buf.B[16]) ignored
 			idTO := binary.BigEndian.Uint64(buf.B[17:25])
 
-			msg, tail, err := sdf.Decode(buf.B[25:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[25:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2132,7 +2242,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			ref.ID[1] = binary.BigEndian.Uint64(buf.B[33:41])
 			ref.ID[2] = binary.BigEndian.Uint64(buf.B[41:49])
 
-			msg, tail, err := sdf.Decode(buf.B[49:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[49:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2208,7 +2318,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			case 255:
 				var ok bool
 
-				msg, tail, err := sdf.Decode(buf.B[50:], c.decodeOptions)
+				msg, tail, err := c.codec.Decode(buf.B[50:], c.decodeOptions)
 				if releaseBuffer {
 					lib.ReleaseBuffer(buf)
 				}
@@ -2241,7 +2351,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			}
 			// priority := gen.MessagePriority(buf.B[8]) ignored
 			idTarget := binary.BigEndian.Uint64(buf.B[9:17])
-			msg, tail, err := sdf.Decode(buf.B[17:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[17:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2313,7 +2423,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				}
 			}
 
-			msg, tail, err := sdf.Decode(data, c.decodeOptions)
+			msg, tail, err := c.codec.Decode(data, c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2379,7 +2489,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				}
 			}
 
-			msg, tail, err := sdf.Decode(data, c.decodeOptions)
+			msg, tail, err := c.codec.Decode(data, c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2415,7 +2525,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 			target.ID[1] = binary.BigEndian.Uint64(buf.B[17:25])
 			target.ID[2] = binary.BigEndian.Uint64(buf.B[25:33])
 
-			msg, tail, err := sdf.Decode(buf.B[33:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[33:], c.decodeOptions)
 			if releaseBuffer {
 				lib.ReleaseBuffer(buf)
 			}
@@ -2443,7 +2553,7 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				continue
 			}
 
-			msg, tail, err := sdf.Decode(buf.B[8:], c.decodeOptions)
+			msg, tail, err := c.codec.Decode(buf.B[8:], c.decodeOptions)
 			lib.ReleaseBuffer(buf)
 
 			if err != nil {
@@ -2463,41 +2573,39 @@ func (c *connection) handleRecvQueue(q lib.QueueMPSC) {
 				continue
 			}
 			skipBytes := 9 // proto header for compressed message
-			switch buf.B[8] {
-			case gen.CompressionTypeGZIP.ID():
-				dbuf, err := lib.DecompressGZIP(buf, uint(skipBytes))
-				if err != nil {
-					c.log.Error("unable to decompress message (gzip), ignored: %s", err)
+			ctype := buf.B[8]
+			var dict []byte
+			if ctype&protoCompressionDict != 0 {
+				if buf.Len() < 14 {
+					c.log.Error("malformed message (too small MessageZ)")
+					lib.ReleaseBuffer(buf)
 					continue
 				}
-				lib.ReleaseBuffer(buf)
-				buf = dbuf
-				goto re
-
-			case gen.CompressionTypeLZW.ID():
-				dbuf, err := lib.DecompressLZW(buf, uint(skipBytes))
-				if err != nil {
-					c.log.Error("unable to decompress message (lzw), ignored")
+				id := binary.BigEndian.Uint32(buf.B[9:13])
+				dict = c.dictionaries[id]
+				if dict == nil {
+					c.log.Error("message compressed with unknown dictionary %08x, ignored", id)
+					lib.ReleaseBuffer(buf)
 					continue
 				}
-				lib.ReleaseBuffer(buf)
-				buf = dbuf
-				goto re
+				ctype &^= protoCompressionDict
+				skipBytes = 13
+			}
 
-			case gen.CompressionTypeZLIB.ID():
-				dbuf, err := lib.DecompressZLIB(buf, uint(skipBytes))
-				if err != nil {
-					c.log.Error("unable to decompress message (zlib), ignored")
-					continue
-				}
+			compressor, found := lib.CompressorByID(ctype)
+			if found == false {
+				c.log.Error("message with unknown compression type %d, ignored", ctype)
 				lib.ReleaseBuffer(buf)
-				buf = dbuf
-				goto re
-
-			default:
-				c.log.Error("message with unknown compression type %d, ignored", buf.B[7])
 				continue
 			}
+			dbuf, err := lib.Decompress(compressor, buf, uint(skipBytes), dict)
+			lib.ReleaseBuffer(buf)
+			if err != nil {
+				c.log.Error("unable to decompress message (%s), ignored: %s", compressor.Name(), err)
+				continue
+			}
+			buf = dbuf
+			goto re
 
 		// case protoMessageF:
 		// TODO fragmentation
@@ -2787,6 +2895,12 @@ func (c *connection) routeMessage(msg any) {
 	case MessageSpawn:
 		if c.node_flags.Enable && c.node_flags.EnableRemoteSpawn == false {
 			c.log.Warning("remote spawn is not allowed for %s", c.peer)
+			result := MessageResult{
+				Error: gen.ErrNotAllowed,
+				Ref:   m.Ref,
+			}
+			orderPeer := uint8(m.Options.ParentPID.ID % 255)
+			c.sendAny(result, 0, orderPeer, gen.Compression{})
 			return
 		}
 		pid, err := c.core.RouteSpawn(c.core.Name(), m.Name, m.Options, c.peer)
@@ -2802,6 +2916,11 @@ func (c *connection) routeMessage(msg any) {
 	case MessageApplicationStart:
 		if c.node_flags.Enable && c.node_flags.EnableRemoteApplicationStart == false {
 			c.log.Warning("remote application start is not allowed for %s", c.peer)
+			result := MessageResult{
+				Error: gen.ErrNotAllowed,
+				Ref:   m.Ref,
+			}
+			c.sendAny(result, 0, 0, gen.Compression{})
 			return
 		}
 		err := c.core.RouteApplicationStart(m.Name, m.Mode, m.Options, c.peer)
@@ -2822,7 +2941,7 @@ func (c *connection) sendAny(msg any, order uint8, orderPeer uint8, compression
 	buf := lib.TakeBuffer()
 	buf.Allocate(8) // for the header
 
-	if err := sdf.Encode(msg, buf, c.encodeOptions); err != nil {
+	if err := c.codec.Encode(msg, buf, c.encodeOptions); err != nil {
 		return err
 	}
 	if buf.Len() > math.MaxUint32 {
@@ -2834,18 +2953,36 @@ func (c *connection) sendAny(msg any, order uint8, orderPeer uint8, compression
 	buf.B[6] = orderPeer
 	buf.B[7] = protoMessageAny
 
-	return c.send(buf, order, compression)
+	return c.send(buf, order, priorityControl, compression, msg)
 }
 
 func (c *connection) wait() {
 	c.wg.Wait()
 }
 
-func (c *connection) send(buf *lib.Buffer, order uint8, compression gen.Compression) error {
+func (c *connection) send(buf *lib.Buffer, order uint8, priority gen.MessagePriority, compression gen.Compression, message any) error {
+
+	if c.sampler != nil {
+		// the payload only, the header makes no sense for the dictionary
+		c.sampler.Add(buf.B[8:])
+	}
+
+	var candidate *adaptiveCandidate
+	if compression.Enable && compression.Adaptive {
+		_, candidate = c.adaptive.choose(message, buf.Len(), compression)
+		compression.Enable = candidate != nil
+		if candidate != nil {
+			compression.Type = candidate.ctype
+			compression.Level = candidate.level
+			compression.Threshold = 0
+		}
+	}
 
 	if compression.Enable && buf.Len() > compression.Threshold {
-		var zbuf *lib.Buffer
-		var err error
+		compressor, found := lib.CompressorByName(string(compression.Type))
+		if found == false {
+			compressor, _ = lib.CompressorByName(string(gen.CompressionTypeGZIP))
+		}
 
 		// 1 - protoMagic
 		// 1 - protoVersion
@@ -2853,33 +2990,35 @@ func (c *connection) send(buf *lib.Buffer, order uint8, compression gen.Compress
 		// 1 - order
 		// 1 - protoMessageZ
 		// 1 - compression type
+		// 4 - dictionary ID (if the compression type has protoCompressionDict flag)
 		preallocate := uint(9)
+		var dict []byte
+		if _, ok := compressor.(lib.DictCompressor); ok && c.dictionary != nil {
+			preallocate = 13
+			dict = c.dictionary
+		}
 
-		switch compression.Type {
-		case gen.CompressionTypeZLIB:
-			zbuf, err = lib.CompressZLIB(buf, preallocate)
-			if err != nil {
-				return fmt.Errorf("unable to compress packet (zlib): %s", err)
-			}
-		case gen.CompressionTypeLZW:
-			zbuf, err = lib.CompressLZW(buf, preallocate)
-			if err != nil {
-				return fmt.Errorf("unable to compress packet (lzw): %s", err)
-			}
-		default:
-			compression.Type = gen.CompressionTypeGZIP
-			zbuf, err = lib.CompressGZIP(buf, preallocate, int(compression.Level))
-			if err != nil {
-				return fmt.Errorf("unable to compress packet (gzip): %s", err)
-			}
-
+		start := time.Now()
+		zbuf, err := lib.Compress(compressor, buf, preallocate, int(compression.Level), dict)
+		if err != nil {
+			return fmt.Errorf("unable to compress packet (%s): %s", compressor.Name(), err)
 		}
+		if candidate != nil {
+			c.adaptive.update(candidate, buf.Len(), zbuf.Len(), time.Since(start))
+		}
+		atomic.AddUint64(&c.compressedMessages, 1)
+		atomic.AddUint64(&c.compressedBytesIn, uint64(buf.Len()))
+		atomic.AddUint64(&c.compressedBytesOut, uint64(zbuf.Len()))
 		zbuf.B[0] = protoMagic
 		zbuf.B[1] = protoVersion
 		binary.BigEndian.PutUint32(zbuf.B[2:6], uint32(zbuf.Len()))
 		zbuf.B[6] = buf.B[6] // keep order of the original message
 		zbuf.B[7] = protoMessageZ
-		zbuf.B[8] = compression.Type.ID()
+		zbuf.B[8] = compressor.ID()
+		if dict != nil {
+			zbuf.B[8] |= protoCompressionDict
+			binary.BigEndian.PutUint32(zbuf.B[9:13], c.dictionaryID)
+		}
 
 		lib.ReleaseBuffer(buf)
 		buf = zbuf
@@ -2889,6 +3028,15 @@ func (c *connection) send(buf *lib.Buffer, order uint8, compression gen.Compress
 		return gen.ErrTooLarge
 	}
 
+	faults, faulty := c.core.NetworkFaults(c.peer)
+	if faulty {
+		order = c.chooseFaultyOrder(faults, order)
+	}
+
+	// the ordered messages must not overtake each other while the pool is changing
+	c.layout.RLock()
+	defer c.layout.RUnlock()
+
 	var pi *pool_item
 	c.pool_mutex.RLock()
 	l := len(c.pool)
@@ -2906,16 +3054,20 @@ func (c *connection) send(buf *lib.Buffer, order uint8, compression gen.Compress
 	}
 	c.pool_mutex.RUnlock()
 
-	atomic.AddUint64(&c.messagesOut, 1)
-	atomic.AddUint64(&c.bytesOut, uint64(buf.Len()))
+	size := uint64(buf.Len())
 
 	// TODO
 	// add proxy, fragmentation support
 	// c.transitOut++
 	// if buf.Len() < protoFragmentSize {
 
-	pi.fl.Write(buf.B)
-	lib.ReleaseBuffer(buf)
+	if faulty {
+		c.sendFaulty(pi, buf, order, priority, faults)
+	} else if err := c.write(pi, buf, order, priority); err != nil {
+		return err
+	}
+	atomic.AddUint64(&c.messagesOut, 1)
+	atomic.AddUint64(&c.bytesOut, size)
 	return nil
 
 	// }
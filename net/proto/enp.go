@@ -2,6 +2,7 @@ package proto
 
 import (
 	"fmt"
+	"github.com/sllt/sparrow/net/codec"
 	"github.com/sllt/sparrow/net/sdf"
 	"sync"
 	"time"
@@ -33,7 +34,21 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 		return nil, gen.ErrNotAllowed
 	}
 
+	name := opts.Codec
+	if name == "" {
+		name = codec.SDF
+	}
+	cdc, found := codec.Get(name)
+	if found == false {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+
 	log.Trace("create new connection with %s (pool size: %d)", result.Peer, opts.PoolSize)
+	if result.Heartbeat.Enable && opts.Heartbeat == false {
+		// the peer doesn't reply to the pings, so it would be declared down
+		log.Trace("heartbeat is not supported by %s, disabled", result.Peer)
+		result.Heartbeat.Enable = false
+	}
 	conn := &connection{
 		id:                  result.ConnectionID,
 		creation:            time.Now().Unix(),
@@ -44,6 +59,7 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 
 		handshakeVersion: result.HandshakeVersion,
 		protoVersion:     e.Version(),
+		cookieGeneration: result.CookieGeneration,
 
 		peer:                result.Peer,
 		peer_creation:       result.PeerCreation,
@@ -53,7 +69,15 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 
 		pool_size: opts.PoolSize,
 		pool_dsn:  opts.PoolDSN,
+		pool_sync: opts.PoolSync,
+
+		sendQueueLimit: result.SendQueueLimit,
 
+		codec:     cdc,
+		sampler:   opts.Sampler,
+		adaptive:  newAdaptiveCompression(),
+		heartbeat: newHeartbeat(result.Heartbeat),
+		scaling:   newPoolScaling(result.PoolScaling),
 		encodeOptions: sdf.Options{
 			AtomCache: opts.EncodeAtomCache,
 			RegCache:  opts.EncodeRegCache,
@@ -70,6 +94,10 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 		requests: make(map[gen.Ref]chan MessageResult),
 	}
 
+	if conn.sendQueueLimit <= 0 {
+		conn.sendQueueLimit = gen.DefaultSendQueueLimit
+	}
+
 	if len(result.AtomMapping) > 0 {
 		conn.encodeOptions.AtomMapping = &sync.Map{}
 		conn.decodeOptions.AtomMapping = &sync.Map{}
@@ -79,6 +107,15 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 		}
 	}
 
+	if len(opts.Dictionaries) > 0 {
+		conn.dictionary = opts.Dictionaries[0]
+		conn.dictionaryID = lib.DictionaryID(conn.dictionary)
+		conn.dictionaries = make(map[uint32][]byte)
+		for _, dict := range opts.Dictionaries {
+			conn.dictionaries[lib.DictionaryID(dict)] = dict
+		}
+	}
+
 	// init recv queues. create 4 recv queues per connection
 	// since the decoding is more costly comparing to the encoding
 	for i := 0; i < opts.PoolSize*4; i++ {
@@ -90,24 +127,31 @@ func (e *enp) NewConnection(core gen.Core, result gen.HandshakeResult, log gen.L
 
 func (e *enp) Serve(c gen.Connection, redial gen.NetworkDial) error {
 	conn := c.(*connection)
+	stop := conn.startHeartbeat()
+	defer stop()
+
 	if redial == nil {
 		// accepted connection. no dialer.
 		conn.wait()
-		return nil
+		return conn.terminateReason()
 	}
 
+	stopScaling := conn.startPoolScaling()
+	defer stopScaling()
+
 	if conn.pool_size < 2 {
 		// just one TCP connection in the pool
 		conn.wait()
-		return nil
+		return conn.terminateReason()
 	}
 
 	if len(conn.pool_dsn) == 0 {
 		conn.log.Warning("pool size is %d, but DSN list is empty", conn.pool_size)
 		conn.wait()
-		return nil
+		return conn.terminateReason()
 	}
 
+	conn.resizing.Lock()
 	for i := 1; i < conn.pool_size; i++ {
 
 		// TODO
@@ -130,10 +174,11 @@ func (e *enp) Serve(c gen.Connection, redial gen.NetworkDial) error {
 			conn.log.Error("unable to join %s: %s", nc.RemoteAddr().String(), err)
 		}
 	}
+	conn.resizing.Unlock()
 
 	conn.wait()
 
-	return nil
+	return conn.terminateReason()
 }
 
 func (e *enp) Version() gen.Version {
@@ -0,0 +1,129 @@
+package proto
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+type faultsStats struct {
+	delayed      uint64
+	dropped      uint64
+	reordered    uint64
+	disconnected uint64
+}
+
+func (s *faultsStats) info() gen.FaultsInfo {
+	return gen.FaultsInfo{
+		Delayed:      atomic.LoadUint64(&s.delayed),
+		Dropped:      atomic.LoadUint64(&s.dropped),
+		Reordered:    atomic.LoadUint64(&s.reordered),
+		Disconnected: atomic.LoadUint64(&s.disconnected),
+	}
+}
+
+// delayLine keeps the delayed packets of the pool link. They are released to the
+// send queue of the link in the order they were sent, so the jitter doesn't
+// break the order within the link.
+type delayLine struct {
+	sync.Mutex
+	active  atomic.Bool
+	packets []delayedPacket
+}
+
+type delayedPacket struct {
+	buf      *lib.Buffer
+	order    uint8
+	priority gen.MessagePriority
+	at       time.Time
+	gate     chan struct{} // fences the send queue (see sendQueue.fence)
+}
+
+func (d *delayLine) push(c *connection, pi *pool_item, packet delayedPacket) {
+	d.Lock()
+	d.packets = append(d.packets, packet)
+	if d.active.Load() {
+		d.Unlock()
+		return
+	}
+	d.active.Store(true)
+	d.Unlock()
+
+	go d.run(c, pi)
+}
+
+func (d *delayLine) run(c *connection, pi *pool_item) {
+	for {
+		d.Lock()
+		if len(d.packets) == 0 {
+			d.active.Store(false)
+			d.Unlock()
+			return
+		}
+		packet := d.packets[0]
+		d.packets[0] = delayedPacket{}
+		d.packets = d.packets[1:]
+		d.Unlock()
+
+		if packet.gate != nil {
+			pi.queue.fence(packet.buf, packet.gate)
+			continue
+		}
+		if wait := time.Until(packet.at); wait > 0 {
+			time.Sleep(wait)
+		}
+		// the rejected packet is counted as dropped by the send queue
+		c.enqueue(pi, packet.buf, packet.order, packet.priority)
+	}
+}
+
+// chooseFaultyOrder returns the random order for the message if it must be reordered
+func (c *connection) chooseFaultyOrder(faults gen.NetworkFaults, order uint8) uint8 {
+	if faults.ReorderRate == 0 || rand.Float64() >= faults.ReorderRate {
+		return order
+	}
+	atomic.AddUint64(&c.faults.reordered, 1)
+	// zero order means the round robin over the pool links
+	return uint8(rand.Intn(255) + 1)
+}
+
+// sendFaulty writes the packet to the pool link applying the injected faults
+func (c *connection) sendFaulty(pi *pool_item, buf *lib.Buffer, order uint8, priority gen.MessagePriority, faults gen.NetworkFaults) {
+	if faults.DisconnectRate > 0 && rand.Float64() < faults.DisconnectRate {
+		atomic.AddUint64(&c.faults.disconnected, 1)
+		if lib.Trace() {
+			c.log.Trace("fault injection: close link %s with %s", pi.connection.RemoteAddr(), c.peer)
+		}
+		lib.ReleaseBuffer(buf)
+		pi.connection.Close()
+		return
+	}
+
+	if faults.DropRate > 0 && rand.Float64() < faults.DropRate {
+		atomic.AddUint64(&c.faults.dropped, 1)
+		lib.ReleaseBuffer(buf)
+		return
+	}
+
+	delay := faults.Latency
+	if faults.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(faults.Jitter)))
+	}
+	if delay == 0 {
+		c.write(pi, buf, order, priority)
+		return
+	}
+
+	atomic.AddUint64(&c.faults.delayed, 1)
+	packet := delayedPacket{
+		buf:      buf,
+		order:    order,
+		priority: priority,
+		at:       time.Now().Add(delay),
+	}
+	pi.delayed.push(c, pi, packet)
+}
@@ -0,0 +1,289 @@
+package proto
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+// heartbeat keeps the state of the connection heartbeat. Every round the ping
+// frames are sent over all the links of the pool. The round is complete once
+// all of them have replied.
+type heartbeat struct {
+	sync.Mutex
+	options gen.NetworkHeartbeat
+
+	round    uint64
+	started  time.Time
+	expected int
+	received int
+	complete bool
+
+	missed      int
+	missedTotal uint64
+
+	rttMin   time.Duration
+	rttMax   time.Duration
+	rttLast  time.Duration
+	rttSum   time.Duration
+	rttCount int64
+
+	// phi-accrual failure detector
+	last      time.Time // the last completed round
+	intervals []float64 // ring buffer
+	next      int
+}
+
+func newHeartbeat(options gen.NetworkHeartbeat) *heartbeat {
+	if options.Enable == false {
+		return nil
+	}
+	if options.Interval <= 0 {
+		options.Interval = gen.DefaultHeartbeatInterval
+	}
+	if options.MaxMissed <= 0 {
+		options.MaxMissed = gen.DefaultHeartbeatMaxMissed
+	}
+	if options.PhiWindow <= 0 {
+		options.PhiWindow = gen.DefaultHeartbeatPhiWindow
+	}
+	return &heartbeat{
+		options: options,
+	}
+}
+
+// tick starts the new round. Returns gen.ErrNoHeartbeat if the peer must be
+// declared down.
+func (h *heartbeat) tick(now time.Time, links int) (uint64, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.round > 0 && h.complete == false {
+		h.missed++
+		h.missedTotal++
+	}
+
+	if h.options.PhiThreshold > 0 {
+		if h.phi(now) > h.options.PhiThreshold {
+			return 0, gen.ErrNoHeartbeat
+		}
+	} else if h.missed >= h.options.MaxMissed {
+		return 0, gen.ErrNoHeartbeat
+	}
+
+	h.round++
+	h.started = now
+	h.expected = links
+	h.received = 0
+	h.complete = false
+	return h.round, nil
+}
+
+func (h *heartbeat) pong(now time.Time, round uint64) {
+	h.Lock()
+	defer h.Unlock()
+
+	if round != h.round || h.complete {
+		return
+	}
+
+	rtt := now.Sub(h.started)
+	h.rttLast = rtt
+	if h.rttCount == 0 || rtt < h.rttMin {
+		h.rttMin = rtt
+	}
+	if rtt > h.rttMax {
+		h.rttMax = rtt
+	}
+	h.rttSum += rtt
+	h.rttCount++
+
+	h.received++
+	if h.received < h.expected {
+		return
+	}
+
+	h.complete = true
+	h.missed = 0
+	if h.last.IsZero() == false {
+		interval := float64(now.Sub(h.last))
+		if len(h.intervals) < h.options.PhiWindow {
+			h.intervals = append(h.intervals, interval)
+		} else {
+			h.intervals[h.next] = interval
+			h.next = (h.next + 1) % h.options.PhiWindow
+		}
+	}
+	h.last = now
+}
+
+// phi returns the suspicion level that the peer is down. It is the -log10 of
+// the probability that the heartbeat would come later than now assuming
+// the normal distribution of the intervals between the heartbeats.
+func (h *heartbeat) phi(now time.Time) float64 {
+	if h.last.IsZero() {
+		return 0
+	}
+
+	// until the window gets the samples assume the configured interval
+	mean := float64(h.options.Interval)
+	stddev := mean / 4
+	if l := len(h.intervals); l > 0 {
+		sum := 0.0
+		for _, v := range h.intervals {
+			sum += v
+		}
+		mean = sum / float64(l)
+		variance := 0.0
+		for _, v := range h.intervals {
+			variance += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(variance / float64(l))
+	}
+	// avoid too sharp distribution for the stable intervals
+	if min := float64(h.options.Interval) / 10; stddev < min {
+		stddev = min
+	}
+
+	elapsed := float64(now.Sub(h.last))
+	y := (elapsed - mean) / stddev
+	// logistic approximation of the cumulative distribution function
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1.0 + e))
+	}
+	return -math.Log10(1.0 - 1.0/(1.0+e))
+}
+
+func (h *heartbeat) info(info *gen.HeartbeatInfo) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+
+	info.Enabled = true
+	info.RTTMin = h.rttMin
+	info.RTTMax = h.rttMax
+	info.RTTLast = h.rttLast
+	if h.rttCount > 0 {
+		info.RTTAvg = h.rttSum / time.Duration(h.rttCount)
+	}
+	info.Missed = h.missed
+	info.MissedTotal = h.missedTotal
+	if h.options.PhiThreshold > 0 {
+		info.Phi = h.phi(time.Now())
+	}
+}
+
+// startHeartbeat runs the heartbeat of the connection. Returns the function
+// to stop it.
+func (c *connection) startHeartbeat() func() {
+	if c.heartbeat == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.heartbeat.options.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.pool_mutex.RLock()
+				pool := append([]*pool_item(nil), c.pool...)
+				c.pool_mutex.RUnlock()
+
+				round, err := c.heartbeat.tick(now, len(pool))
+				if err != nil {
+					c.log.Warning("no heartbeat from %s, the node is declared down", c.peer)
+					c.down(err)
+					return
+				}
+				for _, pi := range pool {
+					c.sendPing(pi, round)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+	}
+}
+
+func (c *connection) sendPing(pi *pool_item, round uint64) {
+	c.sendService(pi, serviceFrame(protoMessagePing, round))
+}
+
+// handleService handles the service frames (heartbeat, pool resizing) right in
+// the reading loop of the link. Returns false if the packet is not the service one.
+func (c *connection) handleService(pi *pool_item, buf *lib.Buffer) bool {
+	switch buf.B[7] {
+	case protoMessagePing:
+		// reply over the same link with the same payload
+		buf.B[7] = protoMessagePong
+		c.sendService(pi, buf)
+		return true
+
+	case protoMessagePong:
+		if c.heartbeat != nil && buf.Len() >= 16 {
+			c.heartbeat.pong(time.Now(), binary.BigEndian.Uint64(buf.B[8:16]))
+		}
+		lib.ReleaseBuffer(buf)
+		return true
+
+	case protoMessageSync:
+		// all the packets sent before over this link are in the recv queues
+		buf.B[7] = protoMessageSyncAck
+		c.write(pi, buf, 0, priorityControl)
+		return true
+
+	case protoMessageSyncAck:
+		if buf.Len() >= 16 {
+			c.poolSync.ack(pi, binary.BigEndian.Uint64(buf.B[8:16]))
+		}
+		lib.ReleaseBuffer(buf)
+		return true
+
+	case protoMessagePoolSize:
+		if buf.Len() >= 16 {
+			c.handlePoolSize(int(binary.BigEndian.Uint64(buf.B[8:16])))
+		}
+		lib.ReleaseBuffer(buf)
+		return true
+
+	case protoMessageLeave:
+		lib.ReleaseBuffer(buf)
+		// must not block the reading loop, the sync is confirmed over this link too
+		go c.leave(pi)
+		return true
+
+	case protoMessageLeft:
+		lib.ReleaseBuffer(buf)
+		select {
+		case <-pi.left:
+		default:
+			close(pi.left)
+		}
+		return true
+	}
+	return false
+}
+
+// sendService sends the service frame. The injected faults are applied to them
+// as well, so the failure detection can be tested.
+func (c *connection) sendService(pi *pool_item, buf *lib.Buffer) {
+	if faults, faulty := c.core.NetworkFaults(c.peer); faulty {
+		c.sendFaulty(pi, buf, 0, priorityControl, faults)
+		return
+	}
+	c.write(pi, buf, 0, priorityControl)
+}
@@ -0,0 +1,426 @@
+package proto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+// how long to wait for the peer to confirm the sync or leaving the link
+const poolSyncTimeout = 3 * time.Second
+
+// poolSync keeps the state of the sync of the pool links. Once the peer has
+// confirmed the sync frame, it has read all the packets sent over the link
+// before and put them into the recv queues. So the ordered messages can be
+// sent over another link with no risk to overtake the previous ones.
+type poolSync struct {
+	sync.Mutex // serializes the syncs
+
+	state   sync.Mutex
+	id      uint64
+	pending map[*pool_item]bool
+	done    chan struct{}
+	serving map[*pool_item]bool
+}
+
+// start starts the new sync of the links. The closed links are skipped, nothing
+// is read from them anymore.
+func (s *poolSync) start(links []*pool_item) (uint64, []*pool_item, chan struct{}) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.id++
+	s.pending = make(map[*pool_item]bool)
+	s.done = make(chan struct{})
+	alive := []*pool_item{}
+	for _, pi := range links {
+		if s.serving[pi] == false {
+			continue
+		}
+		s.pending[pi] = true
+		alive = append(alive, pi)
+	}
+	if len(alive) == 0 {
+		close(s.done)
+	}
+	return s.id, alive, s.done
+}
+
+func (s *poolSync) ack(pi *pool_item, id uint64) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	if id != s.id {
+		return
+	}
+	s.complete(pi)
+}
+
+func (s *poolSync) complete(pi *pool_item) {
+	if s.pending[pi] == false {
+		return
+	}
+	delete(s.pending, pi)
+	if len(s.pending) == 0 {
+		close(s.done)
+	}
+}
+
+// link marks the link of the pool as the serving or closed one
+func (s *poolSync) link(pi *pool_item, serving bool) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	if serving {
+		if s.serving == nil {
+			s.serving = make(map[*pool_item]bool)
+		}
+		s.serving[pi] = true
+		return
+	}
+	delete(s.serving, pi)
+	s.complete(pi)
+}
+
+func serviceFrame(kind byte, value uint64) *lib.Buffer {
+	buf := lib.TakeBuffer()
+	buf.Allocate(16)
+	buf.B[0] = protoMagic
+	buf.B[1] = protoVersion
+	binary.BigEndian.PutUint32(buf.B[2:6], uint32(buf.Len()))
+	buf.B[6] = 0
+	buf.B[7] = kind
+	binary.BigEndian.PutUint64(buf.B[8:16], value)
+	return buf
+}
+
+// sync sends the sync frames over the given links and waits for the peer to
+// confirm them all. The node of the previous version doesn't confirm them,
+// so there is nothing to wait for.
+func (c *connection) sync(links []*pool_item) error {
+	if len(links) == 0 || c.pool_sync == false {
+		return nil
+	}
+
+	s := &c.poolSync
+	s.Lock()
+	defer s.Unlock()
+
+	id, links, done := s.start(links)
+	for _, pi := range links {
+		c.write(pi, serviceFrame(protoMessageSync, id), 0, gen.MessagePriorityNormal)
+	}
+
+	timer := time.NewTimer(poolSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return gen.ErrTimeout
+	}
+}
+
+// links returns the copy of the pool
+func (c *connection) links() []*pool_item {
+	c.pool_mutex.RLock()
+	defer c.pool_mutex.RUnlock()
+	return append([]*pool_item(nil), c.pool...)
+}
+
+// switchLinks changes the links of the pool the ordered messages are sent over.
+// The links are synced before, so the change is aborted if the peer doesn't
+// confirm it. The layout lock is held for the change only: the packets queued
+// before it are followed by the sync frames, the ordered packets queued after
+// it are held by the writers until the peer confirms this sync as well.
+func (c *connection) switchLinks(joined *pool_item, change func() bool) (bool, error) {
+	if err := c.sync(c.links()); err != nil {
+		return false, err
+	}
+
+	if c.pool_sync == false {
+		c.layout.Lock()
+		defer c.layout.Unlock()
+		return change(), nil
+	}
+
+	s := &c.poolSync
+	s.Lock()
+	c.layout.Lock()
+	id, links, done := s.start(c.links())
+	if change() == false {
+		c.layout.Unlock()
+		s.Unlock()
+		return false, nil
+	}
+	if len(links) == 0 {
+		c.layout.Unlock()
+		s.Unlock()
+		return true, nil
+	}
+	gate := make(chan struct{})
+	for _, pi := range links {
+		c.fence(pi, serviceFrame(protoMessageSync, id), gate)
+	}
+	if joined != nil {
+		c.fence(joined, nil, gate)
+	}
+	c.layout.Unlock()
+
+	go func() {
+		defer s.Unlock()
+		timer := time.NewTimer(poolSyncTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			c.log.Warning("the switch of the pool links with %s hasn't been confirmed", c.peer)
+		}
+		close(gate)
+	}()
+	return true, nil
+}
+
+// retireLink removes the link from the pool, so it is not used for sending anymore
+func (c *connection) retireLink(pi *pool_item) (bool, error) {
+	return c.switchLinks(nil, func() bool {
+		c.pool_mutex.Lock()
+		defer c.pool_mutex.Unlock()
+		for i, item := range c.pool {
+			if item != pi {
+				continue
+			}
+			pi.retired.Store(true)
+			c.pool = append(c.pool[:i:i], c.pool[i+1:]...)
+			return true
+		}
+		return false
+	})
+}
+
+// retire closes the link of the pool once the peer has stopped using it
+func (c *connection) retire(pi *pool_item) error {
+	if retired, err := c.retireLink(pi); retired == false {
+		return err
+	}
+	c.write(pi, serviceFrame(protoMessageLeave, 0), 0, priorityControl)
+
+	timer := time.NewTimer(poolSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-pi.left:
+	case <-timer.C:
+		c.log.Warning("link %s with %s hasn't been released by the peer", pi.connection.RemoteAddr(), c.peer)
+	}
+	pi.connection.Close()
+	return nil
+}
+
+// leave handles the request of the peer to release the link
+func (c *connection) leave(pi *pool_item) {
+	retired, err := c.retireLink(pi)
+	if err != nil {
+		c.log.Warning("unable to release link %s with %s: %s", pi.connection.RemoteAddr(), c.peer, err)
+	}
+	if retired == false {
+		return
+	}
+	c.write(pi, serviceFrame(protoMessageLeft, 0), 0, priorityControl)
+}
+
+// resize dials the new links or closes the redundant ones. Can be called by the
+// node that established the connection only.
+func (c *connection) resize(size int) error {
+	c.resizing.Lock()
+	defer c.resizing.Unlock()
+
+	if c.terminated {
+		return gen.ErrNoConnection
+	}
+
+	c.pool_mutex.Lock()
+	c.pool_size = size
+	pool := append([]*pool_item(nil), c.pool...)
+	c.pool_mutex.Unlock()
+
+	if len(pool) == 0 {
+		return gen.ErrNoConnection
+	}
+	if len(pool) == size {
+		return nil
+	}
+
+	if lib.Trace() {
+		c.log.Trace("resize pool with %s: %d => %d", c.peer, len(pool), size)
+	}
+
+	// the peer must handle the new pool size before the join handshakes
+	c.write(pool[0], serviceFrame(protoMessagePoolSize, uint64(size)), 0, priorityControl)
+	if err := c.sync(pool[:1]); err != nil {
+		return err
+	}
+
+	for i := len(pool) - 1; i >= size; i-- {
+		if err := c.retire(pool[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := len(pool); i < size; i++ {
+		if len(c.pool_dsn) == 0 {
+			return fmt.Errorf("pool DSN list is empty")
+		}
+		dsn := c.pool_dsn[i%len(c.pool_dsn)]
+		nc, tail, err := c.dial(dsn, c.id)
+		if err != nil {
+			return err
+		}
+		if err := c.Join(nc, c.id, c.dial, tail); err != nil {
+			nc.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// handlePoolSize handles the pool size frame. The node that established the
+// connection gets it as a request to resize the pool, the other one - as
+// a notification of the new size.
+func (c *connection) handlePoolSize(size int) {
+	if size < 1 {
+		return
+	}
+	c.pool_mutex.Lock()
+	dial := c.dial
+	if dial == nil {
+		c.pool_size = size
+	}
+	c.pool_mutex.Unlock()
+
+	if dial == nil {
+		return
+	}
+	go func() {
+		if err := c.resize(size); err != nil {
+			c.log.Warning("unable to resize pool with %s: %s", c.peer, err)
+		}
+	}()
+}
+
+func (c *connection) SetPoolSize(size int) error {
+	if size < 1 {
+		return gen.ErrIncorrect
+	}
+	if c.terminated {
+		return gen.ErrNoConnection
+	}
+
+	c.pool_mutex.RLock()
+	dial := c.dial
+	var pi *pool_item
+	if len(c.pool) > 0 {
+		pi = c.pool[0]
+	}
+	c.pool_mutex.RUnlock()
+
+	if dial != nil {
+		return c.resize(size)
+	}
+	if pi == nil {
+		return gen.ErrNoConnection
+	}
+	// accepted connection. ask the peer to resize the pool
+	c.write(pi, serviceFrame(protoMessagePoolSize, uint64(size)), 0, priorityControl)
+	return nil
+}
+
+func newPoolScaling(options gen.NetworkPoolScaling) gen.NetworkPoolScaling {
+	if options.Enable == false {
+		return options
+	}
+	if options.Interval <= 0 {
+		options.Interval = gen.DefaultPoolScalingInterval
+	}
+	if options.MinSize < 1 {
+		options.MinSize = 1
+	}
+	if options.MaxSize < 1 {
+		options.MaxSize = gen.DefaultPoolScalingMaxSize
+	}
+	if options.MaxSize < options.MinSize {
+		options.MaxSize = options.MinSize
+	}
+	if options.Grow <= 0 {
+		options.Grow = gen.DefaultPoolScalingGrow
+	}
+	if options.Shrink <= 0 {
+		options.Shrink = options.Grow / 4
+	}
+	return options
+}
+
+// startPoolScaling runs the resizing of the pool by the throughput. Returns the
+// function to stop it.
+func (c *connection) startPoolScaling() func() {
+	options := c.scaling
+	if options.Enable == false {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+
+		in := atomic.LoadUint64(&c.bytesIn)
+		out := atomic.LoadUint64(&c.bytesOut)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				newIn := atomic.LoadUint64(&c.bytesIn)
+				newOut := atomic.LoadUint64(&c.bytesOut)
+				bytes := newIn - in
+				if newOut-out > bytes {
+					bytes = newOut - out
+				}
+				in, out = newIn, newOut
+
+				c.pool_mutex.RLock()
+				links := len(c.pool)
+				size := c.pool_size
+				c.pool_mutex.RUnlock()
+				if links == 0 {
+					continue
+				}
+
+				rate := float64(bytes) / options.Interval.Seconds() / float64(links)
+				switch {
+				case size < options.MinSize:
+					size = options.MinSize
+				case size > options.MaxSize:
+					size = options.MaxSize
+				case rate > float64(options.Grow) && size < options.MaxSize:
+					size++
+				case rate < float64(options.Shrink) && size > options.MinSize:
+					size--
+				default:
+					continue
+				}
+				if err := c.resize(size); err != nil {
+					c.log.Warning("unable to resize pool with %s: %s", c.peer, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+	}
+}
@@ -0,0 +1,317 @@
+package proto
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+// the priority of the link/monitor/exit and the other control messages
+const priorityControl = gen.MessagePriorityMax
+
+// sendQueue schedules the packets of the pool link by their priority. The packets
+// are written by the writer goroutine of the link, the higher priority first, so
+// the senders (and the reading loop) never block on the link. The packets with
+// the same order key are written in the order they were sent. The depth of
+// each queue is limited, the packet is rejected if the queue is full.
+type sendQueue struct {
+	sync.Mutex
+	queues [3][]queuedPacket // by gen.MessagePriority
+	limit  int
+	wake   chan struct{}
+	closed bool
+
+	// the packets queued before the fence. They are written first, the ordered
+	// packets queued after the fence are held until the gate is closed.
+	fenced []queuedPacket
+	gate   chan struct{}
+}
+
+type queuedPacket struct {
+	buf      *lib.Buffer
+	order    uint8 // zero - unordered
+	priority gen.MessagePriority
+}
+
+func (q *sendQueue) init(limit int) {
+	q.limit = limit
+	q.wake = make(chan struct{}, 1)
+}
+
+// push queues the packet. The queued packets with the same order key and lower
+// priority are moved along with it, so it doesn't overtake them. Returns
+// gen.ErrTooLarge if the queue is full, gen.ErrNoConnection if the link has
+// left the pool.
+func (q *sendQueue) push(packet queuedPacket, priority gen.MessagePriority) (int, error) {
+	p := int(priority)
+	if p < 0 {
+		p = 0
+	} else if p >= len(q.queues) {
+		p = len(q.queues) - 1
+	}
+
+	packet.priority = gen.MessagePriority(p)
+
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return 0, gen.ErrNoConnection
+	}
+	if q.gate != nil && packet.order == 0 {
+		// unordered one (the service frames as well) is not held by the gate
+		q.fenced = append(q.fenced, packet)
+		q.Unlock()
+		q.signal()
+		return len(q.fenced), nil
+	}
+	if len(q.queues[p]) >= q.limit {
+		q.Unlock()
+		return 0, gen.ErrTooLarge
+	}
+	if packet.order != 0 {
+		for lower := p - 1; lower >= 0; lower-- {
+			queue := q.queues[lower]
+			kept := queue[:0]
+			for _, qp := range queue {
+				if qp.order == packet.order {
+					qp.priority = packet.priority
+					q.queues[p] = append(q.queues[p], qp)
+					continue
+				}
+				kept = append(kept, qp)
+			}
+			for i := len(kept); i < len(queue); i++ {
+				queue[i] = queuedPacket{}
+			}
+			q.queues[lower] = kept
+		}
+	}
+	q.queues[p] = append(q.queues[p], packet)
+
+	depth := len(q.fenced)
+	for i := range q.queues {
+		depth += len(q.queues[i])
+	}
+	q.Unlock()
+
+	q.signal()
+	return depth, nil
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// fence puts the queued packets ahead of the ones queued after, followed by the
+// given frame (if any). The ordered packets queued after the fence are held
+// until the gate is closed.
+func (q *sendQueue) fence(frame *lib.Buffer, gate chan struct{}) {
+	q.Lock()
+	for p := len(q.queues) - 1; p >= 0; p-- {
+		q.fenced = append(q.fenced, q.queues[p]...)
+		q.queues[p] = nil
+	}
+	if frame != nil {
+		q.fenced = append(q.fenced, queuedPacket{buf: frame, priority: priorityControl})
+	}
+	q.gate = gate
+	q.Unlock()
+
+	q.signal()
+}
+
+// next returns the packet to write. If there is nothing to write at the moment,
+// returns the channel to wait on. Returns false if the queue is closed.
+func (q *sendQueue) next() (queuedPacket, chan struct{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+	if q.closed {
+		return queuedPacket{}, nil, false
+	}
+	if len(q.fenced) > 0 {
+		packet := q.fenced[0]
+		q.fenced[0] = queuedPacket{}
+		q.fenced = q.fenced[1:]
+		return packet, nil, true
+	}
+	if q.gate != nil {
+		select {
+		case <-q.gate:
+			q.gate = nil
+		default:
+			return queuedPacket{}, q.gate, true
+		}
+	}
+	for p := len(q.queues) - 1; p >= 0; p-- {
+		if len(q.queues[p]) == 0 {
+			continue
+		}
+		packet := q.queues[p][0]
+		q.queues[p][0] = queuedPacket{}
+		q.queues[p] = q.queues[p][1:]
+		return packet, nil, true
+	}
+	return queuedPacket{}, q.wake, true
+}
+
+// close stops the writer of the link and releases the queued packets
+func (q *sendQueue) close() {
+	q.Lock()
+	q.closed = true
+	for p := range q.queues {
+		for _, packet := range q.queues[p] {
+			lib.ReleaseBuffer(packet.buf)
+		}
+		q.queues[p] = nil
+	}
+	for _, packet := range q.fenced {
+		lib.ReleaseBuffer(packet.buf)
+	}
+	q.fenced = nil
+	q.Unlock()
+
+	q.signal()
+}
+
+func (q *sendQueue) depth(info *gen.SendQueueInfo) {
+	q.Lock()
+	defer q.Unlock()
+	info.Normal += len(q.queues[gen.MessagePriorityNormal])
+	info.High += len(q.queues[gen.MessagePriorityHigh])
+	info.Max += len(q.queues[gen.MessagePriorityMax])
+	for _, packet := range q.fenced {
+		switch packet.priority {
+		case gen.MessagePriorityNormal:
+			info.Normal++
+		case gen.MessagePriorityHigh:
+			info.High++
+		default:
+			info.Max++
+		}
+	}
+}
+
+// bandwidth limits the rate of the outgoing traffic of the connection
+// (token bucket, the burst is one second of the limit)
+type bandwidth struct {
+	sync.Mutex
+	tokens    float64
+	last      time.Time
+	throttled int64 // nanoseconds
+}
+
+func (c *connection) throttle(n int) {
+	limit := c.core.NetworkBandwidthLimit(c.peer)
+	if limit <= 0 {
+		return
+	}
+
+	b := &c.bandwidth
+	b.Lock()
+	now := time.Now()
+	if b.last.IsZero() {
+		b.tokens = float64(limit)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * float64(limit)
+		if b.tokens > float64(limit) {
+			b.tokens = float64(limit)
+		}
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / float64(limit) * float64(time.Second))
+	}
+	b.Unlock()
+
+	if wait > 0 {
+		atomic.AddInt64(&b.throttled, int64(wait))
+		time.Sleep(wait)
+	}
+}
+
+func (c *connection) write(pi *pool_item, buf *lib.Buffer, order uint8, priority gen.MessagePriority) error {
+	if pi.delayed.active.Load() {
+		// there are delayed packets (fault injection). keep the order
+		pi.delayed.push(c, pi, delayedPacket{buf: buf, order: order, priority: priority})
+		return nil
+	}
+	return c.enqueue(pi, buf, order, priority)
+}
+
+// fence fences the send queue of the link. If there are delayed packets (fault
+// injection), the queue is fenced once they are released.
+func (c *connection) fence(pi *pool_item, frame *lib.Buffer, gate chan struct{}) {
+	if pi.delayed.active.Load() {
+		pi.delayed.push(c, pi, delayedPacket{buf: frame, gate: gate})
+		return
+	}
+	pi.queue.fence(frame, gate)
+}
+
+func (c *connection) enqueue(pi *pool_item, buf *lib.Buffer, order uint8, priority gen.MessagePriority) error {
+	depth, err := pi.queue.push(queuedPacket{buf: buf, order: order}, priority)
+	switch err {
+	case nil:
+	case gen.ErrTooLarge:
+		atomic.AddUint64(&c.queueDropped, 1)
+		lib.ReleaseBuffer(buf)
+		return err
+	default:
+		// the link has left the pool
+		lib.ReleaseBuffer(buf)
+		return nil
+	}
+	for {
+		peak := atomic.LoadInt64(&c.queuePeak)
+		if int64(depth) <= peak || atomic.CompareAndSwapInt64(&c.queuePeak, peak, int64(depth)) {
+			break
+		}
+	}
+	return nil
+}
+
+// writer writes the queued packets of the pool link until it leaves the pool.
+// It is the only one writing to the link, so the throttling blocks nobody else.
+func (c *connection) writer(pi *pool_item) {
+	q := &pi.queue
+	for {
+		packet, wait, ok := q.next()
+		if ok == false {
+			return
+		}
+		if wait != nil {
+			select {
+			case <-wait:
+			case <-q.wake:
+			}
+			continue
+		}
+		c.throttle(packet.buf.Len())
+		pi.fl.Write(packet.buf.B)
+		lib.ReleaseBuffer(packet.buf)
+	}
+}
+
+func (c *connection) sendQueueInfo() gen.SendQueueInfo {
+	info := gen.SendQueueInfo{
+		Peak:           int(atomic.LoadInt64(&c.queuePeak)),
+		Limit:          c.sendQueueLimit,
+		Dropped:        atomic.LoadUint64(&c.queueDropped),
+		BandwidthLimit: c.core.NetworkBandwidthLimit(c.peer),
+		Throttled:      time.Duration(atomic.LoadInt64(&c.bandwidth.throttled)),
+	}
+	c.pool_mutex.RLock()
+	defer c.pool_mutex.RUnlock()
+	for _, pi := range c.pool {
+		pi.queue.depth(&info)
+	}
+	return info
+}
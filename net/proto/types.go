@@ -37,6 +37,15 @@ const (
 	protoMessageTerminateEvent      byte = 185
 	protoMessageTerminateEventCache byte = 186
 
+	// service frames (heartbeat, pool resizing). handled by the link, not queued
+	protoMessagePing     byte = 160
+	protoMessagePong     byte = 161
+	protoMessageSync     byte = 162
+	protoMessageSyncAck  byte = 163
+	protoMessagePoolSize byte = 164
+	protoMessageLeave    byte = 165
+	protoMessageLeft     byte = 166
+
 	// any structured message (link/monitor/spawn/etc...)
 	protoMessageAny byte = 199
 
@@ -46,6 +55,10 @@ const (
 	protoMessageF byte = 202 // fragmented
 	protoMessageP byte = 203 // proxy
 
+	// the flag of the compression type: the message is compressed with the
+	// shared dictionary, which ID follows the compression type
+	protoCompressionDict byte = 0x80
+
 	// TODO
 	// protoFragmentSize int = 65000
 )
@@ -25,6 +25,8 @@ type stateDecode struct {
 
 	decodeType bool
 	decoder    *decoder
+
+	refs refs // used by the root state only
 }
 
 // Decode
@@ -41,6 +43,9 @@ func Decode(packet []byte, options Options) (_ any, _ []byte, ret error) {
 		options:    options,
 		decodeType: true,
 	}
+	// child states inherit options, so they share the same refs
+	state.options.refs = &state.refs
+	state.refs.message = packet
 
 	dec, packet, err := getDecoder(packet, state)
 	if err != nil {
@@ -55,6 +60,9 @@ func Decode(packet []byte, options Options) (_ any, _ []byte, ret error) {
 
 	value, packet, err := dec.Decode(&v, packet, state)
 	if err != nil {
+		if fe, ok := err.(*fieldError); ok {
+			fe.path = dec.Type.String() + fe.path
+		}
 		return nil, nil, fmt.Errorf("malformed SDF: %w", err)
 	}
 	return value.Interface(), packet, nil
@@ -136,6 +144,22 @@ func getRegDecoder(packet []byte, state *stateDecode) (*decoder, []byte, error)
 		state.decodeType = false
 		return v.(*decoder), packet, nil
 	}
+
+	if v, found := registering.Load(id); found {
+		// pointer to the struct being registered (recursive type).
+		// its decoder will be taken on decoding
+		dec := &decoder{
+			Type: v.(reflect.Type),
+			Decode: func(value *reflect.Value, packet []byte, state *stateDecode) (*reflect.Value, []byte, error) {
+				v, found := decoders.Load(id)
+				if found == false {
+					return nil, nil, fmt.Errorf("unknown reg type %v for decoding", id)
+				}
+				return v.(*decoder).Decode(value, packet, state)
+			},
+		}
+		return dec, packet, nil
+	}
 	return nil, nil, fmt.Errorf("unknown reg type %v for decoding", id)
 }
 
@@ -231,7 +255,7 @@ func decodeType(fold []byte, state *stateDecode) (*decoder, []byte, error) {
 				state.decoder = decValue
 				_, p, err = decValue.Decode(&v, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%v]", k))
 				}
 				packet = p
 
@@ -318,7 +342,7 @@ func decodeType(fold []byte, state *stateDecode) (*decoder, []byte, error) {
 				item := value.Index(i)
 				_, p, err := decItem.Decode(&item, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%d]", i))
 				}
 				packet = p
 			}
@@ -380,7 +404,7 @@ func decodeType(fold []byte, state *stateDecode) (*decoder, []byte, error) {
 				item := value.Index(i)
 				_, p, err := decItem.Decode(&item, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%d]", i))
 				}
 				packet = p
 			}
@@ -399,6 +423,100 @@ func decodeType(fold []byte, state *stateDecode) (*decoder, []byte, error) {
 
 		return &dec, nil, nil
 
+	case sdtPointer:
+		decElem, f, err := decodeType(fold[1:], state)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to unfold type (pointer): %s", err)
+		}
+
+		vtype := reflect.PointerTo(decElem.Type)
+
+		fdec := func(value *reflect.Value, packet []byte, state *stateDecode) (*reflect.Value, []byte, error) {
+			if len(packet) == 0 {
+				return nil, nil, errDecodeEOD
+			}
+
+			ptype := vtype
+			if value != nil {
+				// might be a pointer to the named type of the same kind
+				ptype = value.Type()
+			}
+
+			var x reflect.Value
+			refs := state.options.refs
+			if refs == nil {
+				return nil, nil, errInternal
+			}
+
+			switch packet[0] {
+			case sdtNil:
+				packet = packet[1:]
+				x = reflect.Zero(ptype)
+
+			case sdtPointerRef:
+				if len(packet) < 5 {
+					return nil, nil, errDecodeEOD
+				}
+				id := binary.BigEndian.Uint32(packet[1:5])
+				packet = packet[5:]
+				ref, found := refs.decoded[id]
+				if found == false {
+					return nil, nil, fmt.Errorf("unknown pointer reference %d", id)
+				}
+				x = ref
+				if x.Type() != ptype {
+					return nil, nil, fmt.Errorf("pointer reference %d has type %v (exp: %v)",
+						id, x.Type(), ptype)
+				}
+
+			case sdtPointer:
+				id, err := refs.offset(packet)
+				if err != nil {
+					return nil, nil, err
+				}
+				packet = packet[1:]
+				x = reflect.New(ptype.Elem())
+				// register before decoding the value, so it can refer to itself
+				if refs.decoded == nil {
+					refs.decoded = make(map[uint32]reflect.Value)
+				}
+				refs.decoded[id] = x
+
+				if state.child == nil {
+					state.child = &stateDecode{
+						options: state.options,
+						decoder: decElem,
+					}
+				}
+				elem := x.Elem()
+				_, p, err := decElem.Decode(&elem, packet, state.child)
+				if err != nil {
+					return nil, nil, err
+				}
+				packet = p
+
+			default:
+				return nil, nil, fmt.Errorf("incorrect pointer type %d", packet[0])
+			}
+
+			if value == nil {
+				value = &x
+			} else {
+				value.Set(x)
+			}
+			return value, packet, nil
+		}
+
+		dec := decoder{
+			Type:   vtype,
+			Decode: fdec,
+		}
+		if state.options.Cache != nil && len(f) == 0 {
+			state.options.Cache.LoadOrStore(string(fold), &dec)
+		}
+
+		return &dec, f, nil
+
 	case sdtReg:
 		return getRegDecoder(fold[1:], state)
 	}
@@ -1153,6 +1271,9 @@ func decodeAny(value *reflect.Value, packet []byte, state *stateDecode) (*reflec
 		return value, packet, nil
 	}
 
+	if dec.Type.AssignableTo(value.Type()) == false {
+		return nil, nil, fmt.Errorf("type %v does not implement %v", dec.Type, value.Type())
+	}
 	v := reflect.Indirect(reflect.New(dec.Type))
 	_, packet, err = dec.Decode(&v, p, state)
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
 )
 
 func TestDecodeBool(t *testing.T) {
@@ -3547,3 +3548,109 @@ func TestDecodeRegArrayRegArray(t *testing.T) {
 		t.Fatal("incorrect value")
 	}
 }
+
+type testDecPointerNode struct {
+	Value int
+	Next  *testDecPointerNode
+}
+
+type testDecPointerStruct struct {
+	A    *int
+	B    *int
+	Opt  *string
+	Tree *testDecPointerNode
+}
+
+func TestDecodePointer(t *testing.T) {
+	packet := []byte{sdtType, 0, 3,
+		sdtSlice, sdtPointer, sdtInt,
+		sdtSlice,
+		0, 0, 0, 3,
+		sdtPointer, 0, 0, 0, 0, 0, 0, 0, 5,
+		sdtNil,
+		sdtPointerRef, 0, 0, 0, 11,
+	}
+
+	value, _, err := Decode(packet, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := value.([]*int)
+	if ok == false || len(v) != 3 {
+		t.Fatalf("incorrect value %#v", value)
+	}
+	if *v[0] != 5 || v[1] != nil || v[0] != v[2] {
+		t.Fatal("incorrect value")
+	}
+}
+
+func TestDecodeRegPointer(t *testing.T) {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+
+	// recursive type
+	if err := RegisterTypeOf(&testDecPointerNode{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTypeOf(testDecPointerStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	x := 10
+	list := &testDecPointerNode{Value: 1}
+	list.Next = &testDecPointerNode{Value: 2, Next: list} // cycle
+	value := testDecPointerStruct{A: &x, B: &x, Tree: list}
+
+	if err := Encode(value, b, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result, _, err := Decode(b.B, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := result.(testDecPointerStruct)
+	if *v.A != x || v.A != v.B {
+		t.Fatal("incorrect shared pointer")
+	}
+	if v.Opt != nil {
+		t.Fatal("incorrect nil pointer")
+	}
+	if v.Tree.Value != 1 || v.Tree.Next.Value != 2 || v.Tree.Next.Next != v.Tree {
+		t.Fatal("incorrect pointer graph")
+	}
+
+	// pointer in the interface value
+	b.Reset()
+	if err := Encode([]any{list, list.Next}, b, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result, _, err = Decode(b.B, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := result.([]any)
+	if l[0].(*testDecPointerNode).Next != l[1].(*testDecPointerNode) {
+		t.Fatal("incorrect pointer graph")
+	}
+}
+
+func TestDecodeRefOffset(t *testing.T) {
+	message := []byte{sdtSlice, 0, 0, 0, 1, sdtPointer, sdtInt, 0, 0, 0, 0, 0, 0, 0, 5}
+	r := refs{message: message}
+
+	for _, packet := range [][]byte{message[5:], message[5:7], message[5:7:7]} {
+		offset, err := r.offset(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != 5 {
+			t.Fatalf("incorrect offset %d", offset)
+		}
+	}
+
+	// the copy is not the part of the message
+	packet := append([]byte{}, message[5:]...)
+	if _, err := r.offset(packet); err == nil {
+		t.Fatal("must be failed with the copied packet")
+	}
+}
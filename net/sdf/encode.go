@@ -21,14 +21,12 @@ var (
 type stateEncode struct {
 	child *stateEncode
 
-	// TODO loop detection (in slices)
-	//loop map[unsafe.Pointer]struct{}
-	//ptr unsafe.Pointer
-
 	encodeType bool
 
 	options Options
 	encoder encoder
+
+	refs refs // used by the root state only
 }
 
 // Encode
@@ -38,9 +36,10 @@ func Encode(x any, b *lib.Buffer, options Options) (ret error) {
 	}
 	state := &stateEncode{
 		options: options,
-		// TODO
-		//loop:       make(map[unsafe.Pointer]struct{}),
 	}
+	// child states inherit options, so they share the same refs
+	state.options.refs = &state.refs
+	state.refs.base = b.Len()
 
 	xv := reflect.ValueOf(x)
 	enc, err := getEncoder(xv.Type(), state)
@@ -64,7 +63,13 @@ func Encode(x any, b *lib.Buffer, options Options) (ret error) {
 	}
 
 	b.Append(enc.Prefix)
-	return enc.Encode(xv, b, state)
+	if err := enc.Encode(xv, b, state); err != nil {
+		if fe, ok := err.(*fieldError); ok {
+			fe.path = xv.Type().String() + fe.path
+		}
+		return err
+	}
+	return nil
 }
 
 func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
@@ -138,6 +143,7 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 
 		prefix := append([]byte{sdtMap}, keyPrefix...)
 		prefix = append(prefix, itemPrefix...)
+		loop := mayLoop(t)
 
 		fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
 			if state.encodeType {
@@ -164,6 +170,13 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 			buf := b.Extend(4)
 			binary.BigEndian.PutUint32(buf, uint32(n))
 
+			if loop && n > 0 {
+				if err := state.enterLoop(value); err != nil {
+					return err
+				}
+				defer state.leaveLoop(value)
+			}
+
 			iter := value.MapRange()
 			for iter.Next() {
 				state.encodeType = false
@@ -172,7 +185,7 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 				}
 				state.encodeType = false
 				if err := encItem.Encode(iter.Value(), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%v]", iter.Key()))
 				}
 			}
 
@@ -203,6 +216,7 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 			}
 		}
 		prefix := append([]byte{sdtSlice}, itemPrefix...)
+		loop := mayLoop(t)
 		fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
 			if state.encodeType {
 				buf := b.Extend(3)
@@ -229,10 +243,17 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 			buf := b.Extend(4)
 			binary.BigEndian.PutUint32(buf, uint32(n))
 
+			if loop && n > 0 {
+				if err := state.enterLoop(value); err != nil {
+					return err
+				}
+				defer state.leaveLoop(value)
+			}
+
 			for i := 0; i < n; i++ {
 				state.encodeType = false
 				if err := encItem.Encode(value.Index(i), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%d]", i))
 				}
 			}
 
@@ -287,7 +308,7 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 			for i := 0; i < l; i++ {
 				state.encodeType = false
 				if err := encItem.Encode(value.Index(i), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%d]", i))
 				}
 			}
 
@@ -304,7 +325,90 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 		return enc, nil
 
 	case reflect.Pointer:
-		return nil, fmt.Errorf("pointer type is not supported")
+		elem := t.Elem()
+		encElem, err := getEncoder(elem, state)
+		if err != nil {
+			// pointer to the struct being registered (recursive type).
+			// its encoder will be taken on encoding
+			if _, pending := registering.Load(regTypeName(elem)); pending == false {
+				return nil, err
+			}
+			encElem = &encoder{Prefix: regPrefix(regTypeName(elem))}
+		}
+
+		elemPrefix := encElem.Prefix
+		if state.options.RegCache != nil {
+			if v, found := state.options.RegCache.Load(elem); found {
+				elemPrefix = v.([]byte)
+			}
+		}
+		prefix := append([]byte{sdtPointer}, elemPrefix...)
+
+		fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
+			if state.encodeType {
+				buf := b.Extend(3)
+				buf[0] = sdtType
+				binary.BigEndian.PutUint16(buf[1:3], uint16(len(prefix)))
+				b.Append(prefix)
+			}
+
+			if value.IsNil() {
+				b.AppendByte(sdtNil)
+				return nil
+			}
+
+			refs := state.options.refs
+			if refs == nil {
+				return errInternal
+			}
+			key := refKey{t: t, p: value.Pointer()}
+			if id, found := refs.encoded[key]; found {
+				// has been encoded already. shared or cyclic pointer
+				buf := b.Extend(5)
+				buf[0] = sdtPointerRef
+				binary.BigEndian.PutUint32(buf[1:5], id)
+				return nil
+			}
+			if refs.encoded == nil {
+				refs.encoded = make(map[refKey]uint32)
+			}
+			refs.encoded[key] = uint32(b.Len() - refs.base)
+			b.AppendByte(sdtPointer)
+
+			enc := encElem
+			if enc.Encode == nil {
+				v, found := encoders.Load(elem)
+				if found == false {
+					return fmt.Errorf("no encoder for type %v", elem)
+				}
+				enc = v.(*encoder)
+			}
+
+			if state.child == nil {
+				state.child = &stateEncode{
+					options: state.options,
+				}
+			}
+			state = state.child
+			state.encodeType = false
+			return enc.Encode(value.Elem(), b, state)
+		}
+
+		enc := &encoder{
+			Prefix: prefix,
+			Encode: fenc,
+		}
+		if state.options.Cache != nil {
+			state.options.Cache.Store(t, enc)
+		}
+		return enc, nil
+
+	case reflect.Interface:
+		// interface other than any and error. encoded the same way as any,
+		// so the concrete value must be of the registered type
+		enc := &encoder{Prefix: []byte{sdtAny}, Encode: encodeAny}
+		encoders.Store(t, enc)
+		return enc, nil
 	}
 
 	// look among the standard types
@@ -320,6 +424,58 @@ func getEncoder(t reflect.Type, state *stateEncode) (*encoder, error) {
 	return enc, nil
 }
 
+// enterLoop marks the slice/map as being encoded. Returns error if it is
+// being encoded already, which means the value contains itself.
+func (s *stateEncode) enterLoop(value reflect.Value) error {
+	refs := s.options.refs
+	if refs == nil {
+		return nil
+	}
+	key := refKey{t: value.Type(), p: value.Pointer()}
+	if _, found := refs.loop[key]; found {
+		return fmt.Errorf("loop detected: value of %v contains itself", value.Type())
+	}
+	if refs.loop == nil {
+		refs.loop = make(map[refKey]struct{})
+	}
+	refs.loop[key] = struct{}{}
+	return nil
+}
+
+func (s *stateEncode) leaveLoop(value reflect.Value) {
+	delete(s.options.refs.loop, refKey{t: value.Type(), p: value.Pointer()})
+}
+
+// mayLoop returns true if the value of the given type is able to contain itself.
+// It is possible through the interface values only, since the pointers are
+// encoded once.
+func mayLoop(t reflect.Type) bool {
+	return hasInterface(t, make(map[reflect.Type]bool))
+}
+
+func hasInterface(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasInterface(t.Elem(), visited)
+	case reflect.Map:
+		return hasInterface(t.Key(), visited) || hasInterface(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasInterface(t.Field(i).Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func encodePID(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
 	if state.encodeType {
 		b.AppendByte(sdtPID)
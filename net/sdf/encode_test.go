@@ -3915,3 +3915,53 @@ func TestEncodeStructWithMap(t *testing.T) {
 		t.Fatal("incorrect value")
 	}
 }
+
+func TestEncodePointer(t *testing.T) {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+
+	x := 5
+	value := []*int{&x, nil, &x}
+	expect := []byte{sdtType, 0, 3,
+		sdtSlice, sdtPointer, sdtInt,
+		sdtSlice,
+		0, 0, 0, 3,
+		sdtPointer, 0, 0, 0, 0, 0, 0, 0, 5,
+		sdtNil,
+		sdtPointerRef, 0, 0, 0, 11, // refers to the first one (offset)
+	}
+
+	if err := Encode(value, b, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b.B, expect) {
+		fmt.Printf("exp %#v\n", expect)
+		fmt.Printf("got %#v\n", b.B)
+		t.Fatal("incorrect value")
+	}
+}
+
+func TestEncodeLoop(t *testing.T) {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+
+	value := []any{1, nil}
+	value[1] = value
+	if err := Encode(value, b, Options{}); err == nil {
+		t.Fatal("loop must be detected")
+	}
+
+	m := map[string]any{}
+	m["m"] = []any{m}
+	b.Reset()
+	if err := Encode(m, b, Options{}); err == nil {
+		t.Fatal("loop must be detected")
+	}
+
+	// the same slice in the different branches is not a loop
+	s := []any{1, 2}
+	b.Reset()
+	if err := Encode([]any{s, s}, b, Options{}); err != nil {
+		t.Fatal(err)
+	}
+}
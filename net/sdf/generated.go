@@ -0,0 +1,360 @@
+package sdf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/sllt/sparrow/lib"
+)
+
+// GeneratedEncoder is implemented by the struct types with the code generated
+// by sdfgen. The generated code encodes the fields in the same way the
+// reflection-based encoder does, so the nodes with and without generated code
+// are able to exchange these types.
+type GeneratedEncoder interface {
+	EncodeSDF(*Encoder) error
+}
+
+// GeneratedDecoder is implemented by the pointer to the struct type with
+// the code generated by sdfgen.
+type GeneratedDecoder interface {
+	DecodeSDF(*Decoder) error
+}
+
+var (
+	typeGeneratedEncoder = reflect.TypeOf((*GeneratedEncoder)(nil)).Elem()
+	typeGeneratedDecoder = reflect.TypeOf((*GeneratedDecoder)(nil)).Elem()
+
+	// encoders/decoders of the fields encoded with reflection by the generated code
+	fieldEncoders sync.Map // reflect.Type => *encoder
+	fieldDecoders sync.Map // reflect.Type => *decoder
+)
+
+func isGenerated(tov reflect.Type) bool {
+	return tov.Implements(typeGeneratedEncoder) &&
+		reflect.PointerTo(tov).Implements(typeGeneratedDecoder)
+}
+
+// Encoder is used by the generated code to encode the struct fields.
+// The first error is kept and returned by Err, subsequent calls do nothing.
+type Encoder struct {
+	b     *lib.Buffer
+	state *stateEncode
+	err   error
+}
+
+// Err returns the first error occurred while encoding
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+func (e *Encoder) Bool(v bool) {
+	if e.err != nil {
+		return
+	}
+	if v {
+		e.b.AppendByte(1)
+		return
+	}
+	e.b.AppendByte(0)
+}
+
+func (e *Encoder) String(v string) {
+	if e.err != nil {
+		return
+	}
+	if len(v) > math.MaxUint16 {
+		e.err = ErrStringTooLong
+		return
+	}
+	binary.BigEndian.PutUint16(e.b.Extend(2), uint16(len(v)))
+	e.b.AppendString(v)
+}
+
+func (e *Encoder) Binary(v []byte) {
+	if e.err != nil {
+		return
+	}
+	if int64(len(v)) > math.MaxUint32 {
+		e.err = ErrBinaryTooLong
+		return
+	}
+	binary.BigEndian.PutUint32(e.b.Extend(4), uint32(len(v)))
+	e.b.Append(v)
+}
+
+func (e *Encoder) Int(v int)         { e.Uint64(uint64(v)) }
+func (e *Encoder) Int8(v int8)       { e.Uint8(uint8(v)) }
+func (e *Encoder) Int16(v int16)     { e.Uint16(uint16(v)) }
+func (e *Encoder) Int32(v int32)     { e.Uint32(uint32(v)) }
+func (e *Encoder) Int64(v int64)     { e.Uint64(uint64(v)) }
+func (e *Encoder) Uint(v uint)       { e.Uint64(uint64(v)) }
+func (e *Encoder) Float32(v float32) { e.Uint32(math.Float32bits(v)) }
+func (e *Encoder) Float64(v float64) { e.Uint64(math.Float64bits(v)) }
+
+func (e *Encoder) Uint8(v uint8) {
+	if e.err != nil {
+		return
+	}
+	e.b.AppendByte(v)
+}
+
+func (e *Encoder) Uint16(v uint16) {
+	if e.err != nil {
+		return
+	}
+	binary.BigEndian.PutUint16(e.b.Extend(2), v)
+}
+
+func (e *Encoder) Uint32(v uint32) {
+	if e.err != nil {
+		return
+	}
+	binary.BigEndian.PutUint32(e.b.Extend(4), v)
+}
+
+func (e *Encoder) Uint64(v uint64) {
+	if e.err != nil {
+		return
+	}
+	binary.BigEndian.PutUint64(e.b.Extend(8), v)
+}
+
+// Field encodes the value the given pointer points to using reflection.
+// It is used for the fields of the types the generated code has no
+// special case for.
+func (e *Encoder) Field(ptr any) {
+	if e.err != nil {
+		return
+	}
+	value := reflect.ValueOf(ptr).Elem()
+	enc, err := fieldEncoder(value.Type())
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.state.encodeType = false
+	e.err = enc.Encode(value, e.b, e.state)
+}
+
+// Decoder is used by the generated code to decode the struct fields.
+// The first error is kept and returned by Err, subsequent calls return
+// zero values.
+type Decoder struct {
+	packet []byte
+	state  *stateDecode
+	err    error
+}
+
+// Err returns the first error occurred while decoding
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.packet) < n {
+		d.err = errDecodeEOD
+		return nil
+	}
+	v := d.packet[:n]
+	d.packet = d.packet[n:]
+	return v
+}
+
+func (d *Decoder) Bool() bool {
+	v := d.next(1)
+	if v == nil {
+		return false
+	}
+	return v[0] == 1
+}
+
+func (d *Decoder) String() string {
+	l := d.next(2)
+	if l == nil {
+		return ""
+	}
+	v := d.next(int(binary.BigEndian.Uint16(l)))
+	if v == nil {
+		return ""
+	}
+	return string(v)
+}
+
+func (d *Decoder) Binary() []byte {
+	l := d.next(4)
+	if l == nil {
+		return nil
+	}
+	v := d.next(int(binary.BigEndian.Uint32(l)))
+	if v == nil {
+		return nil
+	}
+	// packet is a part of the buffer which is going back to the pool
+	return append([]byte{}, v...)
+}
+
+func (d *Decoder) Int() int         { return int(d.Uint64()) }
+func (d *Decoder) Int8() int8       { return int8(d.Uint8()) }
+func (d *Decoder) Int16() int16     { return int16(d.Uint16()) }
+func (d *Decoder) Int32() int32     { return int32(d.Uint32()) }
+func (d *Decoder) Int64() int64     { return int64(d.Uint64()) }
+func (d *Decoder) Uint() uint       { return uint(d.Uint64()) }
+func (d *Decoder) Float32() float32 { return math.Float32frombits(d.Uint32()) }
+func (d *Decoder) Float64() float64 { return math.Float64frombits(d.Uint64()) }
+
+func (d *Decoder) Uint8() uint8 {
+	v := d.next(1)
+	if v == nil {
+		return 0
+	}
+	return v[0]
+}
+
+func (d *Decoder) Uint16() uint16 {
+	v := d.next(2)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(v)
+}
+
+func (d *Decoder) Uint32() uint32 {
+	v := d.next(4)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(v)
+}
+
+func (d *Decoder) Uint64() uint64 {
+	v := d.next(8)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// Field decodes the value into the given pointer using reflection
+func (d *Decoder) Field(ptr any) {
+	if d.err != nil {
+		return
+	}
+	value := reflect.ValueOf(ptr).Elem()
+	dec, err := fieldDecoder(value.Type())
+	if err != nil {
+		d.err = err
+		return
+	}
+	_, d.packet, d.err = dec.Decode(&value, d.packet, d.state)
+}
+
+func fieldEncoder(t reflect.Type) (*encoder, error) {
+	if v, found := fieldEncoders.Load(t); found {
+		return v.(*encoder), nil
+	}
+	enc, err := getEncoder(t, &stateEncode{})
+	if err != nil {
+		return nil, err
+	}
+	fieldEncoders.Store(t, enc)
+	return enc, nil
+}
+
+func fieldDecoder(t reflect.Type) (*decoder, error) {
+	if v, found := fieldDecoders.Load(t); found {
+		return v.(*decoder), nil
+	}
+	enc, err := fieldEncoder(t)
+	if err != nil {
+		return nil, err
+	}
+	dec, _, err := decodeType(enc.Prefix, &stateDecode{})
+	if err != nil {
+		return nil, err
+	}
+	fieldDecoders.Store(t, dec)
+	return dec, nil
+}
+
+// generatedEncoder returns the encoder using the generated code. Values that
+// can't be used as an interface (unexported fields) are encoded by fallback.
+func generatedEncoder(fallback encodeFunc) encodeFunc {
+	return func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
+		if value.CanInterface() == false {
+			return fallback(value, b, state)
+		}
+		if state.child == nil {
+			state.child = &stateEncode{options: state.options}
+		}
+		e := Encoder{b: b, state: state.child}
+		if err := value.Interface().(GeneratedEncoder).EncodeSDF(&e); err != nil {
+			return err
+		}
+		return e.err
+	}
+}
+
+func generatedDecoder(tov reflect.Type,
+	fallback func(*reflect.Value, []byte, *stateDecode) (*reflect.Value, []byte, error),
+) func(*reflect.Value, []byte, *stateDecode) (*reflect.Value, []byte, error) {
+
+	return func(value *reflect.Value, packet []byte, state *stateDecode) (*reflect.Value, []byte, error) {
+		if value == nil {
+			v := reflect.Indirect(reflect.New(tov))
+			value = &v
+		}
+		if value.CanSet() == false {
+			return fallback(value, packet, state)
+		}
+		if state.child == nil {
+			state.child = &stateDecode{options: state.options}
+		}
+		d := Decoder{packet: packet, state: state.child}
+		if err := value.Addr().Interface().(GeneratedDecoder).DecodeSDF(&d); err != nil {
+			return nil, nil, err
+		}
+		if d.err != nil {
+			return nil, nil, d.err
+		}
+		return value, d.packet, nil
+	}
+}
+
+// MarshalGenerated encodes the fields of the value with the generated code.
+// It is used by the generated MarshalSDF method.
+func MarshalGenerated(v GeneratedEncoder, w io.Writer) error {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+
+	state := &stateEncode{}
+	state.options.refs = &state.refs
+	e := Encoder{b: b, state: state}
+	if err := v.EncodeSDF(&e); err != nil {
+		return err
+	}
+	if e.err != nil {
+		return e.err
+	}
+	_, err := w.Write(b.B)
+	return err
+}
+
+// UnmarshalGenerated decodes the fields of the value with the generated code.
+// It is used by the generated UnmarshalSDF method.
+func UnmarshalGenerated(v GeneratedDecoder, data []byte) error {
+	state := &stateDecode{}
+	state.options.refs = &state.refs
+	state.refs.message = data
+	d := Decoder{packet: data, state: state}
+	if err := v.DecodeSDF(&d); err != nil {
+		return err
+	}
+	return d.err
+}
@@ -13,6 +13,8 @@ var (
 	// register generic Sparrow Framework types for the networking
 	genTypes = []any{
 
+		time.Duration(0),
+
 		gen.Env(""),
 		gen.LogLevel(0),
 		gen.ProcessState(0),
@@ -33,6 +35,7 @@ var (
 		gen.Compression{},
 		gen.ProcessFallback{},
 		gen.MailboxQueues{},
+		gen.TimerInfo{},
 		gen.ProcessInfo{},
 		gen.ProcessShortInfo{},
 		gen.ProcessOptions{},
@@ -45,6 +48,12 @@ var (
 		gen.NetworkProxyFlags{},
 		gen.NetworkSpawnInfo{},
 		gen.NetworkApplicationStartInfo{},
+		gen.CompressionDecision{},
+		gen.CompressionInfo{},
+		gen.FaultsInfo{},
+		gen.NetworkFaults{},
+		gen.HeartbeatInfo{},
+		gen.SendQueueInfo{},
 		gen.RemoteNodeInfo{},
 		gen.RouteInfo{},
 		gen.ProxyRouteInfo{},
@@ -129,6 +138,7 @@ var (
 		gen.ErrUnknown,
 		gen.ErrNameUnknown,
 		gen.ErrNotAllowed,
+		gen.ErrAccessDenied,
 		gen.ErrProcessUnknown,
 		gen.ErrProcessTerminated,
 		gen.ErrMetaUnknown,
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,16 +25,49 @@ type encoder struct {
 	Encode encodeFunc
 }
 
+// regTypeName returns the name the type is sent over the network with. It is
+// "#<package path>/<type name>" unless the type has been registered with a
+// custom name. The name of an instantiated generic type includes the fully
+// qualified type arguments, e.g. "#example.com/pkg/Pair[int,example.com/pkg.Item]".
 func regTypeName(t reflect.Type) string {
+	if v, found := typeNames.Load(t); found {
+		return v.(string)
+	}
 	return fmt.Sprintf("#%s/%s", t.PkgPath(), t.Name())
 }
 
+// RegisterOptions defines how the registered type is encoded
+type RegisterOptions struct {
+	// Tagged enables tagged encoding of the struct type. Every field is encoded
+	// along with its tag, so the decoder skips the fields unknown to this node
+	// and leaves the missing ones zero. The field tag is taken from the struct
+	// tag `sdf:"<number>"` (`sdf:"-"` excludes the field), otherwise it is the
+	// number of the field starting from 1. Tagged encoding is enabled
+	// automatically if any field of the struct has the "sdf" tag.
+	Tagged bool
+
+	// Name overrides the name the type is sent over the network with. It
+	// must be the same on every node. Use it for the generic types
+	// instantiated with the types declared inside a function (their names
+	// are not stable) or to resolve a name collision.
+	Name string
+}
+
+// RegisterTypeOf registers the type of the given value. Registered types are
+// sent over the network with their name, so the remote node must register
+// the same type to decode them.
 func RegisterTypeOf(v any) error {
+	return RegisterTypeOfWithOptions(v, RegisterOptions{})
+}
+
+// RegisterTypeOfWithOptions registers the type of the given value with the given options
+func RegisterTypeOfWithOptions(v any, options RegisterOptions) error {
 	vov := reflect.ValueOf(v)
 	tov := vov.Type()
 
 	if tov.Kind() == reflect.Pointer {
-		return fmt.Errorf("pointer type is not supported")
+		// register the type it points to
+		return RegisterTypeOfWithOptions(reflect.Zero(tov.Elem()).Interface(), options)
 	}
 
 	switch v.(type) {
@@ -47,6 +81,13 @@ func RegisterTypeOf(v any) error {
 	case gen.Atom, gen.PID, gen.ProcessID, gen.Event, gen.Ref, gen.Alias, time.Time:
 		return fmt.Errorf("unable to register a type of Sparrow Framework")
 
+	case GeneratedEncoder:
+		// the generated code keeps the wire format of the reflection-based
+		// encoding, so its MarshalSDF/UnmarshalSDF methods are not used here
+		if reflect.PointerTo(tov).Implements(typeGeneratedDecoder) == false {
+			return fmt.Errorf("DecodeSDF method of %v must be a method of *%v", tov, tov)
+		}
+
 	case Unmarshaler:
 		return fmt.Errorf("UnmarshalSDF method of %v must be a method of *%v", tov, tov)
 
@@ -55,7 +96,10 @@ func RegisterTypeOf(v any) error {
 		if reflect.PointerTo(tov).Implements(reflect.TypeOf((*Unmarshaler)(nil)).Elem()) == false {
 			return fmt.Errorf("UnmarshalSDF method of %v must be a method of *%v", tov, tov)
 		}
-		name := regTypeName(tov)
+		name, err := nameType(tov, options)
+		if err != nil {
+			return err
+		}
 
 		fenc := func(value reflect.Value, b *lib.Buffer, _ *stateEncode) error {
 			v := value.Interface().(Marshaler)
@@ -101,13 +145,60 @@ func RegisterTypeOf(v any) error {
 		decoders.Store(name, dec)
 		decoders.Store(tov, dec)
 		addRegCache(tov)
+		// encoding is up to the type itself
+		fingerprints.Store(name, fingerprintCustom)
 		return nil
 	}
 
-	return registerType(tov)
+	if _, err := nameType(tov, options); err != nil {
+		return err
+	}
+	if err := registerType(tov, options); err != nil {
+		typeNames.Delete(tov)
+		return err
+	}
+	// tagged struct has stored its fingerprint already
+	fingerprints.LoadOrStore(regTypeName(tov), fingerprintOf(tov))
+	return nil
+}
+
+// nameType checks the name of the type being registered and keeps it
+// for regTypeName
+func nameType(tov reflect.Type, options RegisterOptions) (string, error) {
+	if tov.Name() == "" {
+		return "", fmt.Errorf("unable to register unnamed type %v", tov)
+	}
+
+	name := regTypeName(tov)
+	if options.Name != "" {
+		name = "#" + options.Name
+	} else if strings.Contains(tov.Name(), "·") {
+		return "", fmt.Errorf("unable to register type %v: the name of the type "+
+			"declared inside a function is not stable (use RegisterOptions.Name)", tov)
+	}
+	if len(name) > 4095 {
+		return "", fmt.Errorf("unable to register type %v: too long name (use RegisterOptions.Name)", tov)
+	}
+
+	if v, found := decoders.Load(name); found {
+		if t := v.(*decoder).Type; t != tov {
+			return "", fmt.Errorf("unable to register type %v: name %q is taken by %v "+
+				"(use RegisterOptions.Name)", tov, name, t)
+		}
+		return "", gen.ErrTaken
+	}
+
+	v, found := typeNames.LoadOrStore(tov, name)
+	if found == false {
+		return name, nil
+	}
+	if v.(string) != name {
+		return "", fmt.Errorf("type %v is registered with name %q already", tov, v)
+	}
+	return "", gen.ErrTaken
 }
 
-func registerType(tov reflect.Type) error {
+func registerType(tov reflect.Type, options RegisterOptions) error {
 
 	name := regTypeName(tov)
 
@@ -236,19 +327,31 @@ func registerType(tov reflect.Type) error {
 		var encs []*encoder
 		var decs []*decoder
 
+		// allow the fields to refer to this type by pointer
+		registering.Store(name, tov)
+		defer registering.Delete(name)
+
+		if options.Tagged || hasTags(tov) {
+			return registerTaggedStruct(tov, name)
+		}
+
 		nf := tov.NumField()
+		names := make([]string, nf)
 		for i := 0; i < nf; i++ {
-			ft := tov.Field(i).Type
+			sf := tov.Field(i)
+			names[i] = "." + sf.Name
 
-			enc, err := getEncoder(ft, &stateEncode{})
+			enc, err := getEncoder(sf.Type, &stateEncode{})
 			if err != nil {
-				return fmt.Errorf("(struct field encode) type %v must be registered first: %s", ft, err)
+				return fmt.Errorf("(struct field encode) %v%s: type %v must be registered first: %s",
+					tov, names[i], sf.Type, err)
 			}
 			encs = append(encs, enc)
 
 			dec, _, err := decodeType(enc.Prefix, &stateDecode{})
 			if err != nil {
-				return fmt.Errorf("(struct field decode) type %v must be registered first: %s", ft, err)
+				return fmt.Errorf("(struct field decode) %v%s: type %v must be registered first: %s",
+					tov, names[i], sf.Type, err)
 			}
 			decs = append(decs, dec)
 		}
@@ -262,11 +365,14 @@ func registerType(tov reflect.Type) error {
 			for i := 0; i < nf; i++ {
 				state.encodeType = false
 				if err := encs[i].Encode(value.Field(i), b, state); err != nil {
-					return err
+					return withPath(err, names[i])
 				}
 			}
 			return nil
 		}
+		if isGenerated(tov) {
+			fenc = generatedEncoder(fenc)
+		}
 		encoders.Store(tov, regEncoder(name, fenc))
 
 		// decoder closure
@@ -286,11 +392,14 @@ func registerType(tov reflect.Type) error {
 				field := value.Field(i)
 				_, packet, err = decs[i].Decode(&field, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, names[i])
 				}
 			}
 			return value, packet, nil
 		}
+		if isGenerated(tov) {
+			fdec = generatedDecoder(tov, fdec)
+		}
 		decoders.Store(name, &decoder{tov, fdec})
 		addRegCache(tov)
 
@@ -312,6 +421,7 @@ func registerType(tov reflect.Type) error {
 		}
 
 		// encode closure
+		loop := mayLoop(tov)
 		fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
 			if value.IsNil() {
 				b.AppendByte(sdtNil)
@@ -322,6 +432,12 @@ func registerType(tov reflect.Type) error {
 			n := value.Len()
 			buf := b.Extend(4)
 			binary.BigEndian.PutUint32(buf, uint32(n))
+			if loop && n > 0 {
+				if err := state.enterLoop(value); err != nil {
+					return err
+				}
+				defer state.leaveLoop(value)
+			}
 			if state.child == nil {
 				state.child = &stateEncode{options: state.options}
 			}
@@ -329,7 +445,7 @@ func registerType(tov reflect.Type) error {
 			for i := 0; i < n; i++ {
 				state.encodeType = false
 				if err := enc.Encode(value.Index(i), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%d]", i))
 				}
 			}
 			return nil
@@ -385,7 +501,7 @@ func registerType(tov reflect.Type) error {
 				item := value.Index(i)
 				_, p, err := dec.Decode(&item, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%d]", i))
 				}
 				packet = p
 			}
@@ -418,7 +534,7 @@ func registerType(tov reflect.Type) error {
 			for i := 0; i < value.Len(); i++ {
 				state.encodeType = false
 				if err := enc.Encode(value.Index(i), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%d]", i))
 				}
 			}
 			return nil
@@ -449,7 +565,7 @@ func registerType(tov reflect.Type) error {
 				item := value.Index(i)
 				_, p, err := dec.Decode(&item, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%d]", i))
 				}
 				packet = p
 			}
@@ -483,6 +599,7 @@ func registerType(tov reflect.Type) error {
 			return fmt.Errorf("(map value decoder) type %v must be registered first: %s", typeValue, err)
 		}
 
+		loop := mayLoop(tov)
 		fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
 			if value.IsNil() {
 				b.AppendByte(sdtNil)
@@ -491,6 +608,14 @@ func registerType(tov reflect.Type) error {
 				b.AppendByte(sdtReg)
 			}
 
+			n := value.Len()
+			if loop && n > 0 {
+				if err := state.enterLoop(value); err != nil {
+					return err
+				}
+				defer state.leaveLoop(value)
+			}
+
 			if state.child == nil {
 				state.child = &stateEncode{
 					options: state.options,
@@ -498,7 +623,6 @@ func registerType(tov reflect.Type) error {
 			}
 			state = state.child
 
-			n := value.Len()
 			buf := b.Extend(4)
 			binary.BigEndian.PutUint32(buf, uint32(n))
 
@@ -510,7 +634,7 @@ func registerType(tov reflect.Type) error {
 				}
 				state.encodeType = false
 				if err := encValue.Encode(iter.Value(), b, state); err != nil {
-					return err
+					return withPath(err, fmt.Sprintf("[%v]", iter.Key()))
 				}
 			}
 			return nil
@@ -574,7 +698,7 @@ func registerType(tov reflect.Type) error {
 				state.decoder = decValue
 				_, p, err = decValue.Decode(&v, packet, state)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withPath(err, fmt.Sprintf("[%v]", k))
 				}
 				packet = p
 
@@ -604,16 +728,26 @@ func RegisterAtom(a gen.Atom) error {
 var (
 	encoders sync.Map
 	decoders sync.Map
+
+	// struct types being registered (name => reflect.Type)
+	registering sync.Map
+
+	// names of the registered types (reflect.Type => name)
+	typeNames sync.Map
 )
 
-func regEncoder(name string, enc encodeFunc) *encoder {
+func regPrefix(name string) []byte {
 	l := uint16(len(name))
 	if l > 4095 {
 		panic(fmt.Sprintf("unable to register type. too long name: %s", name))
 	}
 	prefix := []byte{sdtReg, 0, 0}
 	binary.BigEndian.PutUint16(prefix[1:3], l)
-	prefix = append(prefix, name...)
+	return append(prefix, name...)
+}
+
+func regEncoder(name string, enc encodeFunc) *encoder {
+	prefix := regPrefix(name)
 
 	return &encoder{
 		Prefix: prefix,
@@ -680,6 +814,37 @@ func GetRegCache() map[uint16]string {
 	return cache
 }
 
+// RegisteredTypeName returns the name of the registered type. The names of
+// the registered types are used by the other codecs as well.
+func RegisteredTypeName(t reflect.Type) (string, bool) {
+	if _, found := regCache.Load(t); found == false {
+		return "", false
+	}
+	return regTypeName(t), true
+}
+
+// RegisteredType returns the registered type by its name
+func RegisteredType(name string) (reflect.Type, bool) {
+	v, found := decoders.Load(name)
+	if found == false {
+		return nil, false
+	}
+	return v.(*decoder).Type, true
+}
+
+// RegisteredError returns the registered error with the given text
+func RegisteredError(text string) (error, bool) {
+	var e error
+	errCache.Range(func(k, _ any) bool {
+		if err, ok := k.(error); ok && err.Error() == text {
+			e = err
+			return false
+		}
+		return true
+	})
+	return e, e != nil
+}
+
 func MakeEncodeRegTypeCache(names []string) *sync.Map {
 	mapnames := make(map[string]bool)
 	for _, name := range names {
@@ -2,8 +2,10 @@ package sdf
 
 import (
 	//  "encoding/binary"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -137,3 +139,249 @@ func TestRegCacheTypes(t *testing.T) {
 		})
 	}
 }
+
+type testRegTaggedV1 struct {
+	A int    `sdf:"1"`
+	B string `sdf:"2"`
+}
+
+// the newer version of testRegTaggedV1
+type testRegTaggedV2 struct {
+	A int     `sdf:"1"`
+	C float64 `sdf:"3"`
+	B string  `sdf:"2"`
+	D string  `sdf:"-"`
+}
+
+// incompatible version of testRegTaggedV1
+type testRegTaggedV3 struct {
+	A string `sdf:"1"`
+}
+
+func TestRegTagged(t *testing.T) {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+
+	for _, v := range []any{testRegTaggedV1{}, testRegTaggedV2{}, testRegTaggedV3{}, testRegStruct{}} {
+		if err := RegisterTypeOf(v); err != nil && err != gen.ErrTaken {
+			t.Fatal(err)
+		}
+	}
+	nameV1 := regTypeName(reflect.TypeOf(testRegTaggedV1{}))
+	nameV2 := regTypeName(reflect.TypeOf(testRegTaggedV2{}))
+
+	// emulate the remote node with the other version of the type
+	// using the same cache id
+	regCache := func(t any) *sync.Map {
+		c := new(sync.Map)
+		c.Store(reflect.TypeOf(t), []byte{sdtReg, 0x13, 0x88})
+		return c
+	}
+	decCache := func(name string) *sync.Map {
+		c := new(sync.Map)
+		c.Store(uint16(5000), name)
+		return c
+	}
+
+	// newer => older. unknown field must be skipped
+	v2 := testRegTaggedV2{A: 1, B: "b", C: 3.14, D: "d"}
+	if err := Encode(v2, b, Options{RegCache: regCache(v2)}); err != nil {
+		t.Fatal(err)
+	}
+	value, _, err := Decode(b.B, Options{RegCache: decCache(nameV1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value, testRegTaggedV1{A: 1, B: "b"}) {
+		t.Fatalf("incorrect value %#v", value)
+	}
+
+	// older => newer. missing field must be zero
+	b.Reset()
+	v1 := testRegTaggedV1{A: 2, B: "bb"}
+	if err := Encode([]any{v1}, b, Options{RegCache: regCache(v1)}); err != nil {
+		t.Fatal(err)
+	}
+	value, _, err = Decode(b.B, Options{RegCache: decCache(nameV2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value, []any{testRegTaggedV2{A: 2, B: "bb"}}) {
+		t.Fatalf("incorrect value %#v", value)
+	}
+
+	// fingerprints
+	fps := make(map[string]string)
+	for _, v := range []any{testRegTaggedV1{}, testRegTaggedV2{}, testRegTaggedV3{}, testRegStruct{}} {
+		name := regTypeName(reflect.TypeOf(v))
+		fp, _ := fingerprints.Load(name)
+		fps[name] = fp.(string)
+	}
+	remote := map[uint16]string{5000: nameV1}
+	if err := CheckTypeFingerprints(remote, map[uint16]string{5000: fps[nameV2]}); err != nil {
+		t.Fatal(err)
+	}
+	nameV3 := regTypeName(reflect.TypeOf(testRegTaggedV3{}))
+	if err := CheckTypeFingerprints(remote, map[uint16]string{5000: fps[nameV3]}); errors.Is(err, ErrIncompatibleTypes) == false {
+		t.Fatal("must be incompatible")
+	}
+	nameS := regTypeName(reflect.TypeOf(testRegStruct{}))
+	if err := CheckTypeFingerprints(remote, map[uint16]string{5000: fps[nameS]}); errors.Is(err, ErrIncompatibleTypes) == false {
+		t.Fatal("must be incompatible")
+	}
+	if fp := GetTypeFingerprints(); len(fp) == 0 {
+		t.Fatal("no fingerprints")
+	}
+}
+
+type testRegPair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+type testRegShape interface{ Area() float64 }
+type testRegSquare struct{ Side float64 }
+
+func (s testRegSquare) Area() float64 { return s.Side * s.Side }
+
+type testRegFigure struct {
+	Name  string
+	Shape testRegShape
+}
+
+type testRegCircle struct{ Radius float64 }
+type testRegHolder struct{ Values []any }
+type testRegUnregistered struct{}
+
+func TestRegGeneric(t *testing.T) {
+	p1 := testRegPair[int, string]{Key: 1, Value: "a"}
+	p2 := testRegPair[int, testRegStruct]{Key: 2, Value: testRegStruct{A: true}}
+	for _, v := range []any{p1, p2} {
+		if err := RegisterTypeOf(v); err != nil && err != gen.ErrTaken {
+			t.Fatal(err)
+		}
+	}
+
+	name := regTypeName(reflect.TypeOf(p2))
+	exp := "#github.com/sllt/sparrow/net/sdf/testRegPair[int,github.com/sllt/sparrow/net/sdf.testRegStruct]"
+	if name != exp {
+		t.Fatalf("incorrect name %q (exp: %q)", name, exp)
+	}
+
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+	for _, v := range []any{p1, p2, []any{p1, p2}} {
+		b.Reset()
+		if err := Encode(v, b, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		value, _, err := Decode(b.B, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(value, v) {
+			t.Fatalf("incorrect value %#v (exp: %#v)", value, v)
+		}
+	}
+
+	// type argument declared inside a function
+	type local struct{ A int }
+	err := RegisterTypeOfWithOptions(local{}, RegisterOptions{Name: "test/local"})
+	if err != nil && err != gen.ErrTaken {
+		t.Fatal(err)
+	}
+	err = RegisterTypeOf(testRegPair[int, local]{})
+	if err == nil {
+		t.Fatal("must be an error")
+	}
+	err = RegisterTypeOfWithOptions(testRegPair[int, local]{}, RegisterOptions{Name: "test/pair.local"})
+	if err != nil && err != gen.ErrTaken {
+		t.Fatal(err)
+	}
+	if name := regTypeName(reflect.TypeOf(testRegPair[int, local]{})); name != "#test/pair.local" {
+		t.Fatalf("incorrect name %q", name)
+	}
+
+	// name collision
+	err = RegisterTypeOfWithOptions(testRegPair[string, string]{}, RegisterOptions{Name: "test/pair.local"})
+	if err == nil || err == gen.ErrTaken {
+		t.Fatalf("must be a name collision error: %v", err)
+	}
+	// already registered with another name
+	err = RegisterTypeOfWithOptions(p1, RegisterOptions{Name: "test/pair.other"})
+	if err == nil || err == gen.ErrTaken {
+		t.Fatalf("must be an error: %v", err)
+	}
+	// failed registration keeps the type unnamed
+	if _, found := typeNames.Load(reflect.TypeOf(testRegPair[string, string]{})); found {
+		t.Fatal("type must not be named")
+	}
+
+	if err := RegisterTypeOf(struct{ A int }{}); err == nil {
+		t.Fatal("unnamed type must not be registered")
+	}
+}
+
+func TestRegInterfaceField(t *testing.T) {
+	for _, v := range []any{testRegSquare{}, testRegCircle{}, testRegFigure{}, testRegHolder{}} {
+		if err := RegisterTypeOf(v); err != nil && err != gen.ErrTaken {
+			t.Fatal(err)
+		}
+	}
+
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+	for _, v := range []testRegFigure{
+		{Name: "square", Shape: testRegSquare{Side: 2}},
+		{Name: "none"},
+	} {
+		b.Reset()
+		if err := Encode(v, b, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		value, _, err := Decode(b.B, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(value, v) {
+			t.Fatalf("incorrect value %#v (exp: %#v)", value, v)
+		}
+	}
+
+	// the registered type that doesn't implement the interface:
+	// replace the name of testRegSquare with the name of testRegCircle
+	// (both names have the same length)
+	b.Reset()
+	if err := Encode(testRegFigure{Shape: testRegSquare{Side: 2}}, b, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	packet := []byte(b.String())
+	square := regTypeName(reflect.TypeOf(testRegSquare{}))
+	circle := regTypeName(reflect.TypeOf(testRegCircle{}))
+	i := len(packet) - len(square) - 8 // the name is followed by the field Side
+	if string(packet[i:i+len(square)]) != square {
+		t.Fatal("incorrect packet")
+	}
+	copy(packet[i:], circle)
+	_, _, err := Decode(packet, Options{})
+	if err == nil || strings.Contains(err.Error(), "does not implement") == false {
+		t.Fatalf("must be an error: %v", err)
+	}
+}
+
+func TestRegFieldPath(t *testing.T) {
+	if err := RegisterTypeOf(testRegHolder{}); err != nil && err != gen.ErrTaken {
+		t.Fatal(err)
+	}
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+	v := testRegHolder{Values: []any{1, map[string]any{"key": testRegUnregistered{}}}}
+	err := Encode(v, b, Options{})
+	if err == nil {
+		t.Fatal("must be an error")
+	}
+	exp := "sdf.testRegHolder.Values[1][key]: "
+	if err.Error()[:len(exp)] != exp {
+		t.Fatalf("incorrect error %q", err)
+	}
+}
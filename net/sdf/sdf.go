@@ -1,7 +1,9 @@
 package sdf
 
 import (
+	"fmt"
 	"io"
+	"reflect"
 	"sync"
 )
 
@@ -12,6 +14,65 @@ type Options struct {
 	RegCache    *sync.Map // type/name => id (encoding), id => type (for decoding)
 	ErrCache    *sync.Map // error => id (for encoder), id => error (for decoder)
 	Cache       *sync.Map // common cache (caching reflect.Type => encoder, string([]byte) => decoder)
+
+	refs *refs // pointers of the message being encoded/decoded
+}
+
+// refs keeps the pointers seen during encoding/decoding a single message,
+// so the shared and cyclic pointers are encoded once and decoded into
+// the same pointer graph. Pointers are referenced by the offset of their
+// value in the message, so the decoder may skip values (unknown fields).
+type refs struct {
+	base    int                 // start of the message in the buffer (encoding)
+	encoded map[refKey]uint32   // pointer => offset (encoding)
+	loop    map[refKey]struct{} // slices/maps being encoded (loop detection)
+
+	message []byte                   // the message being decoded
+	decoded map[uint32]reflect.Value // offset => pointer (decoding)
+}
+
+// offset returns the offset of the given part of the message being decoded.
+// It is taken from the position of the part in the message, so it doesn't
+// depend on how the part was sliced.
+func (r *refs) offset(packet []byte) (uint32, error) {
+	if len(packet) == 0 || len(r.message) == 0 {
+		return 0, errDecodeEOD
+	}
+	start := reflect.ValueOf(r.message).Pointer()
+	p := reflect.ValueOf(packet).Pointer()
+	if p < start || p >= start+uintptr(len(r.message)) {
+		return 0, fmt.Errorf("value is out of the message being decoded")
+	}
+	return uint32(p - start), nil
+}
+
+type refKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+// fieldError is the error of encoding/decoding the value within the message.
+// It keeps the path to this value (like .Field[1].Field)
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.path, e.err)
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// withPath adds the element to the path of the error
+func withPath(err error, elem string) error {
+	if fe, ok := err.(*fieldError); ok {
+		fe.path = elem + fe.path
+		return fe
+	}
+	return &fieldError{path: elem, err: err}
 }
 
 const (
@@ -40,6 +101,9 @@ const (
 	sdtArray   = byte(158) // 0x9e
 	sdtMap     = byte(159) // 0x9f
 
+	sdtPointer    = byte(160) // 0xa0
+	sdtPointerRef = byte(161) // 0xa1 (reference to the pointer encoded earlier)
+
 	sdtPID       = byte(170) // 0xaa
 	sdtProcessID = byte(171) // 0xab
 	sdtAlias     = byte(172) // 0xac
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	directive = "//sdf:generate"
+	header    = "// Code generated by sdfgen. DO NOT EDIT."
+)
+
+// basic types encoded by the generated code directly (Encoder/Decoder method names)
+var basic = map[string]string{
+	"bool":    "Bool",
+	"string":  "String",
+	"int":     "Int",
+	"int8":    "Int8",
+	"int16":   "Int16",
+	"int32":   "Int32",
+	"rune":    "Int32",
+	"int64":   "Int64",
+	"uint":    "Uint",
+	"uint8":   "Uint8",
+	"byte":    "Uint8",
+	"uint16":  "Uint16",
+	"uint32":  "Uint32",
+	"uint64":  "Uint64",
+	"float32": "Float32",
+	"float64": "Float64",
+}
+
+type field struct {
+	name   string
+	method string // Encoder/Decoder method, empty - reflection
+}
+
+type structType struct {
+	name   string
+	fields []field
+	deps   []string // types of this package the fields refer to
+}
+
+// generate returns the package name and the generated source code for the
+// struct types found in the given directory.
+func generate(dir string, pkgName string, names []string) (string, []byte, error) {
+	fset := token.NewFileSet()
+	filter := func(fi os.FileInfo) bool {
+		return strings.HasSuffix(fi.Name(), "_test.go") == false
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var pkg *ast.Package
+	for name, p := range pkgs {
+		if pkgName != "" && name != pkgName {
+			continue
+		}
+		if pkg != nil {
+			return "", nil, fmt.Errorf("multiple packages found in %s", dir)
+		}
+		pkg = p
+	}
+	if pkg == nil {
+		return "", nil, fmt.Errorf("no package found in %s", dir)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		selected[strings.TrimSpace(name)] = true
+	}
+
+	// keep the order of declarations
+	files := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	var types []*structType
+	for _, name := range files {
+		file := pkg.Files[name]
+		if isGenerated(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if ok == false || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+
+				if len(names) > 0 {
+					if selected[ts.Name.Name] == false {
+						continue
+					}
+					delete(selected, ts.Name.Name)
+				} else if hasDirective(gd.Doc) == false && hasDirective(ts.Doc) == false {
+					continue
+				}
+
+				st, err := parseStruct(ts)
+				if err != nil {
+					return "", nil, err
+				}
+				types = append(types, st)
+			}
+		}
+	}
+	for name := range selected {
+		return "", nil, fmt.Errorf("type %s is not found", name)
+	}
+	if len(types) == 0 {
+		return "", nil, fmt.Errorf("no types found (use -type or %q comment)", directive)
+	}
+
+	types, err = sortTypes(types)
+	if err != nil {
+		return "", nil, err
+	}
+
+	src, err := render(pkg.Name, types)
+	if err != nil {
+		return "", nil, err
+	}
+	return pkg.Name, src, nil
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, "// Code generated by sdfgen") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStruct(ts *ast.TypeSpec) (*structType, error) {
+	name := ts.Name.Name
+	if ts.TypeParams != nil {
+		return nil, fmt.Errorf("type %s: generic types are not supported", name)
+	}
+	s, ok := ts.Type.(*ast.StructType)
+	if ok == false {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+
+	st := &structType{name: name}
+	for _, f := range s.Fields.List {
+		if f.Tag != nil {
+			tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+			if _, found := tag.Lookup("sdf"); found {
+				return nil, fmt.Errorf("type %s: tagged structs are not supported", name)
+			}
+		}
+
+		method := fieldMethod(f.Type)
+		st.deps = append(st.deps, idents(f.Type)...)
+
+		if len(f.Names) == 0 {
+			// embedded field
+			st.fields = append(st.fields, field{name: embeddedName(f.Type), method: method})
+			continue
+		}
+		for _, n := range f.Names {
+			st.fields = append(st.fields, field{name: n.Name, method: method})
+		}
+	}
+	return st, nil
+}
+
+func fieldMethod(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return basic[t.Name]
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		if elem, ok := t.Elt.(*ast.Ident); ok && (elem.Name == "byte" || elem.Name == "uint8") {
+			return "Binary"
+		}
+	}
+	return ""
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// idents returns the identifiers of the types the expression refers to
+// within the same package
+func idents(expr ast.Expr) []string {
+	var names []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.SelectorExpr:
+			// type of another package
+			return false
+		case *ast.Ident:
+			names = append(names, t.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// sortTypes orders the types so the field types are registered first
+func sortTypes(types []*structType) ([]*structType, error) {
+	byName := make(map[string]*structType)
+	for _, t := range types {
+		byName[t.name] = t
+	}
+
+	var sorted []*structType
+	state := make(map[string]int) // 1 - visiting, 2 - done
+	var visit func(t *structType) error
+	visit = func(t *structType) error {
+		switch state[t.name] {
+		case 1:
+			return fmt.Errorf("type %s has a cyclic reference", t.name)
+		case 2:
+			return nil
+		}
+		state[t.name] = 1
+		for _, dep := range t.deps {
+			if dep == t.name {
+				// refers to itself by pointer (or slice, map)
+				continue
+			}
+			if d, found := byName[dep]; found {
+				if err := visit(d); err != nil {
+					return err
+				}
+			}
+		}
+		state[t.name] = 2
+		sorted = append(sorted, t)
+		return nil
+	}
+
+	for _, t := range types {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+func render(pkg string, types []*structType) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString(header + "\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\"io\"\n\n")
+	b.WriteString("\"github.com/sllt/sparrow/gen\"\n")
+	b.WriteString("\"github.com/sllt/sparrow/net/sdf\"\n)\n\n")
+
+	b.WriteString("func init() {\ntypes := []any{\n")
+	for _, t := range types {
+		fmt.Fprintf(&b, "%s{},\n", t.name)
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("for _, t := range types {\n")
+	b.WriteString("err := sdf.RegisterTypeOf(t)\n")
+	b.WriteString("if err == nil || err == gen.ErrTaken {\ncontinue\n}\n")
+	b.WriteString("panic(err)\n}\n}\n")
+
+	for _, t := range types {
+		fmt.Fprintf(&b, "\n// EncodeSDF implements sdf.GeneratedEncoder\n")
+		fmt.Fprintf(&b, "func (x %s) EncodeSDF(e *sdf.Encoder) error {\n", t.name)
+		for _, f := range t.fields {
+			switch {
+			case f.method == "":
+				fmt.Fprintf(&b, "e.Field(&x.%s)\n", f.name)
+			default:
+				fmt.Fprintf(&b, "e.%s(x.%s)\n", f.method, f.name)
+			}
+		}
+		b.WriteString("return e.Err()\n}\n")
+
+		fmt.Fprintf(&b, "\n// DecodeSDF implements sdf.GeneratedDecoder\n")
+		fmt.Fprintf(&b, "func (x *%s) DecodeSDF(d *sdf.Decoder) error {\n", t.name)
+		for _, f := range t.fields {
+			switch {
+			case f.method == "":
+				fmt.Fprintf(&b, "d.Field(&x.%s)\n", f.name)
+			default:
+				fmt.Fprintf(&b, "x.%s = d.%s()\n", f.name, f.method)
+			}
+		}
+		b.WriteString("return d.Err()\n}\n")
+
+		fmt.Fprintf(&b, "\n// MarshalSDF implements sdf.Marshaler\n")
+		fmt.Fprintf(&b, "func (x %s) MarshalSDF(w io.Writer) error {\n", t.name)
+		b.WriteString("return sdf.MarshalGenerated(x, w)\n}\n")
+
+		fmt.Fprintf(&b, "\n// UnmarshalSDF implements sdf.Unmarshaler\n")
+		fmt.Fprintf(&b, "func (x *%s) UnmarshalSDF(data []byte) error {\n", t.name)
+		b.WriteString("return sdf.UnmarshalGenerated(x, data)\n}\n")
+	}
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "..", "tests", "003_codegen")
+	pkg, src, err := generate(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(filepath.Join(dir, pkg+"_sdf.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(src, expected) == false {
+		t.Fatalf("generated code of %s is outdated (run go generate)", dir)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"//sdf:generate\ntype A[T any] struct{ V T }", "generic types"},
+		{"//sdf:generate\ntype A struct{ V int `sdf:\"1\"` }", "tagged structs"},
+		{"//sdf:generate\ntype A int", "is not a struct"},
+		{"//sdf:generate\ntype A struct{ B B }\n//sdf:generate\ntype B struct{ A A }", "cyclic reference"},
+		{"type A struct{}", "no types found"},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		src := "package test\n\n" + c.src + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "test.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := generate(dir, "", nil)
+		if err == nil || strings.Contains(err.Error(), c.err) == false {
+			t.Fatalf("%q: unexpected error %v (exp: %q)", c.src, err, c.err)
+		}
+	}
+
+	dir := t.TempDir()
+	src := "package test\n\ntype A struct{ V int }\n"
+	if err := os.WriteFile(filepath.Join(dir, "test.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := generate(dir, "", []string{"B"}); err == nil {
+		t.Fatal("must be an error for the unknown type")
+	}
+	if _, _, err := generate(dir, "", []string{"A"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	dir := filepath.Join("some", "dir")
+	if p := outputPath(dir, "", "Pkg"); p != filepath.Join(dir, "pkg_sdf.go") {
+		t.Fatalf("incorrect default output path %q", p)
+	}
+	if p := outputPath(dir, "out.go", "pkg"); p != filepath.Join(dir, "out.go") {
+		t.Fatalf("incorrect relative output path %q", p)
+	}
+
+	// the absolute output is used as is
+	abs := filepath.Join(t.TempDir(), "out.go")
+	if p := outputPath(dir, abs, "pkg"); p != abs {
+		t.Fatalf("incorrect absolute output path %q", p)
+	}
+}
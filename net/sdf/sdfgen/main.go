@@ -0,0 +1,71 @@
+// Sdfgen generates the SDF encoding code for the struct types, so they are
+// encoded without reflection. The generated code produces the same data as
+// the reflection-based encoding, so the nodes with and without generated
+// code are able to exchange these types.
+//
+// The types are selected with the -type flag or by the "//sdf:generate"
+// comment in their doc. Usage with go generate:
+//
+//	//go:generate go run github.com/sllt/sparrow/net/sdf/sdfgen
+//
+//	//sdf:generate
+//	type MyMessage struct { ... }
+//
+// The generated file contains EncodeSDF/DecodeSDF and MarshalSDF/UnmarshalSDF
+// methods of every selected type and the init function registering them
+// with sdf.RegisterTypeOf. The types of the fields must be registered before.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	flagType   = flag.String("type", "", "comma-separated list of the type names (default: types with the //sdf:generate comment)")
+	flagOutput = flag.String("output", "", "output file name (default: <package>_sdf.go)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: sdfgen [flags] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var types []string
+	if *flagType != "" {
+		types = strings.Split(*flagType, ",")
+	}
+
+	pkg, src, err := generate(dir, os.Getenv("GOPACKAGE"), types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sdfgen: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(outputPath(dir, *flagOutput, pkg), src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "sdfgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// outputPath returns the path of the generated file. The relative output is
+// placed into the package directory.
+func outputPath(dir string, output string, pkg string) string {
+	if output == "" {
+		output = strings.ToLower(pkg) + "_sdf.go"
+	}
+	if filepath.IsAbs(output) {
+		return output
+	}
+	return filepath.Join(dir, output)
+}
@@ -0,0 +1,283 @@
+package sdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sllt/sparrow/lib"
+)
+
+var (
+	ErrIncompatibleTypes = errors.New("incompatible types")
+)
+
+const (
+	fingerprintCustom = "m" // type implements Marshaler/Unmarshaler
+)
+
+// type name => fingerprint
+var fingerprints sync.Map
+
+type taggedField struct {
+	tag   uint16
+	index int
+	enc   *encoder
+	dec   *decoder
+}
+
+func hasTags(tov reflect.Type) bool {
+	for i := 0; i < tov.NumField(); i++ {
+		if _, found := tov.Field(i).Tag.Lookup("sdf"); found {
+			return true
+		}
+	}
+	return false
+}
+
+// registerTaggedStruct registers the struct encoded as a list of the tagged fields:
+// number of fields (uint16), then tag (uint16), length (uint32) and value of every field.
+func registerTaggedStruct(tov reflect.Type, name string) error {
+	var fields []taggedField
+
+	indexes := make(map[uint16]int)
+	for i := 0; i < tov.NumField(); i++ {
+		sf := tov.Field(i)
+
+		tag := uint16(i + 1)
+		if v, found := sf.Tag.Lookup("sdf"); found {
+			if v == "-" {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 16)
+			if err != nil || n == 0 {
+				return fmt.Errorf("incorrect sdf tag %q of the field %s (must be a number 1..%d)",
+					v, sf.Name, math.MaxUint16)
+			}
+			tag = uint16(n)
+		}
+		if index, exist := indexes[tag]; exist {
+			return fmt.Errorf("duplicate sdf tag %d of the fields %s and %s",
+				tag, tov.Field(index).Name, sf.Name)
+		}
+		indexes[tag] = i
+
+		enc, err := getEncoder(sf.Type, &stateEncode{})
+		if err != nil {
+			return fmt.Errorf("(struct field encode) %v.%s: type %v must be registered first: %s",
+				tov, sf.Name, sf.Type, err)
+		}
+		dec, _, err := decodeType(enc.Prefix, &stateDecode{})
+		if err != nil {
+			return fmt.Errorf("(struct field decode) %v.%s: type %v must be registered first: %s",
+				tov, sf.Name, sf.Type, err)
+		}
+
+		fields = append(fields, taggedField{tag: tag, index: i, enc: enc, dec: dec})
+	}
+	tags := make(map[uint16]*taggedField, len(fields))
+	for i := range fields {
+		tags[fields[i].tag] = &fields[i]
+	}
+
+	fenc := func(value reflect.Value, b *lib.Buffer, state *stateEncode) error {
+		if state.child == nil {
+			state.child = &stateEncode{options: state.options}
+		}
+		state = state.child
+
+		buf := b.Extend(2)
+		binary.BigEndian.PutUint16(buf, uint16(len(fields)))
+		for i := range fields {
+			pos := b.Len()
+			buf := b.Extend(6)
+			binary.BigEndian.PutUint16(buf[0:2], fields[i].tag)
+
+			state.encodeType = false
+			if err := fields[i].enc.Encode(value.Field(fields[i].index), b, state); err != nil {
+				return withPath(err, "."+tov.Field(fields[i].index).Name)
+			}
+
+			l := b.Len() - pos - 6
+			if int64(l) > math.MaxUint32 {
+				return ErrBinaryTooLong
+			}
+			binary.BigEndian.PutUint32(b.B[pos+2:pos+6], uint32(l))
+		}
+		return nil
+	}
+	encoders.Store(tov, regEncoder(name, fenc))
+
+	fdec := func(value *reflect.Value, packet []byte, state *stateDecode) (*reflect.Value, []byte, error) {
+		if len(packet) < 2 {
+			return nil, nil, errDecodeEOD
+		}
+		n := int(binary.BigEndian.Uint16(packet[:2]))
+		packet = packet[2:]
+
+		if value == nil {
+			v := reflect.Indirect(reflect.New(tov))
+			value = &v
+		} else {
+			// fields missing in the packet must be zero
+			value.Set(reflect.Zero(tov))
+		}
+
+		if state.child == nil {
+			state.child = &stateDecode{options: state.options}
+		}
+		state = state.child
+
+		for i := 0; i < n; i++ {
+			if len(packet) < 6 {
+				return nil, nil, errDecodeEOD
+			}
+			tag := binary.BigEndian.Uint16(packet[0:2])
+			l := int(binary.BigEndian.Uint32(packet[2:6]))
+			packet = packet[6:]
+			if len(packet) < l {
+				return nil, nil, errDecodeEOD
+			}
+
+			f, known := tags[tag]
+			if known == false {
+				// the field has been added in the newer version of this type
+				packet = packet[l:]
+				continue
+			}
+
+			field := value.Field(f.index)
+			if _, _, err := f.dec.Decode(&field, packet[:l], state); err != nil {
+				return nil, nil, withPath(err, "."+tov.Field(f.index).Name)
+			}
+			packet = packet[l:]
+		}
+		return value, packet, nil
+	}
+	decoders.Store(name, &decoder{tov, fdec})
+	addRegCache(tov)
+
+	// fingerprint is the list of tag:type pairs
+	entries := make([]string, len(fields))
+	for i := range fields {
+		entries[i] = fmt.Sprintf("%d:%08x", fields[i].tag, uint32(fingerprintHash(fields[i].enc.Prefix)))
+	}
+	sort.Strings(entries)
+	fingerprints.Store(name, "t"+strings.Join(entries, ","))
+	return nil
+}
+
+// fingerprintOf returns the fingerprint of the positionally encoded type.
+// It covers the types of the struct fields (or items), but not their names.
+func fingerprintOf(tov reflect.Type) string {
+	desc := []byte(tov.Kind().String())
+
+	prefix := func(t reflect.Type) []byte {
+		enc, err := getEncoder(t, &stateEncode{})
+		if err != nil {
+			return []byte(t.String())
+		}
+		return enc.Prefix
+	}
+
+	switch tov.Kind() {
+	case reflect.Struct:
+		for i := 0; i < tov.NumField(); i++ {
+			desc = append(desc, '|')
+			desc = append(desc, prefix(tov.Field(i).Type)...)
+		}
+	case reflect.Slice:
+		desc = append(desc, prefix(tov.Elem())...)
+	case reflect.Array:
+		desc = append(desc, strconv.Itoa(tov.Len())...)
+		desc = append(desc, prefix(tov.Elem())...)
+	case reflect.Map:
+		desc = append(desc, prefix(tov.Key())...)
+		desc = append(desc, '|')
+		desc = append(desc, prefix(tov.Elem())...)
+	}
+	return fmt.Sprintf("p%016x", fingerprintHash(desc))
+}
+
+func fingerprintHash(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// GetTypeFingerprints returns the fingerprints of the registered types
+// using the ids of GetRegCache.
+func GetTypeFingerprints() map[uint16]string {
+	fps := make(map[uint16]string)
+	for id, name := range GetRegCache() {
+		if fp, found := fingerprints.Load(name); found {
+			fps[id] = fp.(string)
+		}
+	}
+	if len(fps) == 0 {
+		return nil
+	}
+	return fps
+}
+
+// CheckTypeFingerprints compares the fingerprints of the types registered on
+// the remote node with the local ones. The remote types are identified by
+// the ids of the remote RegCache. Types unknown to this node are skipped.
+// Returns ErrIncompatibleTypes with the list of types that can not be
+// decoded by either side.
+func CheckTypeFingerprints(regCache map[uint16]string, remote map[uint16]string) error {
+	var names []string
+	for id, fp := range remote {
+		name, found := regCache[id]
+		if found == false {
+			continue
+		}
+		local, found := fingerprints.Load(name)
+		if found == false {
+			continue
+		}
+		if compatibleFingerprints(local.(string), fp) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w: %s", ErrIncompatibleTypes, strings.Join(names, ", "))
+}
+
+func compatibleFingerprints(local, remote string) bool {
+	if local == remote {
+		return true
+	}
+	if strings.HasPrefix(local, "t") == false || strings.HasPrefix(remote, "t") == false {
+		return false
+	}
+
+	// tagged structs are compatible if the common fields have the same types
+	fields := make(map[string]string)
+	for _, entry := range strings.Split(local[1:], ",") {
+		if tag, fp, found := strings.Cut(entry, ":"); found {
+			fields[tag] = fp
+		}
+	}
+	for _, entry := range strings.Split(remote[1:], ",") {
+		tag, fp, found := strings.Cut(entry, ":")
+		if found == false {
+			continue
+		}
+		if lfp, exist := fields[tag]; exist && lfp != fp {
+			return false
+		}
+	}
+	return true
+}
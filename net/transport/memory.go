@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+var (
+	// listeners of the memory transport are shared by all the nodes of the process
+	memoryListeners sync.Map // address => *memoryListener
+)
+
+// CreateMemory creates in-process transport. The nodes running within the same
+// OS process connect to each other with no network involved.
+func CreateMemory() gen.NetworkTransport {
+	return &memory{}
+}
+
+type memory struct{}
+
+func (m *memory) Name() string {
+	return Memory
+}
+
+func (m *memory) Address(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func (m *memory) Listen(address string) (net.Listener, error) {
+	l := &memoryListener{
+		addr:   memoryAddr(address),
+		accept: make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	if _, exist := memoryListeners.LoadOrStore(address, l); exist {
+		return nil, &net.OpError{Op: "listen", Net: Memory, Addr: l.addr, Err: gen.ErrTaken}
+	}
+	return l, nil
+}
+
+func (m *memory) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	addr := memoryAddr(address)
+	v, found := memoryListeners.Load(address)
+	if found == false {
+		return nil, &net.OpError{Op: "dial", Net: Memory, Addr: addr, Err: gen.ErrNoRoute}
+	}
+	l := v.(*memoryListener)
+
+	c1, c2 := net.Pipe()
+	server := &memoryConn{Conn: c1, local: addr, remote: memoryAddr("client:" + address)}
+	client := &memoryConn{Conn: c2, local: server.remote, remote: addr}
+
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case l.accept <- server:
+		return client, nil
+	case <-l.closed:
+	case <-timer.C:
+		c1.Close()
+		c2.Close()
+		return nil, &net.OpError{Op: "dial", Net: Memory, Addr: addr, Err: gen.ErrTimeout}
+	}
+	c1.Close()
+	c2.Close()
+	return nil, &net.OpError{Op: "dial", Net: Memory, Addr: addr, Err: net.ErrClosed}
+}
+
+type memoryListener struct {
+	addr   memoryAddr
+	accept chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memoryListener) Close() error {
+	l.once.Do(func() {
+		memoryListeners.Delete(string(l.addr))
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
+
+type memoryConn struct {
+	net.Conn
+	local  memoryAddr
+	remote memoryAddr
+}
+
+func (c *memoryConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *memoryConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type memoryAddr string
+
+func (a memoryAddr) Network() string {
+	return Memory
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
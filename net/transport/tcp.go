@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+type TCPOptions struct {
+	// Network "tcp4" (default), "tcp6" or "tcp" (any available address)
+	Network string
+	// KeepAlive default gen.DefaultKeepAlivePeriod
+	KeepAlive time.Duration
+}
+
+// CreateTCP creates TCP transport
+func CreateTCP(options TCPOptions) gen.NetworkTransport {
+	switch options.Network {
+	case "tcp", "tcp6":
+	default:
+		options.Network = "tcp4"
+	}
+	if options.KeepAlive == 0 {
+		options.KeepAlive = gen.DefaultKeepAlivePeriod
+	}
+	return &tcp{options: options}
+}
+
+type tcp struct {
+	options TCPOptions
+}
+
+func (t *tcp) Name() string {
+	return TCP
+}
+
+func (t *tcp) Address(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func (t *tcp) Listen(address string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		KeepAlive: t.options.KeepAlive,
+	}
+	return lc.Listen(context.Background(), t.options.Network, address)
+}
+
+func (t *tcp) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{
+		KeepAlive: t.options.KeepAlive,
+		Timeout:   timeout,
+	}
+	return dialer.Dial(t.options.Network, address)
+}
@@ -0,0 +1,12 @@
+// Package transport provides the built-in implementations of the
+// gen.NetworkTransport interface: TCP, Unix domain sockets (for the nodes
+// running on the same host), in-process memory transport and WebSocket
+// (for the nodes behind the HTTP-only infrastructure).
+package transport
+
+const (
+	TCP       string = "tcp"
+	Unix      string = "unix"
+	Memory    string = "memory"
+	WebSocket string = "websocket"
+)
@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+func TestTransports(t *testing.T) {
+	for _, tr := range []gen.NetworkTransport{
+		CreateTCP(TCPOptions{}),
+		CreateUnix(UnixOptions{Dir: t.TempDir()}),
+		CreateMemory(),
+		CreateWebSocket(WebSocketOptions{}),
+	} {
+		t.Run(tr.Name(), func(t *testing.T) {
+			var l net.Listener
+			var err error
+			for port := uint16(25000); port < 25100; port++ {
+				if l, err = tr.Listen(tr.Address("localhost", port)); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			go func() {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				io.Copy(c, c)
+				c.Close()
+			}()
+
+			c, err := tr.Dial(l.Addr().String(), time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ping := []byte("ping")
+			if _, err := c.Write(ping); err != nil {
+				t.Fatal(err)
+			}
+			pong := make([]byte, len(ping))
+			if _, err := io.ReadFull(c, pong); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(ping, pong) == false {
+				t.Fatalf("mismatch %q", pong)
+			}
+			c.Close()
+
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tr.Dial(l.Addr().String(), 100*time.Millisecond); err == nil {
+				t.Fatal("dial to the closed listener must fail")
+			}
+		})
+	}
+}
+
+func TestMemoryTaken(t *testing.T) {
+	tr := CreateMemory()
+	address := tr.Address("localhost", 1)
+	l, err := tr.Listen(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, err := tr.Listen(address); err == nil {
+		t.Fatal("address must be taken")
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	// borrow the certificate of the test HTTP server
+	server := httptest.NewTLSServer(nil)
+	server.Close()
+	client := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for _, options := range []WebSocketOptions{
+		{Path: "/ws"},
+		{Path: "/wss", TLS: server.TLS},
+	} {
+		t.Run(options.Path, func(t *testing.T) {
+			tr := CreateWebSocket(options)
+			var l net.Listener
+			var err error
+			for port := uint16(25100); port < 25200; port++ {
+				if l, err = tr.Listen(tr.Address("127.0.0.1", port)); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			go func() {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				io.Copy(c, c)
+				c.Close()
+			}()
+
+			if options.TLS != nil {
+				options.TLS = client
+			}
+			dialer := CreateWebSocket(options)
+			host, _, _ := net.SplitHostPort(l.Addr().String())
+			if _, err := dialer.Dial(host+":1/ws", 100*time.Millisecond); err == nil {
+				t.Fatal("dial must fail")
+			}
+			hostport := strings.TrimSuffix(l.Addr().String(), options.Path)
+			if _, err := dialer.Dial(hostport+"/unknown", time.Second); err == nil {
+				t.Fatal("dial to the unknown path must fail")
+			}
+
+			// address with no path uses the path of the dialer
+			c, err := dialer.Dial(hostport, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			// 7-bit, 16-bit and 64-bit payload length
+			for _, size := range []int{100, 1000, 100000} {
+				ping := bytes.Repeat([]byte{byte(size)}, size)
+				if _, err := c.Write(ping); err != nil {
+					t.Fatal(err)
+				}
+				pong := make([]byte, size)
+				if _, err := io.ReadFull(c, pong); err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Equal(ping, pong) == false {
+					t.Fatalf("mismatch (size %d)", size)
+				}
+			}
+		})
+	}
+}
+
+func TestWebSocketUnmasked(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	server := &wsConn{Conn: c2, reader: bufio.NewReader(c2)}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := server.Read(make([]byte, 4))
+		errs <- err
+	}()
+
+	// the frame is not masked, as the server does. the server may close the
+	// connection before the payload is written
+	unmasked := &wsConn{Conn: c1, reader: bufio.NewReader(c1)}
+	go unmasked.Write([]byte("ping"))
+
+	// close frame with the protocol error status
+	frame := make([]byte, 4)
+	if _, err := io.ReadFull(c1, frame); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(frame, []byte{0x80 | wsOpClose, 2, 0x03, 0xEA}) == false {
+		t.Fatalf("expected close frame, got %v", frame)
+	}
+	if err := <-errs; err == nil {
+		t.Fatal("unmasked frame must be rejected")
+	}
+	if _, err := c1.Read(frame); err != io.EOF {
+		t.Fatalf("connection must be closed, got %v", err)
+	}
+}
+
+func TestTCPNetwork(t *testing.T) {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available:", err)
+	}
+	defer l.Close()
+
+	// tcp4 by default, so the IPv6 address can't be dialed
+	if c, err := CreateTCP(TCPOptions{}).Dial(l.Addr().String(), time.Second); err == nil {
+		c.Close()
+		t.Fatal("IPv6 address must not be dialed over tcp4")
+	}
+	c, err := CreateTCP(TCPOptions{Network: "tcp6"}).Dial(l.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+type UnixOptions struct {
+	// Dir the directory for the socket files. Default: <os.TempDir()>/sparrow
+	Dir string
+}
+
+// CreateUnix creates transport using Unix domain sockets. Both nodes must use
+// the same directory for the socket files.
+func CreateUnix(options UnixOptions) gen.NetworkTransport {
+	if options.Dir == "" {
+		options.Dir = filepath.Join(os.TempDir(), "sparrow")
+	}
+	return &unix{options: options}
+}
+
+type unix struct {
+	options UnixOptions
+}
+
+func (u *unix) Name() string {
+	return Unix
+}
+
+func (u *unix) Address(host string, port uint16) string {
+	return filepath.Join(u.options.Dir, fmt.Sprintf("%s-%d.sock", host, port))
+}
+
+func (u *unix) Listen(address string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(address), 0700); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", address)
+	if err == nil {
+		return l, nil
+	}
+	if errors.Is(err, syscall.EADDRINUSE) == false {
+		return nil, err
+	}
+	// the socket file might be left by the terminated node
+	if c, derr := net.DialTimeout("unix", address, time.Second); derr == nil {
+		c.Close()
+		return nil, err
+	}
+	if err := os.Remove(address); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", address)
+}
+
+func (u *unix) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", address, timeout)
+}
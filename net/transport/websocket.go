@@ -0,0 +1,502 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+const (
+	// DefaultWebSocketPath the HTTP path of the WebSocket endpoint
+	DefaultWebSocketPath string = "/sparrow"
+
+	wsGUID string = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+
+	wsMaxControlPayload int = 125
+
+	wsCloseProtocolError uint16 = 1002
+)
+
+type WebSocketOptions struct {
+	// Path the HTTP path of the endpoint. Default: DefaultWebSocketPath.
+	// Dialing the address with no path uses this value as well.
+	Path string
+	// TLS enables WebSocket over TLS (wss). It is the server configuration for
+	// the listener and the client one for the dialer. Leave it empty if TLS is
+	// terminated by the HTTP infrastructure (ingress, reverse proxy).
+	TLS *tls.Config
+	// HandshakeTimeout the timeout for the HTTP upgrade. Default: 5 seconds.
+	HandshakeTimeout time.Duration
+}
+
+// CreateWebSocket creates transport carrying the node-to-node connections over
+// the WebSocket frames, so they can get through the HTTP-only infrastructure.
+// The route of the acceptor advertises the path of the endpoint (gen.Route.Path)
+// to be used with the path-based routing.
+func CreateWebSocket(options WebSocketOptions) gen.NetworkTransport {
+	if options.Path == "" {
+		options.Path = DefaultWebSocketPath
+	}
+	if strings.HasPrefix(options.Path, "/") == false {
+		options.Path = "/" + options.Path
+	}
+	if options.HandshakeTimeout == 0 {
+		options.HandshakeTimeout = 5 * time.Second
+	}
+	return &websocket{options: options}
+}
+
+type websocket struct {
+	options WebSocketOptions
+}
+
+func (w *websocket) Name() string {
+	return WebSocket
+}
+
+func (w *websocket) Path() string {
+	return w.options.Path
+}
+
+func (w *websocket) Address(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func (w *websocket) Listen(address string) (net.Listener, error) {
+	hostport, path := splitWebSocketAddress(address, w.options.Path)
+
+	ln, err := net.Listen("tcp", hostport)
+	if err != nil {
+		return nil, err
+	}
+	if w.options.TLS != nil {
+		ln = tls.NewListener(ln, w.options.TLS)
+	}
+
+	l := &wsListener{
+		ln:      ln,
+		addr:    wsAddr(ln.Addr().String() + path),
+		accept:  make(chan net.Conn),
+		closed:  make(chan struct{}),
+		timeout: w.options.HandshakeTimeout,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, l.upgrade)
+	l.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: w.options.HandshakeTimeout,
+		ErrorLog:          log.New(io.Discard, "", 0),
+	}
+	go l.server.Serve(ln)
+	return l, nil
+}
+
+func (w *websocket) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	hostport, path := splitWebSocketAddress(address, w.options.Path)
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var c net.Conn
+	var err error
+	if w.options.TLS != nil {
+		config := w.options.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(hostport)
+		}
+		c, err = tls.DialWithDialer(dialer, "tcp", hostport, config)
+	} else {
+		c, err = dialer.Dial("tcp", hostport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout == 0 {
+		timeout = w.options.HandshakeTimeout
+	}
+	c.SetDeadline(time.Now().Add(timeout))
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	skey := base64.StdEncoding.EncodeToString(key)
+
+	scheme := "ws"
+	if w.options.TLS != nil {
+		scheme = "wss"
+	}
+	request, err := http.NewRequest(http.MethodGet, scheme+"://"+hostport+path, nil)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", skey)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	if err := request.Write(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(c)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		c.Close()
+		return nil, fmt.Errorf("websocket upgrade %s%s failed: %s", hostport, path, response.Status)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(skey) {
+		c.Close()
+		return nil, fmt.Errorf("websocket upgrade %s%s failed: incorrect accept key", hostport, path)
+	}
+	c.SetDeadline(time.Time{})
+
+	return &wsConn{
+		Conn:   c,
+		reader: reader,
+		client: true,
+		local:  wsAddr(c.LocalAddr().String()),
+		remote: wsAddr(hostport + path),
+	}, nil
+}
+
+// splitWebSocketAddress splits the address "host:port/path" into the host:port
+// and the path parts
+func splitWebSocketAddress(address string, defaultPath string) (string, string) {
+	i := strings.Index(address, "/")
+	if i < 0 {
+		return address, defaultPath
+	}
+	return address[:i], address[i:]
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+//
+// listener
+//
+
+type wsListener struct {
+	ln      net.Listener
+	server  *http.Server
+	addr    wsAddr
+	accept  chan net.Conn
+	closed  chan struct{}
+	once    sync.Once
+	timeout time.Duration
+}
+
+func (l *wsListener) upgrade(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet ||
+		headerContains(r.Header, "Connection", "upgrade") == false ||
+		headerContains(r.Header, "Upgrade", "websocket") == false {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing websocket key", http.StatusBadRequest)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if ok == false {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return
+	}
+	c, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	c.SetDeadline(time.Now().Add(l.timeout))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		c.Close()
+		return
+	}
+	c.SetDeadline(time.Time{})
+
+	// local address is the one the client has dialed (through the proxies
+	// if any), so the peer can use it to make the pool connections
+	local := wsAddr(r.Host + r.URL.Path)
+	if r.Host == "" {
+		local = l.addr
+	}
+	conn := &wsConn{
+		Conn:   c,
+		reader: rw.Reader,
+		local:  local,
+		remote: wsAddr(c.RemoteAddr().String()),
+	}
+
+	select {
+	case l.accept <- conn:
+	case <-l.closed:
+		c.Close()
+	}
+}
+
+func (l *wsListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *wsListener) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.closed)
+		err = l.server.Close()
+	})
+	return err
+}
+
+func (l *wsListener) Addr() net.Addr {
+	return l.addr
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//
+// connection
+//
+
+// wsConn carries the byte stream over the binary WebSocket frames
+type wsConn struct {
+	net.Conn
+	reader *bufio.Reader
+	client bool // client masks the frames it sends
+	local  wsAddr
+	remote wsAddr
+
+	// read state
+	remaining int64
+	mask      [4]byte
+	masked    bool
+	offset    int
+
+	wmutex sync.Mutex
+}
+
+func (c *wsConn) Read(b []byte) (int, error) {
+	for c.remaining == 0 {
+		if err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(b)) > c.remaining {
+		b = b[:c.remaining]
+	}
+	n, err := c.reader.Read(b)
+	if c.masked {
+		for i := 0; i < n; i++ {
+			b[i] ^= c.mask[(c.offset+i)%4]
+		}
+	}
+	c.offset += n
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// nextFrame reads the header of the next data frame handling the control frames
+func (c *wsConn) nextFrame() error {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return err
+		}
+		opcode := header[0] & 0x0F
+		masked := header[1]&0x80 != 0
+		length := int64(header[1] & 0x7F)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint64(ext[:]) & 0x7FFFFFFFFFFFFFFF)
+		}
+
+		if masked == c.client {
+			// the frames sent by the client must be masked, the ones sent by
+			// the server must not (RFC 6455, 5.1)
+			var status [2]byte
+			binary.BigEndian.PutUint16(status[:], wsCloseProtocolError)
+			c.writeFrame(wsOpClose, status[:])
+			c.Conn.Close()
+			if c.client {
+				return fmt.Errorf("websocket frame from the server is masked")
+			}
+			return fmt.Errorf("websocket frame from the client is not masked")
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+				return err
+			}
+		}
+
+		switch opcode {
+		case wsOpContinuation, wsOpText, wsOpBinary:
+			c.remaining = length
+			c.mask = mask
+			c.masked = masked
+			c.offset = 0
+			return nil
+		}
+
+		// control frame
+		if length > int64(wsMaxControlPayload) {
+			return fmt.Errorf("websocket control frame is too large")
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return err
+			}
+		case wsOpPong:
+			// ignore
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return io.EOF
+		default:
+			return fmt.Errorf("unknown websocket opcode %d", opcode)
+		}
+	}
+}
+
+func (c *wsConn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(wsOpBinary, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeFrame writes the header and the payload of the frame at once. The payload
+// of the client frame is masked in the buffer taken from the pool.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	var header [14]byte
+	l := len(payload)
+	frame := append(header[:0], 0x80|opcode) // FIN
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch {
+	case l < 126:
+		frame = append(frame, maskBit|byte(l))
+	case l <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(l))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(l))
+	}
+
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		buf := lib.TakeBuffer()
+		defer lib.ReleaseBuffer(buf)
+		masked := buf.Extend(l)
+		for i := 0; i < l; i++ {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	buffers := net.Buffers{frame, payload}
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	_, err := buffers.WriteTo(c.Conn)
+	return err
+}
+
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type wsAddr string
+
+func (a wsAddr) Network() string {
+	return WebSocket
+}
+
+func (a wsAddr) String() string {
+	return string(a)
+}
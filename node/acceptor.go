@@ -2,6 +2,8 @@ package node
 
 import (
 	"net"
+	"sync/atomic"
+	"time"
 
 	"github.com/sllt/sparrow/gen"
 )
@@ -9,9 +11,12 @@ import (
 type acceptor struct {
 	l                net.Listener
 	bs               int
-	cookie           string
+	cookies          atomic.Pointer[cookieJar]
+	network_cookies  *cookieJar // shared with the network unless it has its own cookie
 	port             uint16
 	cert_manager     gen.CertManager
+	transport        gen.NetworkTransport
+	mtls             *gen.MutualTLSOptions
 	flags            gen.NetworkFlags
 	max_message_size int
 
@@ -27,11 +32,23 @@ type acceptor struct {
 // gen.Acceptor interface implementation
 
 func (a *acceptor) Cookie() string {
-	return a.cookie
+	return a.cookies.Load().current()
 }
 
 func (a *acceptor) SetCookie(cookie string) {
-	a.cookie = cookie
+	jar := &cookieJar{}
+	jar.set(cookie)
+	a.cookies.Store(jar)
+}
+
+func (a *acceptor) RotateCookie(cookie string, expiry time.Duration) {
+	jar := a.cookies.Load()
+	if jar == a.network_cookies {
+		// keep the network cookies untouched
+		jar = jar.clone()
+		a.cookies.Store(jar)
+	}
+	jar.rotate(cookie, expiry)
 }
 
 func (a *acceptor) NetworkFlags() gen.NetworkFlags {
@@ -63,6 +80,8 @@ func (a *acceptor) Info() gen.AcceptorInfo {
 		MaxMessageSize:   a.max_message_size,
 		Flags:            a.flags,
 		TLS:              a.cert_manager != nil,
+		MutualTLS:        a.mtls != nil,
+		Transport:        a.transport.Name(),
 		CustomRegistrar:  a.registrar_custom,
 		HandshakeVersion: a.handshake.Version(),
 		ProtoVersion:     a.proto.Version(),
@@ -0,0 +1,183 @@
+package node
+
+import (
+	"sync/atomic"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+// acl keeps the access control list for the incoming remote requests. The list
+// is replaced entirely on update, so the checks are lock-free.
+type acl struct {
+	list   atomic.Pointer[aclList]
+	denied uint64
+}
+
+type aclList struct {
+	options gen.NetworkACL
+	rules   []aclRule
+}
+
+type aclRule struct {
+	nodes   map[gen.Atom]bool
+	names   map[gen.Atom]bool
+	aliases map[gen.Alias]bool
+	events  map[gen.Atom]bool
+	ops     gen.NetworkACLOperation
+	deny    bool
+}
+
+func (a *acl) set(options gen.NetworkACL) {
+	if len(options.Rules) == 0 && options.DefaultDeny == false {
+		a.list.Store(nil)
+		return
+	}
+
+	list := &aclList{
+		options: copyACL(options),
+	}
+	for _, r := range options.Rules {
+		rule := aclRule{
+			ops:  r.Operations,
+			deny: r.Deny,
+		}
+		if rule.ops == 0 {
+			rule.ops = gen.NetworkACLAny
+		}
+		if len(r.Nodes) > 0 || len(r.Groups) > 0 {
+			rule.nodes = make(map[gen.Atom]bool)
+			for _, node := range r.Nodes {
+				rule.nodes[node] = true
+			}
+			for _, group := range r.Groups {
+				for _, node := range options.Groups[group] {
+					rule.nodes[node] = true
+				}
+			}
+		}
+		if len(r.Names)+len(r.Aliases)+len(r.Events) > 0 {
+			rule.names = make(map[gen.Atom]bool)
+			for _, name := range r.Names {
+				rule.names[name] = true
+			}
+			rule.aliases = make(map[gen.Alias]bool)
+			for _, alias := range r.Aliases {
+				rule.aliases[alias] = true
+			}
+			rule.events = make(map[gen.Atom]bool)
+			for _, event := range r.Events {
+				rule.events[event] = true
+			}
+		}
+		list.rules = append(list.rules, rule)
+	}
+	a.list.Store(list)
+}
+
+func (a *acl) get() gen.NetworkACL {
+	list := a.list.Load()
+	if list == nil {
+		return gen.NetworkACL{}
+	}
+	return copyACL(list.options)
+}
+
+// checkACL returns gen.ErrAccessDenied if the remote node is not allowed to make
+// the request with the local target (gen.PID, gen.ProcessID, gen.Alias or gen.Event)
+func (n *node) checkACL(peer gen.Atom, op gen.NetworkACLOperation, target any) error {
+	if peer == n.name {
+		return nil
+	}
+	list := n.network.acl.list.Load()
+	if list == nil {
+		return nil
+	}
+
+	// registered name of the process the request is made to
+	var name gen.Atom
+	var resolved bool
+	owner := func() gen.Atom {
+		if resolved {
+			return name
+		}
+		resolved = true
+		var value any
+		var found bool
+		switch t := target.(type) {
+		case gen.PID:
+			value, found = n.processes.Load(t)
+		case gen.Alias:
+			value, found = n.aliases.Load(t)
+		}
+		if found {
+			if p := value.(*process); p.registered.Load() {
+				name = p.name
+			}
+		}
+		return name
+	}
+
+	for _, rule := range list.rules {
+		if rule.ops&op == 0 {
+			continue
+		}
+		if rule.nodes != nil && rule.nodes[peer] == false {
+			continue
+		}
+		if rule.names != nil {
+			match := false
+			switch t := target.(type) {
+			case gen.ProcessID:
+				match = rule.names[t.Name]
+			case gen.PID:
+				match = len(rule.names) > 0 && rule.names[owner()]
+			case gen.Alias:
+				match = rule.aliases[t] || (len(rule.names) > 0 && rule.names[owner()])
+			case gen.Event:
+				match = rule.events[t.Name]
+			}
+			if match == false {
+				continue
+			}
+		}
+		if rule.deny {
+			return n.denyACL(peer, op, target)
+		}
+		return nil
+	}
+
+	if list.options.DefaultDeny {
+		return n.denyACL(peer, op, target)
+	}
+	return nil
+}
+
+func (n *node) denyACL(peer gen.Atom, op gen.NetworkACLOperation, target any) error {
+	atomic.AddUint64(&n.network.acl.denied, 1)
+	if lib.Trace() {
+		n.log.Trace("ACL denied %s request from %s to %v", op, peer, target)
+	}
+	return gen.ErrAccessDenied
+}
+
+func copyACL(options gen.NetworkACL) gen.NetworkACL {
+	c := gen.NetworkACL{
+		DefaultDeny: options.DefaultDeny,
+	}
+	if options.Groups != nil {
+		c.Groups = make(map[string][]gen.Atom)
+		for group, nodes := range options.Groups {
+			c.Groups[group] = append([]gen.Atom{}, nodes...)
+		}
+	}
+	for _, rule := range options.Rules {
+		rule.Nodes = append([]gen.Atom(nil), rule.Nodes...)
+		rule.Groups = append([]string(nil), rule.Groups...)
+		rule.Names = append([]gen.Atom(nil), rule.Names...)
+		rule.Aliases = append([]gen.Alias(nil), rule.Aliases...)
+		rule.Events = append([]gen.Atom(nil), rule.Events...)
+		c.Rules = append(c.Rules, rule)
+	}
+	return c
+}
@@ -0,0 +1,11 @@
+package node
+
+import (
+	"github.com/sllt/sparrow/gen"
+)
+
+// NetworkBandwidthLimit implements gen.Core interface
+func (n *node) NetworkBandwidthLimit(peer gen.Atom) int {
+	limit, _ := n.network.bandwidth.lookup(peer)
+	return limit
+}
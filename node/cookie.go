@@ -0,0 +1,91 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+// cookieJar keeps the current cookie and the previous ones that are still
+// accepted after the rotation until they expire
+type cookieJar struct {
+	sync.RWMutex
+	cookie     string
+	generation int
+	previous   []gen.NetworkCookie // the most recent first
+}
+
+func (j *cookieJar) get() (string, int, []gen.NetworkCookie) {
+	j.RLock()
+	defer j.RUnlock()
+
+	var previous []gen.NetworkCookie
+	now := time.Now()
+	for _, c := range j.previous {
+		if c.Expires.After(now) {
+			previous = append(previous, c)
+		}
+	}
+	return j.cookie, j.generation, previous
+}
+
+func (j *cookieJar) current() string {
+	j.RLock()
+	defer j.RUnlock()
+	return j.cookie
+}
+
+func (j *cookieJar) currentGeneration() int {
+	j.RLock()
+	defer j.RUnlock()
+	return j.generation
+}
+
+func (j *cookieJar) set(cookie string) {
+	j.Lock()
+	defer j.Unlock()
+	j.cookie = cookie
+	j.generation++
+	j.previous = nil
+}
+
+func (j *cookieJar) rotate(cookie string, expiry time.Duration) {
+	if expiry <= 0 {
+		expiry = gen.DefaultCookieExpiry
+	}
+
+	j.Lock()
+	defer j.Unlock()
+
+	if cookie == j.cookie {
+		return
+	}
+
+	now := time.Now()
+	previous := []gen.NetworkCookie{
+		{
+			Cookie:     j.cookie,
+			Generation: j.generation,
+			Expires:    now.Add(expiry),
+		},
+	}
+	for _, c := range j.previous {
+		if c.Expires.After(now) && c.Cookie != cookie {
+			previous = append(previous, c)
+		}
+	}
+	j.previous = previous
+	j.cookie = cookie
+	j.generation++
+}
+
+func (j *cookieJar) clone() *cookieJar {
+	j.RLock()
+	defer j.RUnlock()
+	return &cookieJar{
+		cookie:     j.cookie,
+		generation: j.generation,
+		previous:   append([]gen.NetworkCookie(nil), j.previous...),
+	}
+}
@@ -29,6 +29,10 @@ func (n *node) RouteSendPID(from gen.PID, to gen.PID, options gen.MessageOptions
 		return connection.SendPID(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLSend, to); err != nil {
+		return err
+	}
+
 	// local
 	value, found := n.processes.Load(to)
 	if found == false {
@@ -97,6 +101,10 @@ func (n *node) RouteSendProcessID(from gen.PID, to gen.ProcessID, options gen.Me
 		return connection.SendProcessID(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLSend, to); err != nil {
+		return err
+	}
+
 	value, found := n.names.Load(to.Name)
 	if found == false {
 		return gen.ErrProcessUnknown
@@ -165,6 +173,10 @@ func (n *node) RouteSendAlias(from gen.PID, to gen.Alias, options gen.MessageOpt
 		return connection.SendAlias(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLSend, to); err != nil {
+		return err
+	}
+
 	value, found := n.aliases.Load(to)
 	if found == false {
 		return gen.ErrProcessUnknown
@@ -298,6 +310,10 @@ func (n *node) RouteSendExit(from gen.PID, to gen.PID, reason error) error {
 		return connection.SendExit(from, to, reason)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLSend, to); err != nil {
+		return err
+	}
+
 	message := gen.MessageExitPID{
 		PID:    from,
 		Reason: reason,
@@ -397,6 +413,10 @@ func (n *node) RouteCallPID(from gen.PID, to gen.PID, options gen.MessageOptions
 		return connection.CallPID(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLCall, to); err != nil {
+		return err
+	}
+
 	// local
 	value, found := n.processes.Load(to)
 	if found == false {
@@ -450,6 +470,10 @@ func (n *node) RouteCallProcessID(from gen.PID, to gen.ProcessID, options gen.Me
 		return connection.CallProcessID(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLCall, to); err != nil {
+		return err
+	}
+
 	value, found := n.names.Load(to.Name)
 	if found == false {
 		return gen.ErrProcessUnknown
@@ -503,6 +527,10 @@ func (n *node) RouteCallAlias(from gen.PID, to gen.Alias, options gen.MessageOpt
 		return connection.CallAlias(from, to, options, message)
 	}
 
+	if err := n.checkACL(from.Node, gen.NetworkACLCall, to); err != nil {
+		return err
+	}
+
 	value, found := n.aliases.Load(to)
 	if found == false {
 		return gen.ErrProcessUnknown
@@ -556,6 +584,9 @@ func (n *node) RouteLinkPID(pid gen.PID, target gen.PID) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLLink, target); err != nil {
+			return err
+		}
 		// local target
 		if _, exist := n.processes.Load(target); exist == false {
 			return gen.ErrProcessUnknown
@@ -619,6 +650,9 @@ func (n *node) RouteLinkProcessID(pid gen.PID, target gen.ProcessID) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLLink, target); err != nil {
+			return err
+		}
 		// local target
 		if _, exist := n.names.Load(target.Name); exist == false {
 			return gen.ErrProcessUnknown
@@ -679,6 +713,9 @@ func (n *node) RouteLinkAlias(pid gen.PID, target gen.Alias) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLLink, target); err != nil {
+			return err
+		}
 		// local target
 		if _, exist := n.aliases.Load(target); exist == false {
 			return gen.ErrAliasUnknown
@@ -743,6 +780,9 @@ func (n *node) RouteLinkEvent(pid gen.PID, target gen.Event) ([]gen.MessageEvent
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLLink, target); err != nil {
+			return nil, err
+		}
 		var lastEventMessages []gen.MessageEvent
 		// local target
 		value, exist := n.events.Load(target)
@@ -853,6 +893,9 @@ func (n *node) RouteMonitorPID(pid gen.PID, target gen.PID) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLMonitor, target); err != nil {
+			return err
+		}
 		// local target
 		if v, exist := n.processes.Load(target); exist == false {
 			return gen.ErrProcessUnknown
@@ -920,6 +963,9 @@ func (n *node) RouteMonitorProcessID(pid gen.PID, target gen.ProcessID) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLMonitor, target); err != nil {
+			return err
+		}
 		// local target
 		if v, exist := n.names.Load(target.Name); exist == false {
 			return gen.ErrProcessUnknown
@@ -988,6 +1034,9 @@ func (n *node) RouteMonitorAlias(pid gen.PID, target gen.Alias) error {
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLMonitor, target); err != nil {
+			return err
+		}
 		// local target
 		if _, exist := n.aliases.Load(target); exist == false {
 			return gen.ErrAliasUnknown
@@ -1053,6 +1102,9 @@ func (n *node) RouteMonitorEvent(pid gen.PID, target gen.Event) ([]gen.MessageEv
 	}
 
 	if n.name == target.Node {
+		if err := n.checkACL(pid.Node, gen.NetworkACLMonitor, target); err != nil {
+			return nil, err
+		}
 		var lastEventMessages []gen.MessageEvent
 		// local target
 		value, exist := n.events.Load(target)
@@ -0,0 +1,22 @@
+package node
+
+import (
+	"github.com/sllt/sparrow/gen"
+)
+
+func validateFaults(nf gen.NetworkFaults) error {
+	if nf.Latency < 0 || nf.Jitter < 0 {
+		return gen.ErrIncorrect
+	}
+	for _, rate := range []float64{nf.DropRate, nf.ReorderRate, nf.DisconnectRate} {
+		if rate < 0 || rate > 1 {
+			return gen.ErrIncorrect
+		}
+	}
+	return nil
+}
+
+// NetworkFaults implements gen.Core interface
+func (n *node) NetworkFaults(peer gen.Atom) (gen.NetworkFaults, bool) {
+	return n.network.faults.lookup(peer)
+}
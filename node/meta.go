@@ -79,6 +79,13 @@ func (m *meta) Log() gen.Log {
 	return m.log
 }
 
+// MailboxLen returns the number of messages in the mailbox of the local process.
+// It is not a part of gen.MetaProcess, the meta processes use it for the flow
+// control (see meta.FlowControl).
+func (m *meta) MailboxLen(to any) (int64, error) {
+	return m.p.node.mailboxLen(to)
+}
+
 func (m *meta) init() (r error) {
 	if lib.Recover() {
 		defer func() {
@@ -1,13 +1,12 @@
 package node
 
 import (
-	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"reflect"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,6 +17,7 @@ import (
 	"github.com/sllt/sparrow/net/handshake"
 	"github.com/sllt/sparrow/net/proto"
 	"github.com/sllt/sparrow/net/registrar"
+	"github.com/sllt/sparrow/net/transport"
 )
 
 func createNetwork(node *node) *network {
@@ -31,6 +31,10 @@ func createNetwork(node *node) *network {
 	// register standard handshake and proto
 	n.RegisterHandshake(n.defaultHandshake)
 	n.RegisterProto(n.defaultProto)
+	n.RegisterTransport(transport.CreateTCP(transport.TCPOptions{Network: "tcp"}))
+	n.RegisterTransport(transport.CreateUnix(transport.UnixOptions{}))
+	n.RegisterTransport(transport.CreateMemory())
+	n.RegisterTransport(transport.CreateWebSocket(transport.WebSocketOptions{}))
 	return n
 }
 
@@ -40,6 +44,13 @@ type network struct {
 	mode       gen.NetworkMode
 	flags      gen.NetworkFlags
 	skipverify bool
+	mtls       *gen.MutualTLSOptions
+	acl        acl
+	faults     peerMap[gen.NetworkFaults]
+	heartbeat  gen.NetworkHeartbeat
+	scaling    gen.NetworkPoolScaling
+	sendQueue  int
+	bandwidth  peerMap[int]
 
 	node      *node
 	registrar gen.Registrar
@@ -51,8 +62,9 @@ type network struct {
 
 	handshakes sync.Map // .Version().String() -> handshake
 	protos     sync.Map // .Version().String() -> proto
+	transports sync.Map // .Name() -> transport
 
-	cookie         string
+	cookies        cookieJar
 	maxmessagesize int
 
 	staticRoutes  *staticRoutes
@@ -72,16 +84,31 @@ func (n *network) Registrar() (gen.Registrar, error) {
 }
 
 func (n *network) Cookie() string {
-	return n.cookie
+	return n.cookies.current()
 }
 func (n *network) SetCookie(cookie string) error {
-	n.cookie = cookie
+	n.cookies.set(cookie)
 	if lib.Trace() {
 		n.node.Log().Trace("updated cookie")
 	}
 	return nil
 }
 
+func (n *network) RotateCookie(cookie string, expiry time.Duration) error {
+	if cookie == "" {
+		return gen.ErrIncorrect
+	}
+	n.cookies.rotate(cookie, expiry)
+	if lib.Trace() {
+		n.node.Log().Trace("rotated cookie (generation %d)", n.cookies.currentGeneration())
+	}
+	return nil
+}
+
+func (n *network) CookieGeneration() int {
+	return n.cookies.currentGeneration()
+}
+
 func (n *network) NetworkFlags() gen.NetworkFlags {
 	return n.flags
 }
@@ -438,6 +465,17 @@ func (n *network) RegisterHandshake(handshake gen.NetworkHandshake) {
 	}
 }
 
+func (n *network) RegisterTransport(transport gen.NetworkTransport) {
+	if transport == nil {
+		n.node.Log().Error("unable to register nil value as a transport")
+		return
+	}
+	n.transports.Store(transport.Name(), transport)
+	if lib.Trace() {
+		n.node.Log().Trace("registered transport %s", transport.Name())
+	}
+}
+
 func (n *network) RegisterProto(proto gen.NetworkProto) {
 	if proto == nil {
 		n.node.Log().Error("unable to register nil value as a proto ")
@@ -493,10 +531,68 @@ func (n *network) Info() (gen.NetworkInfo, error) {
 
 	info.EnabledSpawn = n.listEnabledSpawn()
 	info.EnabledApplicationStart = n.listEnabledApplicationStart()
+	info.ACLDenied = atomic.LoadUint64(&n.acl.denied)
+	info.CookieGeneration = n.cookies.currentGeneration()
+	if n.faults.enabled() {
+		info.FaultInjection = true
+		info.Faults = n.faults.get()
+	}
 
 	return info, nil
 }
 
+func (n *network) ACL() gen.NetworkACL {
+	return n.acl.get()
+}
+
+func (n *network) SetACL(acl gen.NetworkACL) {
+	n.acl.set(acl)
+}
+
+func (n *network) InjectFaults(peer gen.Atom, faults gen.NetworkFaults) error {
+	if err := validateFaults(faults); err != nil {
+		return err
+	}
+	n.faults.set(peer, faults)
+	if peer == "" {
+		n.node.log.Warning("network fault injection enabled by default for all peers: %#v", faults)
+		return nil
+	}
+	n.node.log.Warning("network fault injection enabled for %s: %#v", peer, faults)
+	return nil
+}
+
+func (n *network) RemoveFaults(peer gen.Atom) {
+	if n.faults.remove(peer) == false {
+		return
+	}
+	if peer == "" {
+		n.node.log.Info("network fault injection disabled by default for all peers")
+		return
+	}
+	n.node.log.Info("network fault injection disabled for %s", peer)
+}
+
+func (n *network) Faults() map[gen.Atom]gen.NetworkFaults {
+	return n.faults.get()
+}
+
+func (n *network) SetBandwidthLimit(peer gen.Atom, limit int) error {
+	if limit < 0 {
+		return gen.ErrIncorrect
+	}
+	if limit == 0 {
+		n.bandwidth.remove(peer)
+		return nil
+	}
+	n.bandwidth.set(peer, limit)
+	return nil
+}
+
+func (n *network) BandwidthLimits() map[gen.Atom]int {
+	return n.bandwidth.get()
+}
+
 func (n *network) Mode() gen.NetworkMode {
 	return n.mode
 }
@@ -563,9 +659,6 @@ func (n *network) GetConnection(name gen.Atom) (gen.Connection, error) {
 				if nroute.Route.TLS && nroute.Cert == nil {
 					nroute.Cert = n.node.certmanager
 				}
-				if nroute.Cookie == "" {
-					nroute.Cookie = n.cookie
-				}
 				if c, err := n.connect(name, nroute); err == nil {
 					return c, nil
 				} else {
@@ -631,7 +724,6 @@ func (n *network) GetConnection(name gen.Atom) (gen.Connection, error) {
 			nroute := gen.NetworkRoute{
 				Route:              route,
 				InsecureSkipVerify: n.skipverify,
-				Cookie:             n.cookie,
 			}
 
 			if route.TLS {
@@ -682,7 +774,7 @@ func (n *network) GetConnection(name gen.Atom) (gen.Connection, error) {
 }
 
 func (n *network) connect(name gen.Atom, route gen.NetworkRoute) (gen.Connection, error) {
-	var dial func(network, addr string) (net.Conn, error)
+	var tlsconfig *tls.Config
 
 	if n.running.Load() == false {
 		return nil, gen.ErrNetworkStopped
@@ -704,50 +796,110 @@ func (n *network) connect(name gen.Atom, route gen.NetworkRoute) (gen.Connection
 		route.Route.Host = name.Host()
 	}
 
+	tr := route.Transport
+	if tr == nil {
+		tname := route.Route.Transport
+		if tname == "" {
+			tname = transport.TCP
+		}
+		v, found := n.transports.Load(tname)
+		if found == false {
+			return nil, fmt.Errorf("no transport %q", tname)
+		}
+		tr = v.(gen.NetworkTransport)
+	}
+
 	if lib.Trace() {
-		n.node.Log().Trace("trying to connect to %s (%s:%d, tls:%v)",
-			name, route.Route.Host, route.Route.Port, route.Route.TLS)
+		n.node.Log().Trace("trying to connect to %s (%s:%d, tls:%v, transport: %s)",
+			name, route.Route.Host, route.Route.Port, route.Route.TLS, tr.Name())
 	}
 
-	dialer := &net.Dialer{
-		KeepAlive: gen.DefaultKeepAlivePeriod,
-		Timeout:   3 * time.Second, // timeout to establish TCP-connection
+	mtls := route.MutualTLS
+	if mtls == nil {
+		mtls = n.mtls
 	}
 
 	if route.Route.TLS {
-		tlsconfig := &tls.Config{
+		tlsconfig = &tls.Config{
+			ServerName:         route.Route.Host,
 			InsecureSkipVerify: route.InsecureSkipVerify,
 		}
-		tlsdialer := tls.Dialer{
-			NetDialer: dialer,
-			Config:    tlsconfig,
+		if mtls != nil {
+			cm := route.Cert
+			if cm == nil {
+				cm = n.node.CertManager()
+			}
+			if cm == nil {
+				return nil, fmt.Errorf("mutual TLS with %s requires certificate", name)
+			}
+			tlsconfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				cert := cm.GetCertificate()
+				return &cert, nil
+			}
+			// the peer is identified by the node name (see authorizePeer),
+			// so verify the chain only, not the host name
+			tlsconfig.InsecureSkipVerify = true
+			tlsconfig.VerifyConnection = verifyPeerChain(mtls.CAs, x509.ExtKeyUsageServerAuth)
 		}
-		dial = tlsdialer.Dial
-	} else {
-		dial = dialer.Dial
+	} else if mtls != nil {
+		return nil, fmt.Errorf("mutual TLS with %s requires TLS-enabled route", name)
 	}
-	dsn := net.JoinHostPort(route.Route.Host, strconv.Itoa(int(route.Route.Port)))
-	conn, err := dial("tcp", dsn)
-	if err != nil {
-		return nil, err
+
+	dial := func(dsn string) (net.Conn, error) {
+		// timeout to establish connection
+		timeout := 3 * time.Second
+		conn, err := tr.Dial(dsn, timeout)
+		if err != nil || tlsconfig == nil {
+			return conn, err
+		}
+		tlsconn := tls.Client(conn, tlsconfig)
+		conn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsconn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return tlsconn, nil
 	}
+	dsn := tr.Address(route.Route.Host, route.Route.Port) + route.Route.Path
 
 	hopts := gen.HandshakeOptions{
-		Cookie:         route.Cookie,
 		Flags:          route.Flags,
 		MaxMessageSize: n.maxmessagesize,
 	}
-
-	if hopts.Cookie == "" {
-		hopts.Cookie = n.cookie
-	}
 	if hopts.Flags.Enable == false {
 		hopts.Flags = n.flags
 	}
+	if mtls != nil {
+		hopts.Authorize = n.authorizer(mtls)
+	}
 
-	result, err := handshake.Start(n.node, conn, hopts)
-	if err != nil {
+	// during the cookie rotation the peer might have the previous cookie
+	var conn net.Conn
+	var result gen.HandshakeResult
+	var err error
+	for _, cookie := range n.routeCookies(route) {
+		conn, err = dial(dsn)
+		if err != nil {
+			return nil, err
+		}
+		hopts.Cookie = cookie.Cookie
+		hopts.CookieGeneration = cookie.Generation
+		result, err = handshake.Start(n.node, conn, hopts)
+		if err == nil {
+			break
+		}
 		conn.Close()
+		if lib.Trace() {
+			n.node.Log().Trace("handshake with %s (cookie generation %d) failed: %s",
+				name, cookie.Generation, err)
+		}
+		if err != gen.ErrDigestMismatch {
+			// the other cookies won't help
+			break
+		}
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -776,6 +928,9 @@ func (n *network) connect(name gen.Atom, route gen.NetworkRoute) (gen.Connection
 	}
 	log.setSource(logSource)
 
+	result.Heartbeat = n.heartbeat
+	result.PoolScaling = n.scaling
+	result.SendQueueLimit = n.sendQueue
 	pconn, err := proto.NewConnection(n.node, result, log)
 	if err != nil {
 		conn.Close()
@@ -783,10 +938,16 @@ func (n *network) connect(name gen.Atom, route gen.NetworkRoute) (gen.Connection
 	}
 
 	redial := func(dsn, id string) (net.Conn, []byte, error) {
-		c, err := dial("tcp", dsn)
+		c, err := dial(dsn)
 		if err != nil {
 			return nil, nil, err
 		}
+		if mtls != nil {
+			if _, err := peerCertificate(c, name); err != nil {
+				c.Close()
+				return nil, nil, err
+			}
+		}
 		tail, err := handshake.Join(n.node, c, id, hopts)
 		if err != nil {
 			return nil, nil, err
@@ -809,6 +970,19 @@ func (n *network) connect(name gen.Atom, route gen.NetworkRoute) (gen.Connection
 	return pconn, nil
 }
 
+// routeCookies returns the cookies to make the handshake with, the current one first
+func (n *network) routeCookies(route gen.NetworkRoute) []gen.NetworkCookie {
+	if route.Cookie != "" {
+		cookies := []gen.NetworkCookie{{Cookie: route.Cookie}}
+		for i, cookie := range route.PreviousCookies {
+			cookies = append(cookies, gen.NetworkCookie{Cookie: cookie, Generation: -(i + 1)})
+		}
+		return cookies
+	}
+	cookie, generation, previous := n.cookies.get()
+	return append([]gen.NetworkCookie{{Cookie: cookie, Generation: generation}}, previous...)
+}
+
 func (n *network) serve(proto gen.NetworkProto, conn gen.Connection, redial gen.NetworkDial) {
 	name := conn.Node().Name()
 	if lib.Recover() {
@@ -875,6 +1049,8 @@ func (n *network) start(options gen.NetworkOptions) error {
 	}
 
 	n.skipverify = options.InsecureSkipVerify
+	n.mtls = options.MutualTLS
+	n.acl.set(options.ACL)
 	n.registrar = options.Registrar
 	if n.registrar == nil {
 		n.registrar = registrar.Create(registrar.Options{})
@@ -884,8 +1060,16 @@ func (n *network) start(options gen.NetworkOptions) error {
 		n.node.log.Warning("cookie is empty (gen.NetworkOptions), used randomized value")
 		options.Cookie = lib.RandomString(16)
 	}
-	n.cookie = options.Cookie
+	n.cookies.set(options.Cookie)
 	n.maxmessagesize = options.MaxMessageSize
+	n.heartbeat = options.Heartbeat
+	n.scaling = options.PoolScaling
+	n.sendQueue = options.SendQueueLimit
+	for peer, limit := range options.BandwidthLimits {
+		if limit > 0 {
+			n.bandwidth.set(peer, limit)
+		}
+	}
 
 	if options.Flags.Enable == false {
 		options.Flags = gen.DefaultNetworkFlags
@@ -924,7 +1108,6 @@ func (n *network) start(options gen.NetworkOptions) error {
 			Host:           nodehost[1],
 			Port:           gen.DefaultPort,
 			CertManager:    n.node.CertManager(),
-			Cookie:         options.Cookie,
 			MaxMessageSize: options.MaxMessageSize,
 			Flags:          options.Flags,
 		}
@@ -933,6 +1116,8 @@ func (n *network) start(options gen.NetworkOptions) error {
 
 	if options.Handshake != nil {
 		n.defaultHandshake = options.Handshake
+		// replace the standard one, so it is used for the outgoing connections as well
+		n.handshakes.Store(options.Handshake.Version().Str(), options.Handshake)
 	}
 	if options.Proto != nil {
 		n.defaultProto = options.Proto
@@ -1000,6 +1185,10 @@ func (n *network) start(options gen.NetworkOptions) error {
 			TLS:              acceptor.cert_manager != nil,
 			HandshakeVersion: acceptor.handshake.Version(),
 			ProtoVersion:     acceptor.proto.Version(),
+			Transport:        acceptor.transport.Name(),
+		}
+		if tp, ok := acceptor.transport.(gen.NetworkTransportPath); ok {
+			r.Path = tp.Path()
 		}
 		if a.Registrar == nil {
 			acceptor.registrar_info = n.registrar.Info
@@ -1057,8 +1246,9 @@ func (n *network) start(options gen.NetworkOptions) error {
 }
 
 func (n *network) startAcceptor(a gen.AcceptorOptions) (*acceptor, error) {
-	lc := net.ListenConfig{
-		KeepAlive: gen.DefaultKeepAlivePeriod,
+	tr := a.Transport
+	if tr == nil {
+		tr = transport.CreateTCP(transport.TCPOptions{Network: a.TCP})
 	}
 
 	cert_manager := a.CertManager
@@ -1087,19 +1277,23 @@ func (n *network) startAcceptor(a gen.AcceptorOptions) (*acceptor, error) {
 		proto:            a.Proto,
 		handshake:        a.Handshake,
 		cert_manager:     cert_manager,
+		transport:        tr,
+		mtls:             a.MutualTLS,
 		max_message_size: a.MaxMessageSize,
 		atom_mapping:     make(map[gen.Atom]gen.Atom),
 	}
+	acceptor.network_cookies = &n.cookies
 	if a.Cookie == "" {
-		acceptor.cookie = n.cookie
+		acceptor.cookies.Store(&n.cookies)
+	} else {
+		acceptor.SetCookie(a.Cookie)
 	}
 	for k, v := range a.AtomMapping {
 		acceptor.atom_mapping[k] = v
 	}
 
 	for i := pstart; i < pend+1; i++ {
-		hp := net.JoinHostPort(a.Host, strconv.Itoa(int(i)))
-		lcl, err := lc.Listen(context.Background(), a.TCP, hp)
+		lcl, err := tr.Listen(tr.Address(a.Host, i))
 		if err != nil {
 			if e, ok := err.(*net.OpError); ok {
 				if _, ok := e.Err.(*net.DNSError); ok {
@@ -1119,11 +1313,20 @@ func (n *network) startAcceptor(a gen.AcceptorOptions) (*acceptor, error) {
 			a.Host, pstart, pend)
 	}
 
+	if acceptor.mtls != nil && acceptor.cert_manager == nil {
+		acceptor.l.Close()
+		return acceptor, fmt.Errorf("mutual TLS requires certificate")
+	}
+
 	if acceptor.cert_manager != nil {
 		config := &tls.Config{
 			GetCertificate:     acceptor.cert_manager.GetCertificateFunc(),
 			InsecureSkipVerify: a.InsecureSkipVerify,
 		}
+		if acceptor.mtls != nil {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+			config.ClientCAs = acceptor.mtls.CAs
+		}
 		acceptor.l = tls.NewListener(acceptor.l, config)
 	}
 
@@ -1135,10 +1338,11 @@ func (n *network) startAcceptor(a gen.AcceptorOptions) (*acceptor, error) {
 	go n.accept(acceptor)
 
 	if lib.Trace() {
-		n.node.Log().Trace("started acceptor on %s with handshake %s and proto %s (TLS: %t)",
+		n.node.Log().Trace("started acceptor on %s with handshake %s and proto %s (TLS: %t, transport: %s)",
 			acceptor.l.Addr(),
 			acceptor.handshake.Version(),
 			acceptor.proto.Version(), acceptor.cert_manager != nil,
+			acceptor.transport.Name(),
 		)
 	}
 
@@ -1150,11 +1354,13 @@ func (n *network) startAcceptor(a gen.AcceptorOptions) (*acceptor, error) {
 
 func (n *network) accept(a *acceptor) {
 	hopts := gen.HandshakeOptions{
-		Cookie:         a.cookie,
 		Flags:          a.flags,
 		MaxMessageSize: a.max_message_size,
 		CertManager:    a.cert_manager,
 	}
+	if a.mtls != nil {
+		hopts.Authorize = n.authorizer(a.mtls)
+	}
 	for {
 		c, err := a.l.Accept()
 		if err != nil {
@@ -1169,9 +1375,7 @@ func (n *network) accept(a *acceptor) {
 			n.node.Log().Trace("accepted new TCP-connection from %s", c.RemoteAddr().String())
 		}
 
-		if hopts.Cookie == "" {
-			hopts.Cookie = n.cookie
-		}
+		hopts.Cookie, hopts.CookieGeneration, hopts.PreviousCookies = a.cookies.Load().get()
 
 		result, err := a.handshake.Accept(n.node, c, hopts)
 		if err != nil {
@@ -1221,6 +1425,9 @@ func (n *network) accept(a *acceptor) {
 			Creation: result.PeerCreation,
 		}
 		log.setSource(logSource)
+		result.Heartbeat = n.heartbeat
+		result.PoolScaling = n.scaling
+		result.SendQueueLimit = n.sendQueue
 		conn, err := a.proto.NewConnection(n.node, result, log)
 		if err != nil {
 			n.node.Log().Warning("unable to create new connection: %s", err)
@@ -1238,6 +1445,67 @@ func (n *network) accept(a *acceptor) {
 	}
 }
 
+// authorizer makes sure the peer node presented the certificate issued for
+// its name and returns the flags granted by the policy
+func (n *network) authorizer(mtls *gen.MutualTLSOptions) func(net.Conn, gen.Atom, gen.NetworkFlags) (gen.NetworkFlags, error) {
+	return func(conn net.Conn, peer gen.Atom, flags gen.NetworkFlags) (gen.NetworkFlags, error) {
+		cert, err := peerCertificate(conn, peer)
+		if err != nil {
+			return flags, err
+		}
+		if mtls.Policy == nil {
+			return flags, nil
+		}
+		granted, err := mtls.Policy(peer, cert, flags)
+		if err != nil {
+			return flags, err
+		}
+		if lib.Trace() {
+			n.node.Log().Trace("policy granted flags %#v to %s", granted, peer)
+		}
+		return granted, nil
+	}
+}
+
+func peerCertificate(conn net.Conn, peer gen.Atom) (*x509.Certificate, error) {
+	tlsconn, ok := conn.(*tls.Conn)
+	if ok == false {
+		return nil, fmt.Errorf("mutual TLS requires TLS connection with %s", peer)
+	}
+	if err := tlsconn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := tlsconn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("node %s presented no certificate", peer)
+	}
+	cert := state.PeerCertificates[0]
+	if gen.CertificateMatchNode(peer, cert) == false {
+		return nil, fmt.Errorf("certificate does not match node name %s", peer)
+	}
+	return cert, nil
+}
+
+// verifyPeerChain verifies the certificate chain of the peer against the given
+// CAs without checking the host name
+func verifyPeerChain(roots *x509.CertPool, usage x509.ExtKeyUsage) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("peer presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
 func (n *network) registerConnection(name gen.Atom, conn gen.Connection) (gen.Connection, error) {
 	if v, exist := n.connections.LoadOrStore(name, conn); exist {
 		return v.(gen.Connection), gen.ErrTaken
@@ -383,6 +383,37 @@ func (n *node) MetaInfo(m gen.Alias) (gen.MetaInfo, error) {
 	return info, nil
 }
 
+func (n *node) mailboxLen(to any) (int64, error) {
+	var value any
+	var found bool
+
+	switch t := to.(type) {
+	case gen.PID:
+		if t.Node != n.name {
+			return 0, gen.ErrUnsupported
+		}
+		value, found = n.processes.Load(t)
+	case gen.Atom:
+		value, found = n.names.Load(t)
+	case gen.ProcessID:
+		if t.Node != n.name {
+			return 0, gen.ErrUnsupported
+		}
+		value, found = n.names.Load(t.Name)
+	default:
+		return 0, gen.ErrUnsupported
+	}
+	if found == false {
+		return 0, gen.ErrProcessUnknown
+	}
+	p := value.(*process)
+	l := p.mailbox.Main.Len() +
+		p.mailbox.System.Len() +
+		p.mailbox.Urgent.Len() +
+		p.mailbox.Log.Len()
+	return l, nil
+}
+
 func (n *node) ProcessInfo(pid gen.PID) (gen.ProcessInfo, error) {
 	var info gen.ProcessInfo
 
@@ -420,6 +451,7 @@ func (n *node) ProcessInfo(pid gen.PID) (gen.ProcessInfo, error) {
 	info.LogLevel = p.log.Level()
 	info.KeepNetworkOrder = p.keeporder
 	info.ImportantDelivery = p.important
+	info.Timers = p.timersInfo()
 
 	if n.security.ExposeEnvInfo {
 		info.Env = p.EnvList()
@@ -695,7 +727,7 @@ func (n *node) ProcessListShortInfo(start, limit int) ([]gen.ProcessShortInfo, e
 			MessagesIn:      process.messagesIn,
 			MessagesOut:     process.messagesOut,
 			MessagesMailbox: uint64(messagesMailbox),
-			RunningTime:     process.runningTime,
+			RunningTime:     atomic.LoadUint64(&process.runningTime),
 			Uptime:          process.Uptime(),
 			State:           process.State(),
 			Parent:          process.parent,
@@ -1502,6 +1534,7 @@ func (n *node) spawn(factory gen.ProcessFactory, options gen.ProcessOptionsExtra
 
 	if err := behavior.ProcessInit(p, options.Args...); err != nil {
 		n.names.Delete(p.name)
+		p.cancelTimers()
 		// make sure to notify children that might have been spawned
 		// (during ProcessInit callback) with the enabled LinkParent option
 		messageExit := gen.MessageExitPID{
@@ -1573,6 +1606,8 @@ func (n *node) unregisterProcess(p *process, reason error) {
 	}
 	n.log.Trace("...unregisterProcess %s", p.pid)
 
+	p.cancelTimers()
+
 	if p.registered.Load() {
 		n.names.Delete(p.name)
 		pname := gen.ProcessID{Name: p.name, Node: n.name}
@@ -0,0 +1,275 @@
+// Package nodetest provides the harness for testing the distributed behavior
+// within a single OS process. It starts a set of nodes connected to each other
+// over the in-memory transport and resolved by the stub registrar, so neither
+// the network nor the external registrar is involved. The links between
+// the nodes can be partitioned and healed to simulate network failures.
+package nodetest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/transport"
+)
+
+const (
+	defaultHost   string        = "localhost"
+	defaultCookie string        = "nodetest"
+	pollInterval  time.Duration = 5 * time.Millisecond
+)
+
+// Options options for the cluster
+type Options struct {
+	// Node options used for starting every node of the cluster. Network.Registrar
+	// and Network.Acceptors are replaced by the cluster ones.
+	Node gen.NodeOptions
+	// Host is appended to the node names given without the host part.
+	// Default value is "localhost".
+	Host string
+}
+
+// Cluster set of the nodes running in the same OS process
+type Cluster struct {
+	sync.Mutex
+	nodes   []gen.Node
+	byname  map[gen.Atom]gen.Node
+	links   *links
+	routes  *routes
+	stopped bool
+}
+
+// Start starts the cluster of the nodes with the given names. If any of nodes
+// can not be started, the nodes started before are stopped.
+func Start(options Options, names ...gen.Atom) (*Cluster, error) {
+	if options.Host == "" {
+		options.Host = defaultHost
+	}
+	if options.Node.Network.Cookie == "" {
+		options.Node.Network.Cookie = defaultCookie
+	}
+
+	c := &Cluster{
+		byname: make(map[gen.Atom]gen.Node),
+		links: &links{
+			prefix:      "nodetest-" + lib.RandomString(8) + "/",
+			memory:      transport.CreateMemory(),
+			owners:      make(map[string]gen.Atom),
+			conns:       make(map[*conn]bool),
+			partitioned: make(map[pair]bool),
+		},
+		routes: &routes{
+			nodes: make(map[gen.Atom][]gen.Route),
+		},
+	}
+
+	for _, name := range names {
+		if _, err := c.Add(name, options); err != nil {
+			c.Stop()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Add starts a new node and adds it to the cluster
+func (c *Cluster) Add(name gen.Atom, options Options) (gen.Node, error) {
+	if strings.Contains(string(name), "@") == false {
+		host := options.Host
+		if host == "" {
+			host = defaultHost
+		}
+		name = gen.Atom(string(name) + "@" + host)
+	}
+	if options.Node.Network.Cookie == "" {
+		options.Node.Network.Cookie = defaultCookie
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.stopped {
+		return nil, gen.ErrNodeTerminated
+	}
+	if _, exist := c.byname[name]; exist {
+		return nil, gen.ErrTaken
+	}
+
+	tr := &nodeTransport{node: name, links: c.links}
+	nopts := options.Node
+	nopts.Network.Registrar = &registrar{routes: c.routes}
+	nopts.Network.Acceptors = []gen.AcceptorOptions{
+		{
+			Host:      name.Host(),
+			Transport: tr,
+		},
+	}
+
+	node, err := sparrow.StartNode(name, nopts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start node %s: %s", name, err)
+	}
+	node.Network().RegisterTransport(tr)
+
+	c.nodes = append(c.nodes, node)
+	c.byname[name] = node
+	return node, nil
+}
+
+// Node returns the node of the cluster by the name
+func (c *Cluster) Node(name gen.Atom) gen.Node {
+	c.Lock()
+	defer c.Unlock()
+	return c.byname[name]
+}
+
+// Nodes returns the nodes of the cluster in the order they were started
+func (c *Cluster) Nodes() []gen.Node {
+	c.Lock()
+	defer c.Unlock()
+	return append([]gen.Node(nil), c.nodes...)
+}
+
+// Connect makes node a connect to node b
+func (c *Cluster) Connect(a, b gen.Atom) error {
+	node, err := c.node(a)
+	if err != nil {
+		return err
+	}
+	_, err = node.Network().GetNode(b)
+	return err
+}
+
+// ConnectAll connects every node of the cluster to each other (full mesh)
+func (c *Cluster) ConnectAll() error {
+	nodes := c.Nodes()
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			if err := c.Connect(nodes[i].Name(), nodes[j].Name()); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			if err := c.WaitConnected(nodes[i].Name(), nodes[j].Name(), 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Partition breaks the link between the nodes a and b. Existing connection is
+// terminated, and any further attempt to connect fails until the link is healed.
+func (c *Cluster) Partition(a, b gen.Atom) {
+	c.links.partition(a, b)
+}
+
+// Isolate breaks the links between the given node and the rest of the cluster
+func (c *Cluster) Isolate(name gen.Atom) {
+	for _, node := range c.Nodes() {
+		if node.Name() == name {
+			continue
+		}
+		c.links.partition(name, node.Name())
+	}
+}
+
+// Heal restores the link between the nodes a and b. The nodes don't reconnect
+// automatically, the connection is established on the next request
+// (or use Connect).
+func (c *Cluster) Heal(a, b gen.Atom) {
+	c.links.heal(a, b)
+}
+
+// HealAll restores all the partitioned links of the cluster
+func (c *Cluster) HealAll() {
+	c.links.healAll()
+}
+
+// Partitioned returns true if the link between the nodes a and b is partitioned
+func (c *Cluster) Partitioned(a, b gen.Atom) bool {
+	return c.links.isPartitioned(a, b)
+}
+
+// WaitConnected waits until both nodes have the connection with each other.
+// Zero timeout means gen.DefaultRequestTimeout seconds.
+func (c *Cluster) WaitConnected(a, b gen.Atom, timeout time.Duration) error {
+	return c.wait(a, b, timeout, true)
+}
+
+// WaitDisconnected waits until both nodes have no connection with each other.
+// Zero timeout means gen.DefaultRequestTimeout seconds.
+func (c *Cluster) WaitDisconnected(a, b gen.Atom, timeout time.Duration) error {
+	return c.wait(a, b, timeout, false)
+}
+
+// Stop stops all the nodes of the cluster in the reverse order they were started
+// and waits for their termination. It is safe to call Stop more than once.
+func (c *Cluster) Stop() {
+	c.Lock()
+	nodes := c.nodes
+	c.stopped = true
+	c.Unlock()
+
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i].Stop()
+		nodes[i].Wait()
+	}
+
+	// nothing must be left behind
+	c.links.Lock()
+	var conns []*conn
+	for tc := range c.links.conns {
+		conns = append(conns, tc)
+	}
+	c.links.Unlock()
+	for _, tc := range conns {
+		tc.Close()
+	}
+}
+
+func (c *Cluster) node(name gen.Atom) (gen.Node, error) {
+	c.Lock()
+	defer c.Unlock()
+	node, found := c.byname[name]
+	if found == false {
+		return nil, gen.ErrNoRoute
+	}
+	return node, nil
+}
+
+func (c *Cluster) wait(a, b gen.Atom, timeout time.Duration, connected bool) error {
+	nodeA, err := c.node(a)
+	if err != nil {
+		return err
+	}
+	nodeB, err := c.node(b)
+	if err != nil {
+		return err
+	}
+
+	if timeout == 0 {
+		timeout = time.Duration(gen.DefaultRequestTimeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		_, errA := nodeA.Network().Node(b)
+		_, errB := nodeB.Network().Node(a)
+		if connected && errA == nil && errB == nil {
+			return nil
+		}
+		if connected == false && errA != nil && errB != nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return gen.ErrTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,95 @@
+package nodetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+func TestCluster(t *testing.T) {
+	options := Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+
+	cluster, err := Start(options, "nodetestA", "nodetestB", "nodetestC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	a := gen.Atom("nodetestA@localhost")
+	b := gen.Atom("nodetestB@localhost")
+	c := gen.Atom("nodetestC@localhost")
+
+	if len(cluster.Nodes()) != 3 || cluster.Node(b) == nil {
+		t.Fatal("incorrect list of the cluster nodes")
+	}
+	registrar, err := cluster.Node(a).Network().Registrar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes, _ := registrar.Nodes(); len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes in the registrar, got %v", nodes)
+	}
+	acceptors, err := cluster.Node(a).Network().Acceptors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := acceptors[0].Info(); info.Transport != transportName {
+		t.Fatalf("expected transport %q, got %q", transportName, info.Transport)
+	}
+
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// partition terminates existing connection and doesn't allow to reconnect
+	cluster.Partition(a, b)
+	if err := cluster.WaitDisconnected(a, b, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.Connect(b, a); err == nil {
+		t.Fatal("partitioned nodes must not be connected")
+	}
+	if cluster.Partitioned(b, a) == false {
+		t.Fatal("link must be partitioned")
+	}
+	// the rest of the links are still alive
+	if err := cluster.WaitConnected(a, c, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster.Heal(b, a)
+	if err := cluster.Connect(b, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.WaitConnected(a, b, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster.Isolate(c)
+	if err := cluster.WaitDisconnected(c, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.WaitDisconnected(c, b, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	cluster.HealAll()
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := cluster.Nodes()
+	cluster.Stop()
+	for _, node := range nodes {
+		if node.IsAlive() {
+			t.Fatalf("node %s is still alive", node.Name())
+		}
+	}
+	if len(cluster.links.owners) != 0 || len(cluster.links.conns) != 0 {
+		t.Fatal("cluster is not cleaned up")
+	}
+	if _, err := cluster.Add("nodetestD", options); err != gen.ErrNodeTerminated {
+		t.Fatalf("expected %v, got %v", gen.ErrNodeTerminated, err)
+	}
+}
@@ -0,0 +1,134 @@
+package nodetest
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	registrarName    string = "Node Test Registrar"
+	registrarRelease string = "R1"
+)
+
+// routes keeps the routes of the cluster nodes. It is shared by the registrar
+// clients of all the nodes of the cluster.
+type routes struct {
+	sync.RWMutex
+	nodes map[gen.Atom][]gen.Route
+}
+
+// registrar stub registrar of the cluster node. Its resolver knows only
+// the nodes of the same cluster.
+type registrar struct {
+	routes *routes
+	node   gen.NodeRegistrar
+}
+
+func (r *registrar) Register(node gen.NodeRegistrar, routes gen.RegisterRoutes) (gen.StaticRoutes, error) {
+	var static gen.StaticRoutes
+
+	r.routes.Lock()
+	defer r.routes.Unlock()
+
+	if _, exist := r.routes.nodes[node.Name()]; exist {
+		return static, gen.ErrTaken
+	}
+	r.node = node
+	r.routes.nodes[node.Name()] = append([]gen.Route(nil), routes.Routes...)
+	return static, nil
+}
+
+func (r *registrar) Resolver() gen.Resolver {
+	return r
+}
+
+func (r *registrar) RegisterProxy(to gen.Atom) error {
+	return gen.ErrUnsupported
+}
+
+func (r *registrar) UnregisterProxy(to gen.Atom) error {
+	return gen.ErrUnsupported
+}
+
+func (r *registrar) RegisterApplicationRoute(route gen.ApplicationRoute) error {
+	return gen.ErrUnsupported
+}
+
+func (r *registrar) UnregisterApplicationRoute(name gen.Atom) error {
+	return gen.ErrUnsupported
+}
+
+func (r *registrar) Nodes() ([]gen.Atom, error) {
+	r.routes.RLock()
+	defer r.routes.RUnlock()
+
+	nodes := []gen.Atom{}
+	for name := range r.routes.nodes {
+		if r.node != nil && name == r.node.Name() {
+			continue
+		}
+		nodes = append(nodes, name)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes, nil
+}
+
+func (r *registrar) Config(items ...string) (map[string]any, error) {
+	return nil, gen.ErrUnsupported
+}
+
+func (r *registrar) ConfigItem(item string) (any, error) {
+	return nil, gen.ErrUnsupported
+}
+
+func (r *registrar) Event() (gen.Event, error) {
+	return gen.Event{}, gen.ErrUnsupported
+}
+
+func (r *registrar) Info() gen.RegistrarInfo {
+	return gen.RegistrarInfo{
+		Server:  "nodetest",
+		Version: r.Version(),
+	}
+}
+
+func (r *registrar) Terminate() {
+	if r.node == nil {
+		return
+	}
+	r.routes.Lock()
+	delete(r.routes.nodes, r.node.Name())
+	r.routes.Unlock()
+}
+
+func (r *registrar) Version() gen.Version {
+	return gen.Version{
+		Name:    registrarName,
+		Release: registrarRelease,
+	}
+}
+
+//
+// gen.Resolver interface implementation
+//
+
+func (r *registrar) Resolve(name gen.Atom) ([]gen.Route, error) {
+	r.routes.RLock()
+	defer r.routes.RUnlock()
+
+	routes, found := r.routes.nodes[name]
+	if found == false || len(routes) == 0 {
+		return nil, gen.ErrNoRoute
+	}
+	return append([]gen.Route(nil), routes...), nil
+}
+
+func (r *registrar) ResolveProxy(name gen.Atom) ([]gen.ProxyRoute, error) {
+	return nil, gen.ErrNoRoute
+}
+
+func (r *registrar) ResolveApplication(name gen.Atom) ([]gen.ApplicationRoute, error) {
+	return nil, gen.ErrNoRoute
+}
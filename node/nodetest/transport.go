@@ -0,0 +1,145 @@
+package nodetest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+const transportName string = "nodetest"
+
+// links keeps the state of the links between the cluster nodes: the listeners,
+// the established connections and the partitioned pairs of nodes.
+type links struct {
+	sync.Mutex
+	prefix      string
+	memory      gen.NetworkTransport
+	owners      map[string]gen.Atom // listening address => node
+	conns       map[*conn]bool
+	partitioned map[pair]bool
+}
+
+type pair struct {
+	a gen.Atom
+	b gen.Atom
+}
+
+func makePair(a, b gen.Atom) pair {
+	if b < a {
+		a, b = b, a
+	}
+	return pair{a: a, b: b}
+}
+
+func (l *links) partition(a, b gen.Atom) {
+	var drop []*conn
+
+	l.Lock()
+	l.partitioned[makePair(a, b)] = true
+	for c := range l.conns {
+		if c.link == makePair(a, b) {
+			drop = append(drop, c)
+		}
+	}
+	l.Unlock()
+
+	// the pipe is closed on both ends, so the nodes see the connection down
+	for _, c := range drop {
+		c.Close()
+	}
+}
+
+func (l *links) heal(a, b gen.Atom) {
+	l.Lock()
+	delete(l.partitioned, makePair(a, b))
+	l.Unlock()
+}
+
+func (l *links) healAll() {
+	l.Lock()
+	l.partitioned = make(map[pair]bool)
+	l.Unlock()
+}
+
+func (l *links) isPartitioned(a, b gen.Atom) bool {
+	l.Lock()
+	defer l.Unlock()
+	return l.partitioned[makePair(a, b)]
+}
+
+// nodeTransport the memory transport of the cluster node. The listening
+// addresses are unique for the cluster, so the clusters running in the same
+// process don't see each other.
+type nodeTransport struct {
+	node  gen.Atom
+	links *links
+}
+
+func (t *nodeTransport) Name() string {
+	return transportName
+}
+
+func (t *nodeTransport) Address(host string, port uint16) string {
+	return t.links.prefix + net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func (t *nodeTransport) Listen(address string) (net.Listener, error) {
+	l, err := t.links.memory.Listen(address)
+	if err != nil {
+		return nil, err
+	}
+	t.links.Lock()
+	t.links.owners[address] = t.node
+	t.links.Unlock()
+	return &listener{Listener: l, links: t.links}, nil
+}
+
+func (t *nodeTransport) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	t.links.Lock()
+	peer, found := t.links.owners[address]
+	if found && t.links.partitioned[makePair(t.node, peer)] {
+		t.links.Unlock()
+		return nil, fmt.Errorf("link %s <-> %s is partitioned", t.node, peer)
+	}
+	t.links.Unlock()
+
+	c, err := t.links.memory.Dial(address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &conn{Conn: c, links: t.links, link: makePair(t.node, peer)}
+	t.links.Lock()
+	t.links.conns[tc] = true
+	t.links.Unlock()
+	return tc, nil
+}
+
+type listener struct {
+	net.Listener
+	links *links
+}
+
+func (l *listener) Close() error {
+	l.links.Lock()
+	delete(l.links.owners, l.Addr().String())
+	l.links.Unlock()
+	return l.Listener.Close()
+}
+
+type conn struct {
+	net.Conn
+	links *links
+	link  pair
+}
+
+func (c *conn) Close() error {
+	c.links.Lock()
+	delete(c.links.conns, c)
+	c.links.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,73 @@
+package node
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/sllt/sparrow/gen"
+)
+
+// peerMap keeps the values per peer, the empty name is the value for all
+// the peers. The map is replaced entirely on update, so the lookup on sending
+// a message is lock-free.
+type peerMap[V any] struct {
+	sync.Mutex // serializes updates
+	list       atomic.Pointer[map[gen.Atom]V]
+}
+
+func (m *peerMap[V]) set(peer gen.Atom, value V) {
+	m.Lock()
+	defer m.Unlock()
+
+	list := m.get()
+	list[peer] = value
+	m.list.Store(&list)
+}
+
+func (m *peerMap[V]) remove(peer gen.Atom) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	current := m.list.Load()
+	if current == nil {
+		return false
+	}
+	if _, found := (*current)[peer]; found == false {
+		return false
+	}
+	if len(*current) == 1 {
+		m.list.Store(nil)
+		return true
+	}
+	list := m.get()
+	delete(list, peer)
+	m.list.Store(&list)
+	return true
+}
+
+func (m *peerMap[V]) get() map[gen.Atom]V {
+	list := make(map[gen.Atom]V)
+	if current := m.list.Load(); current != nil {
+		for k, v := range *current {
+			list[k] = v
+		}
+	}
+	return list
+}
+
+func (m *peerMap[V]) lookup(peer gen.Atom) (V, bool) {
+	current := m.list.Load()
+	if current == nil {
+		var empty V
+		return empty, false
+	}
+	if value, found := (*current)[peer]; found {
+		return value, true
+	}
+	value, found := (*current)[""]
+	return value, found
+}
+
+func (m *peerMap[V]) enabled() bool {
+	return m.list.Load() != nil
+}
@@ -60,6 +60,9 @@ type process struct {
 	// meta processes
 	metas sync.Map // metas[Alias] -> *meta
 
+	// active timers
+	timers timers
+
 	// gen.Log interface
 	log *log
 
@@ -367,11 +370,7 @@ func (p *process) SetCompressionType(ctype gen.CompressionType) error {
 		return gen.ErrProcessTerminated
 	}
 
-	switch ctype {
-	case gen.CompressionTypeGZIP:
-	case gen.CompressionTypeLZW:
-	case gen.CompressionTypeZLIB:
-	default:
+	if ctype.ID() == 0 {
 		return gen.ErrIncorrect
 	}
 
@@ -415,6 +414,18 @@ func (p *process) SetCompressionThreshold(threshold int) error {
 	return nil
 }
 
+func (p *process) CompressionAdaptive() bool {
+	return p.compression.Adaptive
+}
+
+func (p *process) SetCompressionAdaptive(adaptive bool) error {
+	if p.isAlive() == false {
+		return gen.ErrProcessTerminated
+	}
+	p.compression.Adaptive = adaptive
+	return nil
+}
+
 func (p *process) SendPriority() gen.MessagePriority {
 	return p.priority
 }
@@ -705,37 +716,41 @@ func (p *process) SendAlias(to gen.Alias, message any) error {
 }
 
 func (p *process) SendAfter(to any, message any, after time.Duration) (gen.CancelFunc, error) {
-	if p.isAlive() == false {
-		return nil, gen.ErrNotAllowed
+	return p.startTimer("", to, message, after, 0)
+}
+
+func (p *process) SendInterval(to any, message any, interval time.Duration) (gen.CancelFunc, error) {
+	if interval <= 0 {
+		return nil, gen.ErrIncorrect
 	}
-	return time.AfterFunc(after, func() {
-		var err error
-		if lib.Trace() {
-			p.log.Trace("SendAfter %s to %s", after, to)
-		}
-		// we can't use p.Send(...) because it checks the process state
-		// and returns gen.ErrNotAllowed, so use p.node.Route* methods for that
-		options := gen.MessageOptions{
-			Priority:         p.priority,
-			Compression:      p.compression,
-			KeepNetworkOrder: p.keeporder,
-			// ImportantDelivery: ignore on sending with delay
-		}
-		switch t := to.(type) {
-		case gen.Atom:
-			err = p.node.RouteSendProcessID(p.pid, gen.ProcessID{Name: t, Node: p.node.name}, options, message)
-		case gen.PID:
-			err = p.node.RouteSendPID(p.pid, t, options, message)
-		case gen.ProcessID:
-			err = p.node.RouteSendProcessID(p.pid, t, options, message)
-		case gen.Alias:
-			err = p.node.RouteSendAlias(p.pid, t, options, message)
-		}
+	if err := checkTimerTarget(to); err != nil {
+		return nil, err
+	}
+	return p.startTimer("", to, message, interval, interval)
+}
 
-		if err == nil {
-			atomic.AddUint64(&p.messagesOut, 1)
-		}
-	}).Stop, nil
+func (p *process) SendAfterNamed(name string, to any, message any, after time.Duration) (gen.CancelFunc, error) {
+	if name == "" {
+		return nil, gen.ErrIncorrect
+	}
+	if err := checkTimerTarget(to); err != nil {
+		return nil, err
+	}
+	return p.startTimer(name, to, message, after, 0)
+}
+
+func (p *process) SendIntervalNamed(name string, to any, message any, interval time.Duration) (gen.CancelFunc, error) {
+	if name == "" || interval <= 0 {
+		return nil, gen.ErrIncorrect
+	}
+	if err := checkTimerTarget(to); err != nil {
+		return nil, err
+	}
+	return p.startTimer(name, to, message, interval, interval)
+}
+
+func (p *process) CancelTimer(name string) bool {
+	return p.cancelTimer(name)
 }
 
 func (p *process) SendEvent(name gen.Atom, token gen.Ref, message any) error {
@@ -1580,6 +1595,13 @@ func (p *process) Mailbox() gen.ProcessMailbox {
 	return p.mailbox
 }
 
+// MailboxLen returns the number of messages in the mailbox of the local process.
+// It is not a part of gen.Process, the pool uses it for the least loaded routing
+// (see actor.PoolRoutingLeastLoaded).
+func (p *process) MailboxLen(to any) (int64, error) {
+	return p.node.mailboxLen(to)
+}
+
 func (p *process) Behavior() gen.ProcessBehavior {
 	return p.behavior
 }
@@ -1642,7 +1664,7 @@ func (p *process) run() {
 		startTime := time.Now().UnixNano()
 		// handle mailbox
 		if err := p.behavior.ProcessRun(); err != nil {
-			p.runningTime = p.runningTime + uint64(time.Now().UnixNano()-startTime)
+			atomic.AddUint64(&p.runningTime, uint64(time.Now().UnixNano()-startTime))
 			e := errors.Unwrap(err)
 			if e == nil {
 				e = err
@@ -1662,7 +1684,7 @@ func (p *process) run() {
 		}
 
 		// count the running time
-		p.runningTime = p.runningTime + uint64(time.Now().UnixNano()-startTime)
+		atomic.AddUint64(&p.runningTime, uint64(time.Now().UnixNano()-startTime))
 
 		// change running state to sleep
 		if atomic.CompareAndSwapInt32(&p.state, int32(gen.ProcessStateRunning), int32(gen.ProcessStateSleep)) == false {
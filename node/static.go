@@ -86,6 +86,10 @@ func (srs *staticRoutes) info() []gen.RouteInfo {
 			ProtoVersion:     sr.route.Route.ProtoVersion,
 			Host:             sr.route.Route.Host,
 			Port:             sr.route.Route.Port,
+			Transport:        sr.route.Route.Transport,
+		}
+		if sr.route.Transport != nil {
+			ri.Transport = sr.route.Transport.Name()
 		}
 		info = append(info, ri)
 		return true
@@ -0,0 +1,204 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+)
+
+type timers struct {
+	sync.Mutex
+	id    uint64
+	list  map[uint64]*timer
+	names map[string]uint64
+}
+
+type timer struct {
+	id       uint64
+	name     string
+	to       any
+	message  any
+	interval time.Duration
+	deadline time.Time
+	fired    uint64
+	t        *time.Timer
+}
+
+func (p *process) startTimer(name string, to any, message any, after time.Duration, interval time.Duration) (gen.CancelFunc, error) {
+	if p.isAlive() == false {
+		return nil, gen.ErrNotAllowed
+	}
+
+	p.timers.Lock()
+	defer p.timers.Unlock()
+
+	if p.timers.list == nil {
+		p.timers.list = make(map[uint64]*timer)
+		p.timers.names = make(map[string]uint64)
+	}
+
+	if name != "" {
+		// re-arming named timer replaces the previous one
+		if id, exist := p.timers.names[name]; exist {
+			p.timers.list[id].t.Stop()
+			delete(p.timers.list, id)
+		}
+	}
+
+	p.timers.id++
+	t := &timer{
+		id:       p.timers.id,
+		name:     name,
+		to:       to,
+		message:  message,
+		interval: interval,
+		deadline: time.Now().Add(after),
+	}
+	t.t = time.AfterFunc(after, func() { p.fireTimer(t) })
+
+	p.timers.list[t.id] = t
+	if name != "" {
+		p.timers.names[name] = t.id
+	}
+
+	return func() bool { return p.stopTimer(t) }, nil
+}
+
+// checkTimerTarget is used by the timer methods except SendAfter, which
+// keeps ignoring the unsupported targets silently
+func checkTimerTarget(to any) error {
+	switch to.(type) {
+	case gen.Atom, string, gen.PID, gen.ProcessID, gen.Alias:
+		return nil
+	}
+	return gen.ErrUnsupported
+}
+
+func (p *process) fireTimer(t *timer) {
+	p.timers.Lock()
+	if p.timers.list[t.id] != t {
+		// canceled or replaced
+		p.timers.Unlock()
+		return
+	}
+
+	t.fired++
+	if t.interval > 0 {
+		// re-arm periodic timer keeping the schedule
+		t.deadline = t.deadline.Add(t.interval)
+		next := time.Until(t.deadline)
+		if next < 0 {
+			// we are late. skip the missed ticks
+			t.deadline = time.Now().Add(t.interval)
+			next = t.interval
+		}
+		t.t.Reset(next)
+	} else {
+		p.removeTimer(t)
+	}
+	p.timers.Unlock()
+
+	p.sendTimer(t.to, t.message)
+}
+
+func (p *process) stopTimer(t *timer) bool {
+	p.timers.Lock()
+	defer p.timers.Unlock()
+
+	if p.timers.list[t.id] != t {
+		// has already fired (one-shot timer) or canceled
+		return false
+	}
+	t.t.Stop()
+	p.removeTimer(t)
+	return true
+}
+
+func (p *process) cancelTimer(name string) bool {
+	p.timers.Lock()
+	defer p.timers.Unlock()
+
+	id, exist := p.timers.names[name]
+	if exist == false {
+		return false
+	}
+	t := p.timers.list[id]
+	t.t.Stop()
+	p.removeTimer(t)
+	return true
+}
+
+// removeTimer must be invoked with the locked mutex
+func (p *process) removeTimer(t *timer) {
+	delete(p.timers.list, t.id)
+	if t.name != "" && p.timers.names[t.name] == t.id {
+		delete(p.timers.names, t.name)
+	}
+}
+
+func (p *process) cancelTimers() {
+	p.timers.Lock()
+	defer p.timers.Unlock()
+
+	for _, t := range p.timers.list {
+		t.t.Stop()
+	}
+	p.timers.list = nil
+	p.timers.names = nil
+}
+
+func (p *process) timersInfo() []gen.TimerInfo {
+	p.timers.Lock()
+	defer p.timers.Unlock()
+
+	info := make([]gen.TimerInfo, 0, len(p.timers.list))
+	for _, t := range p.timers.list {
+		info = append(info, gen.TimerInfo{
+			Name:     t.name,
+			To:       fmt.Sprintf("%s", t.to),
+			Interval: int64(t.interval),
+			Deadline: t.deadline.UnixNano(),
+			Fired:    t.fired,
+		})
+	}
+	sort.Slice(info, func(i, j int) bool {
+		return info[i].Deadline < info[j].Deadline
+	})
+	return info
+}
+
+func (p *process) sendTimer(to any, message any) {
+	var err error
+	if lib.Trace() {
+		p.log.Trace("timer sends message to %s", to)
+	}
+	// we can't use p.Send(...) because it checks the process state
+	// and returns gen.ErrNotAllowed, so use p.node.Route* methods for that
+	options := gen.MessageOptions{
+		Priority:         p.priority,
+		Compression:      p.compression,
+		KeepNetworkOrder: p.keeporder,
+		// ImportantDelivery: ignore on sending with delay
+	}
+	switch t := to.(type) {
+	case gen.Atom:
+		err = p.node.RouteSendProcessID(p.pid, gen.ProcessID{Name: t, Node: p.node.name}, options, message)
+	case string:
+		err = p.node.RouteSendProcessID(p.pid, gen.ProcessID{Name: gen.Atom(t), Node: p.node.name}, options, message)
+	case gen.PID:
+		err = p.node.RouteSendPID(p.pid, t, options, message)
+	case gen.ProcessID:
+		err = p.node.RouteSendProcessID(p.pid, t, options, message)
+	case gen.Alias:
+		err = p.node.RouteSendAlias(p.pid, t, options, message)
+	}
+
+	if err == nil {
+		atomic.AddUint64(&p.messagesOut, 1)
+	}
+}
@@ -199,6 +199,20 @@ func (t *t1) TestCompression(tc *testcase) {
 		tc.err <- errIncorrect
 		return
 	}
+	// adaptive
+	if t.CompressionAdaptive() {
+		tc.err <- errIncorrect
+		return
+	}
+	if err := t.SetCompressionAdaptive(true); err != nil {
+		tc.err <- err
+		return
+	}
+	if t.CompressionAdaptive() == false {
+		t.Log().Error("CompressionAdaptive")
+		tc.err <- errIncorrect
+		return
+	}
 	tc.err <- nil
 }
 
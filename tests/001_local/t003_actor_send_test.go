@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sllt/sparrow"
 	"github.com/sllt/sparrow/actor"
@@ -216,6 +217,123 @@ func (t *t3) PongAlias(input any) {
 	t.testcase.err <- nil
 }
 
+func factory_t3ticker() gen.ProcessBehavior {
+	return &t3ticker{}
+}
+
+type t3ticker struct {
+	actor.Actor
+
+	ticks chan string
+}
+
+func (t *t3ticker) Init(args ...any) error {
+	t.ticks = args[0].(chan string)
+	_, err := t.SendIntervalNamed("tick", t.PID(), "tick", 10*time.Millisecond)
+	return err
+}
+
+func (t *t3ticker) HandleMessage(from gen.PID, message any) error {
+	switch message {
+	case "rearm":
+		// must replace the active timer
+		_, err := t.SendIntervalNamed("tick", t.PID(), "tock", 10*time.Millisecond)
+		return err
+	case "cancel":
+		if t.CancelTimer("tick") == false {
+			return errIncorrect
+		}
+		return nil
+	}
+	t.ticks <- message.(string)
+	return nil
+}
+
+func (t *t3) TestSendInterval(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	ticks := make(chan string, 100)
+	pid, err := t.Spawn(factory_t3ticker, gen.ProcessOptions{}, ticks)
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+
+	expect := func(exp string, n int) error {
+		for i := 0; i < n; i++ {
+			select {
+			case v := <-ticks:
+				if v != exp {
+					return errIncorrect
+				}
+			case <-time.After(time.Second):
+				return gen.ErrTimeout
+			}
+		}
+		return nil
+	}
+
+	if err := expect("tick", 3); err != nil {
+		t.testcase.err <- err
+		return
+	}
+
+	info, err := t.Node().ProcessInfo(pid)
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+	if len(info.Timers) != 1 || info.Timers[0].Name != "tick" || time.Duration(info.Timers[0].Interval) != 10*time.Millisecond {
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	t.Send(pid, "rearm")
+	// drain the ticks that might have been sent before re-arming
+	for {
+		v := <-ticks
+		if v == "tock" {
+			break
+		}
+	}
+	if err := expect("tock", 2); err != nil {
+		t.testcase.err <- err
+		return
+	}
+	info, _ = t.Node().ProcessInfo(pid)
+	if len(info.Timers) != 1 {
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	// timers must be canceled on termination
+	t.Node().Kill(pid)
+	time.Sleep(30 * time.Millisecond)
+	for len(ticks) > 0 {
+		<-ticks
+	}
+	select {
+	case <-ticks:
+		t.testcase.err <- errIncorrect
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// SendAfter ignores the unsupported target, the others return an error
+	if _, err := t.SendAfter(1, "tick", time.Millisecond); err != nil {
+		t.testcase.err <- err
+		return
+	}
+	if _, err := t.SendInterval(1, "tick", time.Millisecond); err != gen.ErrUnsupported {
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	t.testcase.err <- nil
+}
+
 func TestT3ActorSend(t *testing.T) {
 	nopt := gen.NodeOptions{}
 	nopt.Log.DefaultLogger.Disable = true
@@ -235,6 +353,7 @@ func TestT3ActorSend(t *testing.T) {
 		{"TestSendProcessID", nil, nil, make(chan error)},
 		{"TestSendAlias", nil, nil, make(chan error)},
 		{"TestSendUnknown", nil, nil, make(chan error)},
+		{"TestSendInterval", nil, nil, make(chan error)},
 	}
 	for _, tc := range t3cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sllt/sparrow/actor"
 	"github.com/sllt/sparrow/gen"
@@ -137,6 +138,98 @@ func (p *t14worker) Terminate(reason error) {
 	p.Log().Info("worker process id=%d terminated: %s", p.id, reason)
 }
 
+func factory_t14scalingpool() gen.ProcessBehavior {
+	return &t14scalingpool{}
+}
+
+type t14scalingpool struct {
+	actor.Pool
+}
+
+func (p *t14scalingpool) Init(args ...any) (actor.PoolOptions, error) {
+	var options actor.PoolOptions
+	options.WorkerFactory = factory_t14slowworker
+	options.PoolSize = 1
+	options.Scaling = actor.PoolScaling{
+		Enable:      true,
+		MinSize:     1,
+		MaxSize:     4,
+		Interval:    50 * time.Millisecond,
+		GrowAfter:   1,
+		ShrinkAfter: 1,
+		Policy:      actor.PoolScalingDefault{GrowBacklog: 2},
+	}
+	return options, nil
+}
+
+func factory_t14slowworker() gen.ProcessBehavior {
+	return &t14slowworker{}
+}
+
+type t14slowworker struct {
+	actor.Actor
+}
+
+func (w *t14slowworker) HandleMessage(from gen.PID, message any) error {
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func factory_t14routingpool() gen.ProcessBehavior {
+	return &t14routingpool{}
+}
+
+type t14routingpool struct {
+	actor.Pool
+}
+
+type t14keyed struct {
+	key string
+}
+
+type t14control struct {
+	ch chan gen.PID
+}
+
+func (p *t14routingpool) Init(args ...any) (actor.PoolOptions, error) {
+	var options actor.PoolOptions
+	options.WorkerFactory = factory_t14routingworker
+	options.PoolSize = 5
+	options.Routing = actor.PoolRouting{
+		Strategy: actor.PoolRoutingConsistentHash,
+		Key: func(message any) string {
+			if m, ok := message.(t14keyed); ok {
+				return m.key
+			}
+			return ""
+		},
+		Broadcast: func(message any) bool {
+			_, ok := message.(t14control)
+			return ok
+		},
+	}
+	return options, nil
+}
+
+func factory_t14routingworker() gen.ProcessBehavior {
+	return &t14routingworker{}
+}
+
+type t14routingworker struct {
+	actor.Actor
+}
+
+func (w *t14routingworker) HandleMessage(from gen.PID, message any) error {
+	if m, ok := message.(t14control); ok {
+		m.ch <- w.PID()
+	}
+	return nil
+}
+
+func (w *t14routingworker) HandleCall(from gen.PID, ref gen.Ref, request any) (any, error) {
+	return w.PID(), nil
+}
+
 func (t *t14) TestBasic(input any) {
 	defer func() {
 		t.testcase = nil
@@ -288,6 +381,148 @@ func (t *t14) TestBasic(input any) {
 	t.testcase.err <- nil
 }
 
+func (t *t14) TestScaling(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	poolpid, err := t.Spawn(factory_t14scalingpool, gen.ProcessOptions{})
+	if err != nil {
+		t.Log().Error("unable to spawn pool process: %s", err)
+		t.testcase.err <- err
+		return
+	}
+
+	workers := func() string {
+		info, err := t.Inspect(poolpid)
+		if err != nil {
+			return err.Error()
+		}
+		return info["scaling_workers"]
+	}
+
+	if w := workers(); w != "1" {
+		t.Log().Error("incorrect number of workers: %s (exp: 1)", w)
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	// make a backlog. the pool must grow up to the max size
+	for i := 0; i < 200; i++ {
+		t.Send(poolpid, i)
+	}
+	grown := false
+	for i := 0; i < 40; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if workers() == "4" {
+			grown = true
+			break
+		}
+	}
+	if grown == false {
+		t.Log().Error("pool hasn't grown: %s workers (exp: 4)", workers())
+		t.testcase.err <- errIncorrect
+		return
+	}
+	if info, _ := t.Inspect(poolpid); info["pool_size"] != "4" {
+		t.Log().Error("pool size must follow scaling: %v", info)
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	// the pool must shrink back to the min size once the backlog is handled
+	shrunk := false
+	for i := 0; i < 80; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if workers() == "1" {
+			shrunk = true
+			break
+		}
+	}
+	if shrunk == false {
+		t.Log().Error("pool hasn't shrunk: %s workers (exp: 1)", workers())
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	info, err := t.Inspect(poolpid)
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+	if info["scaling_grown"] != "3" || info["scaling_shrunk"] != "3" || info["scaling_last_decision"] == "" {
+		t.Log().Error("incorrect scaling report: %v", info)
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	t.testcase.err <- nil
+}
+
+func (t *t14) TestRouting(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	poolpid, err := t.Spawn(factory_t14routingpool, gen.ProcessOptions{})
+	if err != nil {
+		t.Log().Error("unable to spawn pool process: %s", err)
+		t.testcase.err <- err
+		return
+	}
+
+	// messages with the same key must be routed to the same worker
+	routed := make(map[string]gen.PID)
+	workers := make(map[gen.PID]bool)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user%d", i%20)
+		v, err := t.Call(poolpid, t14keyed{key})
+		if err != nil {
+			t.Log().Error("call worker process failed: %s", err)
+			t.testcase.err <- err
+			return
+		}
+		pid := v.(gen.PID)
+		workers[pid] = true
+		if prev, exist := routed[key]; exist && prev != pid {
+			t.Log().Error("key %s routed to %s (exp: %s)", key, pid, prev)
+			t.testcase.err <- errIncorrect
+			return
+		}
+		routed[key] = pid
+	}
+	if len(workers) < 2 {
+		t.Log().Error("keys are not distributed across the workers")
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	// control message must reach all workers
+	ch := make(chan gen.PID, 10)
+	if err := t.Send(poolpid, t14control{ch}); err != nil {
+		t.testcase.err <- err
+		return
+	}
+	reached := make(map[gen.PID]bool)
+	for i := 0; i < 5; i++ {
+		select {
+		case pid := <-ch:
+			reached[pid] = true
+		case <-time.After(time.Second):
+			t.Log().Error("control message reached %d workers (exp: 5)", len(reached))
+			t.testcase.err <- errIncorrect
+			return
+		}
+	}
+	if len(reached) != 5 {
+		t.Log().Error("control message reached %d workers (exp: 5)", len(reached))
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	t.testcase.err <- nil
+}
+
 func TestT14Pool(t *testing.T) {
 	nopt := gen.NodeOptions{}
 	nopt.Log.DefaultLogger.Disable = true
@@ -305,6 +540,8 @@ func TestT14Pool(t *testing.T) {
 
 	t14cases = []*testcase{
 		{"TestBasic", nil, nil, make(chan error)},
+		{"TestScaling", nil, nil, make(chan error)},
+		{"TestRouting", nil, nil, make(chan error)},
 	}
 	for _, tc := range t14cases {
 		name := tc.name
@@ -0,0 +1,267 @@
+package local
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/app/system/scheduler"
+	"github.com/sllt/sparrow/gen"
+)
+
+var (
+	t18cases []*testcase
+)
+
+func factory_t18() gen.ProcessBehavior {
+	return &t18{}
+}
+
+type t18 struct {
+	actor.Actor
+
+	testcase *testcase
+}
+
+func (t *t18) HandleMessage(from gen.PID, message any) error {
+	if t.testcase == nil {
+		t.testcase = message.(*testcase)
+		message = initcase{}
+	}
+	// get method by name
+	method := reflect.ValueOf(t).MethodByName(t.testcase.name)
+	if method.IsValid() == false {
+		t.testcase.err <- fmt.Errorf("unknown method %q", t.testcase.name)
+		t.testcase = nil
+		return nil
+	}
+	method.Call([]reflect.Value{reflect.ValueOf(message)})
+	return nil
+}
+
+func factory_t18receiver() gen.ProcessBehavior {
+	return &t18receiver{}
+}
+
+type t18receiver struct {
+	actor.Actor
+
+	ch chan any
+}
+
+func (r *t18receiver) Init(args ...any) error {
+	r.ch = args[0].(chan any)
+	return nil
+}
+
+func (r *t18receiver) HandleMessage(from gen.PID, message any) error {
+	r.ch <- message
+	return nil
+}
+
+var t18jobs int32
+
+func factory_t18job() gen.ProcessBehavior {
+	return &t18job{}
+}
+
+type t18job struct {
+	actor.Actor
+}
+
+func (j *t18job) Init(args ...any) error {
+	atomic.AddInt32(&t18jobs, 1)
+	// keep running longer than the job interval
+	j.SendAfter(j.PID(), "stop", 150*time.Millisecond)
+	return nil
+}
+
+func (j *t18job) HandleMessage(from gen.PID, message any) error {
+	return gen.TerminateReasonNormal
+}
+
+//
+// test methods
+//
+
+func (t *t18) TestSendJob(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	ticks := make(chan any, 100)
+	pid, err := t.Spawn(factory_t18receiver, gen.ProcessOptions{}, ticks)
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+
+	spec := scheduler.JobSpec{
+		Name:     "t18send",
+		Interval: 30 * time.Millisecond,
+		To:       pid,
+		Message:  "tick",
+	}
+	if v, err := t.Call(scheduler.Name, scheduler.RequestAddJob{Spec: spec}); err != nil || v != true {
+		t.testcase.err <- fmt.Errorf("unable to add job: %v %v", v, err)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-ticks:
+			if v != "tick" {
+				t.testcase.err <- errIncorrect
+				return
+			}
+		case <-time.After(time.Second):
+			t.testcase.err <- gen.ErrTimeout
+			return
+		}
+	}
+	t.testcase.err <- nil
+}
+
+func (t *t18) TestJobManage(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	v, err := t.Call(scheduler.Name, scheduler.RequestRemoveJob{Name: "t18send"})
+	if err != nil || v != true {
+		t.testcase.err <- fmt.Errorf("unable to remove job: %v %v", v, err)
+		return
+	}
+
+	// incorrect specs
+	specs := []scheduler.JobSpec{
+		{Name: "t18bad", Cron: "* * *", To: t.PID()},
+		{Name: "t18bad", Interval: time.Second},
+		{Name: "t18bad", Cron: "* * * * *", To: t.PID(), Location: "Unknown/Location"},
+		{Name: "t18bad", Cron: "* * * * *", Factory: "unknown"},
+	}
+	for _, spec := range specs {
+		v, err := t.Call(scheduler.Name, scheduler.RequestAddJob{Spec: spec})
+		if err != nil {
+			t.testcase.err <- err
+			return
+		}
+		if _, ok := v.(error); ok == false {
+			t.testcase.err <- fmt.Errorf("expected error for %#v", spec)
+			return
+		}
+	}
+
+	spec := scheduler.JobSpec{
+		Name:     "t18cron",
+		Cron:     "0 0 1 1 *",
+		Location: "UTC",
+		To:       t.PID(),
+		Message:  "tick",
+	}
+	if v, err := t.Call(scheduler.Name, scheduler.RequestAddJob{Spec: spec}); err != nil || v != true {
+		t.testcase.err <- fmt.Errorf("unable to add job: %v %v", v, err)
+		return
+	}
+	if v, _ := t.Call(scheduler.Name, scheduler.RequestAddJob{Spec: spec}); v != gen.ErrTaken {
+		t.testcase.err <- fmt.Errorf("expected gen.ErrTaken: %v", v)
+		return
+	}
+
+	v, err = t.Call(scheduler.Name, scheduler.RequestJobInfo{Name: "t18cron"})
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+	info := v.(scheduler.JobInfo)
+	now := time.Now().UTC()
+	exp := time.Date(now.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	if info.Next.Equal(exp) == false {
+		t.testcase.err <- fmt.Errorf("incorrect next run %s (exp: %s)", info.Next, exp)
+		return
+	}
+
+	t.Call(scheduler.Name, scheduler.RequestDisableJob{Name: "t18cron"})
+	v, _ = t.Call(scheduler.Name, scheduler.RequestJobList{})
+	list := v.([]scheduler.JobInfo)
+	if len(list) != 1 || list[0].Next.IsZero() == false || list[0].Spec.Disabled == false {
+		t.testcase.err <- fmt.Errorf("incorrect job list: %#v", list)
+		return
+	}
+
+	t.Call(scheduler.Name, scheduler.RequestRemoveJob{Name: "t18cron"})
+	t.testcase.err <- nil
+}
+
+func (t *t18) TestSpawnJob(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	reg := scheduler.RequestRegisterFactory{Name: "t18job", Factory: factory_t18job}
+	if v, err := t.Call(scheduler.Name, reg); err != nil || v != true {
+		t.testcase.err <- fmt.Errorf("unable to register factory: %v %v", v, err)
+		return
+	}
+
+	spec := scheduler.JobSpec{
+		Name:     "t18spawn",
+		Interval: 40 * time.Millisecond,
+		Factory:  "t18job",
+		Policy:   scheduler.JobPolicySkip,
+	}
+	if v, err := t.Call(scheduler.Name, scheduler.RequestAddJob{Spec: spec}); err != nil || v != true {
+		t.testcase.err <- fmt.Errorf("unable to add job: %v %v", v, err)
+		return
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	v, err := t.Call(scheduler.Name, scheduler.RequestJobInfo{Name: "t18spawn"})
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+	t.Call(scheduler.Name, scheduler.RequestRemoveJob{Name: "t18spawn"})
+
+	info := v.(scheduler.JobInfo)
+	// with the skip policy the overlapping runs must be skipped
+	if info.Runs < 1 || info.Skipped < 1 || int32(info.Runs) != atomic.LoadInt32(&t18jobs) {
+		t.testcase.err <- fmt.Errorf("incorrect job info: %#v", info)
+		return
+	}
+	t.testcase.err <- nil
+}
+
+func TestT18Scheduler(t *testing.T) {
+	nopt := gen.NodeOptions{}
+	nopt.Log.DefaultLogger.Disable = true
+	node, err := sparrow.StartNode("t18node@localhost", nopt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := node.Spawn(factory_t18, gen.ProcessOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	t18cases = []*testcase{
+		{"TestSendJob", nil, nil, make(chan error)},
+		{"TestJobManage", nil, nil, make(chan error)},
+		{"TestSpawnJob", nil, nil, make(chan error)},
+	}
+	for _, tc := range t18cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node.Send(pid, tc)
+			if err := tc.wait(5); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	node.Stop()
+}
@@ -0,0 +1,201 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/meta"
+)
+
+func factory_t19() gen.ProcessBehavior {
+	return &t19{}
+}
+
+// t19 starts the tcp server and echoes the received frames back
+type t19 struct {
+	actor.Actor
+
+	ch chan any
+}
+
+func (t *t19) Init(args ...any) error {
+	t.ch = args[0].(chan any)
+	options := args[1].(meta.TCPServerOptions)
+	server, err := meta.CreateTCPServer(options)
+	if err != nil {
+		return err
+	}
+	if _, err := t.SpawnMeta(server, gen.MetaOptions{}); err != nil {
+		server.Terminate(err)
+		return err
+	}
+	return nil
+}
+
+func (t *t19) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case meta.MessageTCP:
+		t.ch <- string(m.Data)
+		if err := t.SendAlias(m.ID, m); err != nil {
+			t.Log().Error("unable to send to %s: %s", m.ID, err)
+		}
+	case meta.MessageTCPDisconnect:
+		t.ch <- m
+	}
+	return nil
+}
+
+func TestT19TCPFraming(t *testing.T) {
+	nopt := gen.NodeOptions{}
+	nopt.Log.DefaultLogger.Disable = true
+	node, err := sparrow.StartNode("t19node@localhost", nopt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+
+	cases := []struct {
+		name    string
+		framing meta.TCPFraming
+		input   []byte // frames "abc" and "de"
+		output  []byte // echoed frames
+	}{
+		{
+			name:    "length1",
+			framing: meta.TCPFraming{Mode: meta.TCPFramingLength1},
+			input:   []byte("\x03abc\x02de"),
+			output:  []byte("\x03abc\x02de"),
+		},
+		{
+			name:    "length2le",
+			framing: meta.TCPFraming{Mode: meta.TCPFramingLength2, LittleEndian: true},
+			input:   []byte("\x03\x00abc\x02\x00de"),
+			output:  []byte("\x03\x00abc\x02\x00de"),
+		},
+		{
+			name:    "length4",
+			framing: meta.TCPFraming{Mode: meta.TCPFramingLength4},
+			input:   []byte("\x00\x00\x00\x03abc\x00\x00\x00\x02de"),
+			output:  []byte("\x00\x00\x00\x03abc\x00\x00\x00\x02de"),
+		},
+		{
+			name:    "line",
+			framing: meta.TCPFraming{Mode: meta.TCPFramingLine},
+			input:   []byte("abc\r\nde\n"),
+			output:  []byte("abc\nde\n"),
+		},
+		{
+			name:    "delimiter",
+			framing: meta.TCPFraming{Mode: meta.TCPFramingDelimiter, Delimiter: []byte("||")},
+			input:   []byte("abc||de||"),
+			output:  []byte("abc||de||"),
+		},
+		{
+			name: "custom",
+			framing: meta.TCPFraming{
+				Mode:   meta.TCPFramingCustom,
+				Split:  bufio.ScanWords,
+				Encode: func(data []byte) []byte { return append(data, ' ') },
+			},
+			input:  []byte(" abc  de "),
+			output: []byte("abc de "),
+		},
+	}
+
+	port := uint16(17300)
+	for _, c := range cases {
+		port++
+		t.Run(c.name, func(t *testing.T) {
+			ch := make(chan any, 10)
+			options := meta.TCPServerOptions{
+				Host:    "127.0.0.1",
+				Port:    port,
+				Framing: c.framing,
+			}
+			if _, err := node.Spawn(factory_t19, gen.ProcessOptions{}, ch, options); err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// the frames must be reassembled
+			half := len(c.input) / 2
+			conn.Write(c.input[:half])
+			time.Sleep(20 * time.Millisecond)
+			conn.Write(c.input[half:])
+
+			for _, exp := range []string{"abc", "de"} {
+				select {
+				case v := <-ch:
+					if v != exp {
+						t.Fatalf("expected frame %q, got %#v", exp, v)
+					}
+				case <-time.After(time.Second):
+					t.Fatal(gen.ErrTimeout)
+				}
+			}
+
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			output := make([]byte, len(c.output))
+			if _, err := io.ReadFull(conn, output); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(output, c.output) == false {
+				t.Fatalf("expected echo %q, got %q", c.output, output)
+			}
+		})
+	}
+
+	t.Run("maxframesize", func(t *testing.T) {
+		ch := make(chan any, 10)
+		port++
+		options := meta.TCPServerOptions{
+			Host:    "127.0.0.1",
+			Port:    port,
+			Framing: meta.TCPFraming{Mode: meta.TCPFramingLength4, MaxFrameSize: 16},
+		}
+		if _, err := node.Spawn(factory_t19, gen.ProcessOptions{}, ch, options); err != nil {
+			t.Fatal(err)
+		}
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("\x00\x00\x00\x11"))
+		select {
+		case v := <-ch:
+			if _, ok := v.(meta.MessageTCPDisconnect); ok == false {
+				t.Fatalf("expected disconnect, got %#v", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal(gen.ErrTimeout)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Fatalf("connection must be closed by the peer: %v", err)
+		}
+	})
+
+	t.Run("incorrect", func(t *testing.T) {
+		options := meta.TCPServerOptions{
+			Framing: meta.TCPFraming{Mode: meta.TCPFramingDelimiter},
+		}
+		if _, err := meta.CreateTCPServer(options); err == nil {
+			t.Fatal("must be failed with no delimiter")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package local
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/meta"
+)
+
+func factory_t20() gen.ProcessBehavior {
+	return &t20{}
+}
+
+// t20 owns the reconnecting tcp client. The strings are sent to the client,
+// the messages of the client are forwarded to the channel.
+type t20 struct {
+	actor.Actor
+
+	ch     chan any
+	client gen.Alias
+}
+
+func (t *t20) Init(args ...any) error {
+	t.ch = args[0].(chan any)
+	options := args[1].(meta.TCPConnectionOptions)
+	client, err := meta.CreateTCPConnection(options)
+	if err != nil {
+		return err
+	}
+	t.client, err = t.SpawnMeta(client, gen.MetaOptions{})
+	return err
+}
+
+func (t *t20) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case string:
+		return t.SendAlias(t.client, meta.MessageTCP{Data: []byte(m)})
+	case gen.Alias:
+		t.ch <- t.client
+	default:
+		t.ch <- message
+	}
+	return nil
+}
+
+func t20expect(t *testing.T, ch chan any, id gen.Alias, expect any) {
+	select {
+	case v := <-ch:
+		switch m := v.(type) {
+		case meta.MessageTCPConnect:
+			if _, ok := expect.(meta.MessageTCPConnect); ok && m.ID == id {
+				return
+			}
+		case meta.MessageTCPDisconnect:
+			if _, ok := expect.(meta.MessageTCPDisconnect); ok && m.ID == id {
+				return
+			}
+		case meta.MessageTCP:
+			if data, ok := expect.(string); ok && m.ID == id && string(m.Data) == data {
+				return
+			}
+		}
+		t.Fatalf("expected %#v, got %#v", expect, v)
+	case <-time.After(time.Second):
+		t.Fatalf("expected %#v: %s", expect, gen.ErrTimeout)
+	}
+}
+
+func t20read(t *testing.T, conn net.Conn, expect string) {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, len(expect))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != expect {
+		t.Fatalf("expected %q, got %q", expect, buf)
+	}
+}
+
+func TestT20TCPReconnect(t *testing.T) {
+	nopt := gen.NodeOptions{}
+	nopt.Log.DefaultLogger.Disable = true
+	node, err := sparrow.StartNode("t20node@localhost", nopt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+
+	port := uint16(17400)
+	addr := "127.0.0.1:17400"
+
+	ch := make(chan any, 10)
+	options := meta.TCPConnectionOptions{
+		Host: "127.0.0.1",
+		Port: port,
+		Reconnect: meta.TCPReconnect{
+			Enable:      true,
+			MinBackoff:  10 * time.Millisecond,
+			MaxBackoff:  50 * time.Millisecond,
+			BufferLimit: 8,
+		},
+	}
+	// no listener yet
+	pid, err := node.Spawn(factory_t20, gen.ProcessOptions{}, ch, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.Send(pid, gen.Alias{})
+	id := (<-ch).(gen.Alias)
+
+	// buffered while disconnected. the second one exceeds the limit
+	node.Send(pid, "one")
+	node.Send(pid, "0123456789")
+	time.Sleep(100 * time.Millisecond)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	for i := 0; i < 2; i++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t20expect(t, ch, id, meta.MessageTCPConnect{})
+
+		if i == 0 {
+			t20read(t, conn, "one")
+		}
+		node.Send(pid, "two")
+		t20read(t, conn, "two")
+		conn.Write([]byte("hi"))
+		t20expect(t, ch, id, "hi")
+
+		// must be reconnected
+		conn.Close()
+		t20expect(t, ch, id, meta.MessageTCPDisconnect{})
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t20expect(t, ch, id, meta.MessageTCPConnect{})
+
+	// terminated meta process doesn't reconnect
+	node.SendExit(pid, gen.TerminateReasonNormal)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("connection must be closed: %v", err)
+	}
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(200 * time.Millisecond))
+	if c, err := listener.Accept(); err == nil {
+		c.Close()
+		t.Fatal("terminated meta process must not reconnect")
+	}
+}
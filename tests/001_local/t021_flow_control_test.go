@@ -0,0 +1,312 @@
+package local
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/meta"
+)
+
+func factory_t21() gen.ProcessBehavior {
+	return &t21{}
+}
+
+// t21 starts the tcp/udp server and handles the incoming data slowly: it blocks
+// on the first message until the gate is closed.
+type t21 struct {
+	actor.Actor
+
+	ch      chan any
+	gate    chan struct{}
+	blocked bool
+	id      gen.Alias // tcp connection or udp server
+}
+
+type t21inspect struct{}
+
+func (t *t21) Init(args ...any) error {
+	var server gen.MetaBehavior
+	var err error
+
+	t.ch = args[0].(chan any)
+	t.gate = args[1].(chan struct{})
+	switch options := args[2].(type) {
+	case nil:
+		// receives the data only
+		return nil
+	case meta.TCPServerOptions:
+		server, err = meta.CreateTCPServer(options)
+	case meta.UDPServerOptions:
+		server, err = meta.CreateUDPServer(options)
+	}
+	if err != nil {
+		return err
+	}
+	t.id, err = t.SpawnMeta(server, gen.MetaOptions{})
+	return err
+}
+
+func (t *t21) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case meta.MessageTCPConnect:
+		t.id = m.ID
+	case meta.MessageTCP:
+		t.handle(m.Data)
+	case meta.MessageUDP:
+		t.handle(m.Data)
+	case t21inspect:
+		info, err := t.InspectMeta(t.id)
+		if err != nil {
+			t.ch <- err
+			return nil
+		}
+		t.ch <- info
+	}
+	return nil
+}
+
+func (t *t21) handle(data []byte) {
+	if t.blocked == false {
+		t.blocked = true
+		<-t.gate
+	}
+	t.ch <- string(data)
+}
+
+func factory_t21owner() gen.ProcessBehavior {
+	return &t21owner{}
+}
+
+// t21owner starts the tcp server delivering the data to the process pool and
+// terminates the accepted connections on request
+type t21owner struct {
+	actor.Actor
+
+	server gen.Alias
+}
+
+type t21exit struct{}
+
+func (t *t21owner) Init(args ...any) error {
+	server, err := meta.CreateTCPServer(args[0].(meta.TCPServerOptions))
+	if err != nil {
+		return err
+	}
+	t.server, err = t.SpawnMeta(server, gen.MetaOptions{})
+	return err
+}
+
+func (t *t21owner) HandleMessage(from gen.PID, message any) error {
+	switch message.(type) {
+	case t21exit:
+		info, err := t.Info()
+		if err != nil {
+			return err
+		}
+		for _, id := range info.Metas {
+			if id == t.server {
+				continue
+			}
+			t.SendExitMeta(id, gen.TerminateReasonNormal)
+		}
+	}
+	return nil
+}
+
+func t21start(t *testing.T, node gen.Node, options any) (gen.PID, chan any, chan struct{}) {
+	ch := make(chan any, 1000)
+	gate := make(chan struct{})
+	pid, err := node.Spawn(factory_t21, gen.ProcessOptions{}, ch, gate, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pid, ch, gate
+}
+
+// t21mailbox checks the mailbox of the blocked process doesn't exceed the limit
+func t21mailbox(t *testing.T, node gen.Node, pid gen.PID, limit int64) {
+	time.Sleep(300 * time.Millisecond)
+	info, err := node.ProcessInfo(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := info.MailboxQueues.Main + info.MailboxQueues.System + info.MailboxQueues.Urgent
+	if l > limit {
+		t.Fatalf("mailbox must not exceed %d messages, got %d", limit, l)
+	}
+}
+
+func t21inspectMeta(t *testing.T, node gen.Node, pid gen.PID, ch chan any) map[string]string {
+	node.Send(pid, t21inspect{})
+	select {
+	case v := <-ch:
+		info, ok := v.(map[string]string)
+		if ok == false {
+			t.Fatalf("expected inspect info, got %#v", v)
+		}
+		return info
+	case <-time.After(time.Second):
+		t.Fatal(gen.ErrTimeout)
+	}
+	return nil
+}
+
+func TestT21FlowControl(t *testing.T) {
+	nopt := gen.NodeOptions{}
+	nopt.Log.DefaultLogger.Disable = true
+	node, err := sparrow.StartNode("t21node@localhost", nopt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+
+	flow := meta.FlowControl{
+		Enable:        true,
+		HighWatermark: 10,
+		LowWatermark:  2,
+		CheckInterval: time.Millisecond,
+	}
+	total := 100
+
+	t.Run("tcp", func(t *testing.T) {
+		options := meta.TCPServerOptions{
+			Host:        "127.0.0.1",
+			Port:        17500,
+			Framing:     meta.TCPFraming{Mode: meta.TCPFramingLine},
+			FlowControl: flow,
+		}
+		pid, ch, gate := t21start(t, node, options)
+
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(options.Port))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		for i := 0; i < total; i++ {
+			fmt.Fprintf(conn, "%d\n", i)
+		}
+		// the reading is paused, the rest of the data remains in the socket
+		t21mailbox(t, node, pid, flow.HighWatermark+1)
+		close(gate)
+
+		// nothing is lost, the order is kept
+		for i := 0; i < total; i++ {
+			select {
+			case v := <-ch:
+				if v != strconv.Itoa(i) {
+					t.Fatalf("expected %d, got %#v", i, v)
+				}
+			case <-time.After(time.Second):
+				t.Fatal(gen.ErrTimeout)
+			}
+		}
+
+		info := t21inspectMeta(t, node, pid, ch)
+		if info["flow pauses"] == "0" || info["flow dropped"] != "0" {
+			t.Fatalf("incorrect flow control stats: %v", info)
+		}
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		options := meta.UDPServerOptions{
+			Host:        "127.0.0.1",
+			Port:        17501,
+			FlowControl: flow,
+		}
+		pid, ch, gate := t21start(t, node, options)
+
+		conn, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(options.Port))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		for i := 0; i < total; i++ {
+			fmt.Fprintf(conn, "%d", i)
+			time.Sleep(time.Millisecond)
+		}
+		// the packets are dropped while paused
+		t21mailbox(t, node, pid, flow.HighWatermark+1)
+		close(gate)
+
+		received := 0
+		for done := false; done == false; {
+			select {
+			case <-ch:
+				received++
+			case <-time.After(200 * time.Millisecond):
+				done = true
+			}
+		}
+		if received == total {
+			t.Fatal("the packets must be dropped")
+		}
+
+		info := t21inspectMeta(t, node, pid, ch)
+		if info["flow dropped"] != strconv.Itoa(total-received) {
+			t.Fatalf("expected %d dropped packets: %v", total-received, info)
+		}
+	})
+
+	t.Run("terminate", func(t *testing.T) {
+		recv, _, gate := t21start(t, node, nil)
+		defer close(gate)
+		if err := node.RegisterName("t21recv", recv); err != nil {
+			t.Fatal(err)
+		}
+		options := meta.TCPServerOptions{
+			Host:        "127.0.0.1",
+			Port:        17502,
+			ProcessPool: []gen.Atom{"t21recv"},
+			Framing:     meta.TCPFraming{Mode: meta.TCPFramingLine},
+			FlowControl: flow,
+		}
+		owner, err := node.Spawn(factory_t21owner, gen.ProcessOptions{}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(options.Port))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		for i := 0; i < total; i++ {
+			fmt.Fprintf(conn, "%d\n", i)
+		}
+		t21mailbox(t, node, recv, flow.HighWatermark+1)
+
+		// the paused reader must be stopped along with the connection
+		node.Send(owner, t21exit{})
+		for i := 0; ; i++ {
+			info, err := node.ProcessInfo(owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(info.Metas) == 1 {
+				break
+			}
+			if i == 100 {
+				t.Fatalf("the reader of the terminated connection is still running: %v", info.Metas)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("incorrect", func(t *testing.T) {
+		options := meta.UDPServerOptions{
+			FlowControl: meta.FlowControl{Enable: true, HighWatermark: 5, LowWatermark: 5},
+		}
+		if _, err := meta.CreateUDPServer(options); err == nil {
+			t.Fatal("must be failed with incorrect watermarks")
+		}
+	})
+}
@@ -1,11 +1,18 @@
 package distributed
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
 	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/codec"
+	"github.com/sllt/sparrow/net/handshake"
 )
 
 // TODO test static route
@@ -110,3 +117,367 @@ func TestT0NodeBasic(t *testing.T) {
 		t.Fatal("proto version mismatch")
 	}
 }
+
+func factory_t0codec() gen.ProcessBehavior {
+	return &t0codec{}
+}
+
+type t0codec struct {
+	actor.Actor
+	ch chan any
+}
+
+func (t *t0codec) Init(args ...any) error {
+	t.ch = args[0].(chan any)
+	return nil
+}
+
+func (t *t0codec) HandleMessage(from gen.PID, message any) error {
+	t.ch <- message
+	return nil
+}
+
+func TestT0NodeCodec(t *testing.T) {
+	startNode := func(name gen.Atom, codecs ...string) gen.Node {
+		options := gen.NodeOptions{}
+		options.Network.Cookie = "123"
+		options.Network.Handshake = handshake.Create(handshake.Options{Codecs: codecs})
+		options.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	node1 := startNode("distT0node1codec@localhost", codec.JSON, codec.SDF)
+	defer node1.Stop()
+	node2 := startNode("distT0node2codec@localhost", codec.MsgPack, codec.JSON)
+	defer node2.Stop()
+	node3 := startNode("distT0node3codec@localhost", codec.MsgPack)
+	defer node3.Stop()
+
+	// the first codec of the dialing node supported by the accepting one
+	remote, err := node1.Network().GetNode(node2.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.Info().Codec != codec.JSON {
+		t.Fatalf("incorrect codec %q", remote.Info().Codec)
+	}
+	time.Sleep(100 * time.Millisecond)
+	remote2, err := node2.Network().Node(node1.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote2.Info().Codec != codec.JSON {
+		t.Fatalf("incorrect codec %q on the accepting node", remote2.Info().Codec)
+	}
+
+	ch := make(chan any, 1)
+	pid, err := node2.Spawn(factory_t0codec, gen.ProcessOptions{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []any{
+		"hello",
+		map[string]int{"a": 1},
+		gen.ProcessID{Name: "name", Node: node1.Name()},
+	}
+	for _, message := range messages {
+		if err := node1.Send(pid, message); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-ch:
+			if reflect.DeepEqual(m, message) == false {
+				t.Fatalf("incorrect message %#v (exp: %#v)", m, message)
+			}
+		case <-time.After(time.Second):
+			t.Fatal(gen.ErrTimeout)
+		}
+	}
+
+	// no common codec
+	if _, err := node3.Network().GetNode(node1.Name()); err == nil {
+		t.Fatal("nodes without common codec must not be connected")
+	}
+}
+
+func factory_t0forward() gen.ProcessBehavior {
+	return &t0forward{}
+}
+
+// t0forward sends the received messages to the given process
+type t0forward struct {
+	actor.Actor
+	to gen.PID
+}
+
+func (t *t0forward) Init(args ...any) error {
+	t.to = args[0].(gen.PID)
+	return nil
+}
+
+func (t *t0forward) HandleMessage(from gen.PID, message any) error {
+	return t.Send(t.to, message)
+}
+
+func TestT0NodeCompressionDictionary(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 100; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"id":%d,"name":"item-%d","status":"active"}`, i, i*3)))
+	}
+	dict := lib.TrainDictionary(samples, 1024)
+	sampler := lib.NewDictionarySampler(1, 100)
+
+	startNode := func(name gen.Atom, options handshake.Options) gen.Node {
+		nopts := gen.NodeOptions{}
+		nopts.Network.Cookie = "123"
+		nopts.Network.Handshake = handshake.Create(options)
+		nopts.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, nopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	node1 := startNode("distT0node1dict@localhost", handshake.Options{
+		Dictionaries:      [][]byte{[]byte("unknown dictionary"), dict},
+		DictionarySampler: sampler,
+	})
+	defer node1.Stop()
+	node2 := startNode("distT0node2dict@localhost", handshake.Options{
+		Dictionaries: [][]byte{dict},
+	})
+	defer node2.Stop()
+
+	ch := make(chan any, 1)
+	pid, err := node2.Spawn(factory_t0codec, gen.ProcessOptions{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ctype := range []gen.CompressionType{
+		gen.CompressionTypeSnappy,
+		gen.CompressionTypeDeflate,
+		gen.CompressionTypeGZIP, // has no dictionary support
+	} {
+		popts := gen.ProcessOptions{
+			Compression: gen.Compression{Enable: true, Type: ctype, Threshold: 16},
+		}
+		fwd, err := node1.Spawn(factory_t0forward, popts, pid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range []any{string(samples[7]), string(samples[42])} {
+			if err := node1.Send(fwd, message); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case m := <-ch:
+				if reflect.DeepEqual(m, message) == false {
+					t.Fatalf("%s: incorrect message %#v (exp: %#v)", ctype, m, message)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: %s", ctype, gen.ErrTimeout)
+			}
+		}
+	}
+
+	if len(sampler.Samples()) == 0 {
+		t.Fatal("outgoing messages must be sampled")
+	}
+}
+
+func TestT0NodeAdaptiveCompression(t *testing.T) {
+	startNode := func(name gen.Atom) gen.Node {
+		nopts := gen.NodeOptions{}
+		nopts.Network.Cookie = "123"
+		nopts.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, nopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+	node1 := startNode("distT0node1adaptive@localhost")
+	defer node1.Stop()
+	node2 := startNode("distT0node2adaptive@localhost")
+	defer node2.Stop()
+
+	ch := make(chan any, 1)
+	pid, err := node2.Spawn(factory_t0codec, gen.ProcessOptions{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	popts := gen.ProcessOptions{
+		Compression: gen.Compression{Enable: true, Adaptive: true, Type: gen.CompressionTypeGZIP},
+	}
+	fwd, err := node1.Spawn(factory_t0forward, popts, pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// text compresses well, random data doesn't
+	text := strings.Repeat("adaptive compression of the similar messages ", 100)
+	for i := 0; i < 100; i++ {
+		random := []byte(lib.RandomString(4096))
+		for _, message := range []any{fmt.Sprintf("%d %s", i, text), random} {
+			if err := node1.Send(fwd, message); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case m := <-ch:
+				if reflect.DeepEqual(m, message) == false {
+					t.Fatalf("incorrect message %#v (exp: %#v)", m, message)
+				}
+			case <-time.After(time.Second):
+				t.Fatal(gen.ErrTimeout)
+			}
+		}
+	}
+
+	remote, err := node1.Network().Node(node2.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := remote.Info().Compression
+	if info.Messages == 0 || info.BytesOut >= info.BytesIn {
+		t.Fatalf("incorrect compression statistics: %#v", info)
+	}
+	if info.Skipped == 0 {
+		t.Fatal("random data must be sent uncompressed")
+	}
+	decisions := make(map[string]gen.CompressionDecision)
+	for _, d := range info.Decisions {
+		decisions[d.MessageType] = d
+	}
+	if d := decisions["string"]; d.Enable == false || d.Ratio > 0.5 {
+		t.Fatalf("text must be compressed: %#v", d)
+	}
+	if d := decisions["[]uint8"]; d.Enable || d.Messages == 0 {
+		t.Fatalf("random data must not be compressed: %#v", d)
+	}
+}
+
+func TestT0NodeCookieRotation(t *testing.T) {
+	startNode := func(name gen.Atom, cookie string) gen.Node {
+		nopts := gen.NodeOptions{}
+		nopts.Network.Cookie = cookie
+		nopts.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, nopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	node1 := startNode("distT0node1cookie@localhost", "old")
+	defer node1.Stop()
+	node2 := startNode("distT0node2cookie@localhost", "old")
+	defer node2.Stop()
+
+	if _, err := node1.Network().GetNode(node2.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	expiry := 300 * time.Millisecond
+	if err := node2.Network().RotateCookie("new", expiry); err != nil {
+		t.Fatal(err)
+	}
+	if gen := node2.Network().CookieGeneration(); gen != 2 {
+		t.Fatalf("incorrect cookie generation: %d", gen)
+	}
+
+	// established connection stays alive
+	ch := make(chan any, 1)
+	pid, err := node2.Spawn(factory_t0codec, gen.ProcessOptions{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Send(pid, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal(gen.ErrTimeout)
+	}
+
+	// both cookies are accepted
+	node3 := startNode("distT0node3cookie@localhost", "new")
+	defer node3.Stop()
+	node4 := startNode("distT0node4cookie@localhost", "old")
+	defer node4.Stop()
+	for _, c := range []struct {
+		node       gen.Node
+		generation int
+	}{
+		{node3, 2},
+		{node4, 1},
+	} {
+		if _, err := c.node.Network().GetNode(node2.Name()); err != nil {
+			t.Fatal(err)
+		}
+		// accepting side registers the connection asynchronously
+		var remote gen.RemoteNode
+		var err error
+		for i := 0; i < 100; i++ {
+			if remote, err = node2.Network().Node(c.node.Name()); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info := remote.Info(); info.CookieGeneration != c.generation {
+			t.Fatalf("%s: incorrect cookie generation %d (exp: %d)",
+				c.node.Name(), info.CookieGeneration, c.generation)
+		}
+	}
+
+	// the node with the rotated cookie connects to the node with the old one
+	node5 := startNode("distT0node5cookie@localhost", "old")
+	defer node5.Stop()
+	remote, err := node2.Network().GetNode(node5.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := remote.Info(); info.CookieGeneration != 1 {
+		t.Fatalf("incorrect cookie generation %d", info.CookieGeneration)
+	}
+
+	// the old cookie expired
+	time.Sleep(expiry)
+	node6 := startNode("distT0node6cookie@localhost", "old")
+	defer node6.Stop()
+	if _, err := node6.Network().GetNode(node2.Name()); err == nil {
+		t.Fatal("expired cookie must be rejected")
+	}
+	registrar, err := node6.Network().Registrar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := registrar.Resolver().Resolve(node2.Name())
+	if err != nil || len(routes) == 0 {
+		t.Fatalf("unable to resolve %s: %v", node2.Name(), err)
+	}
+	route := gen.NetworkRoute{Route: routes[0]}
+	if _, err := node6.Network().GetNodeWithRoute(node2.Name(), route); err != gen.ErrDigestMismatch {
+		t.Fatalf("expected %v, got %v", gen.ErrDigestMismatch, err)
+	}
+
+	info, err := node2.Network().Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CookieGeneration != 2 {
+		t.Fatalf("incorrect cookie generation %d", info.CookieGeneration)
+	}
+	if len(info.Nodes) != 4 {
+		t.Fatalf("established connections must stay alive: %v", info.Nodes)
+	}
+}
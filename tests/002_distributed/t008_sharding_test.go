@@ -0,0 +1,316 @@
+package distributed
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/app/sharding"
+	"github.com/sllt/sparrow/gen"
+)
+
+const (
+	t8region gen.Atom = "t8region"
+)
+
+func factory_t8entity() gen.ProcessBehavior {
+	return &t8entity{}
+}
+
+type t8entity struct {
+	actor.Actor
+
+	id    string
+	count int
+}
+
+func (t *t8entity) Init(args ...any) error {
+	t.id = args[0].(string)
+	return nil
+}
+
+func (t *t8entity) HandleMessage(from gen.PID, message any) error {
+	if message == "inc" {
+		t.count++
+	}
+	return nil
+}
+
+func (t *t8entity) HandleCall(from gen.PID, ref gen.Ref, request any) (any, error) {
+	switch request {
+	case "where":
+		return t.Node().Name(), nil
+	case "count":
+		return t.count, nil
+	}
+	return request, nil
+}
+
+func factory_t8() gen.ProcessBehavior {
+	return &t8{}
+}
+
+type t8 struct {
+	actor.Actor
+
+	remote   gen.Atom
+	region   gen.PID
+	testcase *testcase
+}
+
+func (t *t8) Init(args ...any) error {
+	t.remote = args[0].(gen.Atom)
+	t.region = args[1].(gen.PID)
+	return nil
+}
+
+func (t *t8) HandleMessage(from gen.PID, message any) error {
+	if t.testcase == nil {
+		t.testcase = message.(*testcase)
+		message = initcase{}
+	}
+
+	// get method by name
+	method := reflect.ValueOf(t).MethodByName(t.testcase.name)
+	if method.IsValid() == false {
+		t.testcase.err <- fmt.Errorf("unknown method %q", t.testcase.name)
+		t.testcase = nil
+		return nil
+	}
+	method.Call([]reflect.Value{reflect.ValueOf(message)})
+	return nil
+}
+
+// waitRegion waits until the region inspect value of the given item becomes expected
+func (t *t8) waitRegion(item string, value string) error {
+	var info map[string]string
+	var err error
+	for i := 0; i < 50; i++ {
+		info, err = t.Inspect(t.region)
+		if err != nil {
+			return err
+		}
+		if info[item] == value {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("region %s: %q (exp: %q)", item, info[item], value)
+}
+
+func (t *t8) TestJoin(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	nodes := []string{string(t.Node().Name()), string(t.remote)}
+	if nodes[0] > nodes[1] {
+		nodes[0], nodes[1] = nodes[1], nodes[0]
+	}
+	live := nodes[0] + ", " + nodes[1]
+	t.testcase.err <- t.waitRegion("nodes_live", live)
+}
+
+func (t *t8) TestCall(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	where := make(map[string]gen.Atom)
+	used := make(map[gen.Atom]bool)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("entity%d", i)
+		v, err := sharding.Call(t, t8region, id, "where")
+		if err != nil {
+			t.testcase.err <- err
+			return
+		}
+		where[id] = v.(gen.Atom)
+		used[where[id]] = true
+	}
+	if len(used) != 2 {
+		t.testcase.err <- fmt.Errorf("entities must be spread across both nodes: %v", used)
+		return
+	}
+
+	// the same entity must be served by the same node
+	for id, node := range where {
+		v, err := sharding.Call(t, t8region, id, "where")
+		if err != nil {
+			t.testcase.err <- err
+			return
+		}
+		if v != node {
+			t.testcase.err <- fmt.Errorf("entity %s moved from %s to %s", id, node, v)
+			return
+		}
+	}
+
+	// region accepts sharding.RequestEntity only
+	if _, err := t.Call(t8region, "where"); err != gen.ErrUnsupported {
+		t.testcase.err <- errIncorrect
+		return
+	}
+
+	t.testcase.err <- nil
+}
+
+func (t *t8) TestSend(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("entity%d", i)
+		for j := 0; j < 3; j++ {
+			if err := sharding.Send(t, t8region, id, "inc"); err != nil {
+				t.testcase.err <- err
+				return
+			}
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("entity%d", i)
+		// messages and requests go by the different routes
+		var count any
+		for k := 0; k < 20; k++ {
+			v, err := sharding.Call(t, t8region, id, "count")
+			if err != nil {
+				t.testcase.err <- err
+				return
+			}
+			count = v
+			if count == 3 {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if count != 3 {
+			t.testcase.err <- fmt.Errorf("entity %s: count %v (exp: 3)", id, count)
+			return
+		}
+	}
+
+	t.testcase.err <- nil
+}
+
+func (t *t8) TestPassivate(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	if err := t.waitRegion("entities", "0"); err != nil {
+		t.testcase.err <- err
+		return
+	}
+
+	// passivated entity must be spawned again
+	v, err := sharding.Call(t, t8region, "entity0", "count")
+	if err != nil {
+		t.testcase.err <- err
+		return
+	}
+	if v != 0 {
+		t.testcase.err <- fmt.Errorf("count %v of the new entity (exp: 0)", v)
+		return
+	}
+
+	t.testcase.err <- nil
+}
+
+func (t *t8) TestLeave(input any) {
+	defer func() {
+		t.testcase = nil
+	}()
+
+	if err := t.waitRegion("nodes_live", string(t.Node().Name())); err != nil {
+		t.testcase.err <- err
+		return
+	}
+
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("entity%d", i)
+		v, err := sharding.Call(t, t8region, id, "where")
+		if err != nil {
+			t.testcase.err <- err
+			return
+		}
+		if v != t.Node().Name() {
+			t.testcase.err <- fmt.Errorf("entity %s is served by %s", id, v)
+			return
+		}
+	}
+
+	t.testcase.err <- nil
+}
+
+func TestT8Sharding(t *testing.T) {
+	startNode := func(name gen.Atom, peer gen.Atom) gen.Node {
+		options := gen.NodeOptions{}
+		options.Network.Cookie = "123"
+		options.Log.DefaultLogger.Disable = true
+		options.Applications = []gen.ApplicationBehavior{
+			sharding.CreateApp(sharding.Options{
+				Name:              t8region,
+				Nodes:             []gen.Atom{peer},
+				Factory:           factory_t8entity,
+				PassivateAfter:    time.Second,
+				ReconnectInterval: 100 * time.Millisecond,
+			}),
+		}
+		node, err := sparrow.StartNode(name, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	name1 := gen.Atom("distT8node1Sharding@localhost")
+	name2 := gen.Atom("distT8node2Sharding@localhost")
+	node1 := startNode(name1, name2)
+	defer node1.Stop()
+	node2 := startNode(name2, name1)
+	defer node2.Stop()
+
+	info, err := node1.ApplicationInfo(t8region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := node1.Spawn(factory_t8, gen.ProcessOptions{}, name2, info.Group[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t8cases := []*testcase{
+		{"TestJoin", nil, nil, make(chan error)},
+		{"TestCall", nil, nil, make(chan error)},
+		{"TestSend", nil, nil, make(chan error)},
+		{"TestPassivate", nil, nil, make(chan error)},
+	}
+	for _, tc := range t8cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := node1.Send(pid, tc); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.wait(10); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	node2.Stop()
+	tc := &testcase{"TestLeave", nil, nil, make(chan error)}
+	t.Run(tc.name, func(t *testing.T) {
+		if err := node1.Send(pid, tc); err != nil {
+			t.Fatal(err)
+		}
+		if err := tc.wait(10); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package distributed
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+)
+
+type t9ca struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func t9createCA(t *testing.T) *t9ca {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"sparrow test CA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &t9ca{cert: cert, key: key, pool: x509.NewCertPool()}
+	ca.pool.AddCert(cert)
+	return ca
+}
+
+func (ca *t9ca) issue(t *testing.T, name gen.Atom) gen.CertManager {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        pkix.Name{CommonName: string(name)},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		EmailAddresses: []string{string(name)},
+		DNSNames:       []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen.CreateCertManager(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key})
+}
+
+type t9spawn struct {
+	actor.Actor
+}
+
+func factory_t9spawn() gen.ProcessBehavior {
+	return &t9spawn{}
+}
+
+func TestT9MutualTLS(t *testing.T) {
+	ca := t9createCA(t)
+	foreign := t9createCA(t)
+
+	startNode := func(name gen.Atom, cert gen.CertManager, mtls *gen.MutualTLSOptions) gen.Node {
+		nopts := gen.NodeOptions{}
+		nopts.Network.Cookie = "123"
+		nopts.Network.MutualTLS = mtls
+		nopts.Network.Acceptors = []gen.AcceptorOptions{
+			{
+				Host:      "localhost",
+				MutualTLS: mtls,
+			},
+		}
+		nopts.CertManager = cert
+		nopts.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, nopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	// node2 doesn't allow remote spawn to node3
+	policy := func(peer gen.Atom, cert *x509.Certificate, flags gen.NetworkFlags) (gen.NetworkFlags, error) {
+		if peer == "distT9node3mtls@localhost" {
+			flags.EnableRemoteSpawn = false
+		}
+		return flags, nil
+	}
+
+	name2 := gen.Atom("distT9node2mtls@localhost")
+	node2 := startNode(name2, ca.issue(t, name2), &gen.MutualTLSOptions{CAs: ca.pool, Policy: policy})
+	defer node2.Stop()
+	if err := node2.Network().EnableSpawn("t9", factory_t9spawn); err != nil {
+		t.Fatal(err)
+	}
+
+	acceptors, err := node2.Network().Acceptors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := acceptors[0].Info(); info.TLS == false || info.MutualTLS == false {
+		t.Fatalf("incorrect acceptor info: %#v", info)
+	}
+
+	name1 := gen.Atom("distT9node1mtls@localhost")
+	node1 := startNode(name1, ca.issue(t, name1), &gen.MutualTLSOptions{CAs: ca.pool})
+	defer node1.Stop()
+
+	remote, err := node1.Network().GetNode(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Spawn("t9", gen.ProcessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// granted flags are limited by the policy
+	name3 := gen.Atom("distT9node3mtls@localhost")
+	node3 := startNode(name3, ca.issue(t, name3), &gen.MutualTLSOptions{CAs: ca.pool})
+	defer node3.Stop()
+
+	remote, err = node3.Network().GetNode(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Spawn("t9", gen.ProcessOptions{}); err != gen.ErrNotAllowed {
+		t.Fatalf("expected %q, got %v", gen.ErrNotAllowed, err)
+	}
+
+	// certificate issued for another node
+	name4 := gen.Atom("distT9node4mtls@localhost")
+	node4 := startNode(name4, ca.issue(t, name1), &gen.MutualTLSOptions{CAs: ca.pool})
+	defer node4.Stop()
+	if _, err := node4.Network().GetNode(name2); err == nil {
+		t.Fatal("node with the certificate of another node must be rejected")
+	}
+
+	// certificate issued by the unknown CA
+	name5 := gen.Atom("distT9node5mtls@localhost")
+	node5 := startNode(name5, foreign.issue(t, name5), &gen.MutualTLSOptions{CAs: ca.pool})
+	defer node5.Stop()
+	if _, err := node5.Network().GetNode(name2); err == nil {
+		t.Fatal("node with the certificate issued by unknown CA must be rejected")
+	}
+
+	// no mutual TLS on the dialing side
+	name6 := gen.Atom("distT9node6mtls@localhost")
+	node6 := startNode(name6, nil, nil)
+	defer node6.Stop()
+	if _, err := node6.Network().GetNode(name2); err == nil {
+		t.Fatal("node without certificate must be rejected")
+	}
+}
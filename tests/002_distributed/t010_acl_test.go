@@ -0,0 +1,178 @@
+package distributed
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+)
+
+type t10request struct {
+	op     gen.NetworkACLOperation
+	target any
+	err    chan error
+}
+
+func factory_t10() gen.ProcessBehavior {
+	return &t10{}
+}
+
+type t10 struct {
+	actor.Actor
+}
+
+func (t *t10) HandleMessage(from gen.PID, message any) error {
+	r, ok := message.(t10request)
+	if ok == false {
+		return nil
+	}
+	switch r.op {
+	case gen.NetworkACLSend:
+		r.err <- t.SendImportant(r.target, "ping")
+	case gen.NetworkACLCall:
+		_, err := t.Call(r.target, "ping")
+		r.err <- err
+	case gen.NetworkACLMonitor:
+		r.err <- t.Monitor(r.target)
+	case gen.NetworkACLLink:
+		r.err <- t.Link(r.target)
+	default:
+		r.err <- fmt.Errorf("unknown operation %s", r.op)
+	}
+	return nil
+}
+
+func (t *t10) HandleCall(from gen.PID, ref gen.Ref, request any) (any, error) {
+	return request, nil
+}
+
+func TestT10NetworkACL(t *testing.T) {
+	startNode := func(name gen.Atom, acl gen.NetworkACL) gen.Node {
+		nopts := gen.NodeOptions{}
+		nopts.Network.Cookie = "123"
+		nopts.Network.ACL = acl
+		nopts.Log.DefaultLogger.Disable = true
+		node, err := sparrow.StartNode(name, nopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	name1 := gen.Atom("distT10node1acl@localhost")
+	name2 := gen.Atom("distT10node2acl@localhost")
+	name3 := gen.Atom("distT10node3acl@localhost")
+
+	acl := gen.NetworkACL{
+		Groups: map[string][]gen.Atom{
+			"trusted": {name1},
+		},
+		Rules: []gen.NetworkACLRule{
+			{Groups: []string{"trusted"}, Names: []gen.Atom{"private"}},
+			{Names: []gen.Atom{"private"}, Deny: true},
+			{Nodes: []gen.Atom{name3}, Operations: gen.NetworkACLLink | gen.NetworkACLMonitor, Deny: true},
+		},
+	}
+	node2 := startNode(name2, acl)
+	defer node2.Stop()
+
+	private, err := node2.SpawnRegister("private", factory_t10, gen.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node2.SpawnRegister("public", factory_t10, gen.ProcessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	node1 := startNode(name1, gen.NetworkACL{})
+	defer node1.Stop()
+	node3 := startNode(name3, gen.NetworkACL{})
+	defer node3.Stop()
+
+	caller1, err := node1.Spawn(factory_t10, gen.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller3, err := node3.Spawn(factory_t10, gen.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(node gen.Node, caller gen.PID, op gen.NetworkACLOperation, target any) error {
+		r := t10request{op: op, target: target, err: make(chan error, 1)}
+		if err := node.Send(caller, r); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-r.err:
+			return err
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s %v: %s", op, target, gen.ErrTimeout)
+		}
+		return nil
+	}
+
+	privateName := gen.ProcessID{Name: "private", Node: name2}
+	publicName := gen.ProcessID{Name: "public", Node: name2}
+
+	cases := []struct {
+		node   gen.Node
+		caller gen.PID
+		op     gen.NetworkACLOperation
+		target any
+		err    error
+	}{
+		{node1, caller1, gen.NetworkACLCall, privateName, nil},
+		{node1, caller1, gen.NetworkACLSend, privateName, nil},
+		{node1, caller1, gen.NetworkACLMonitor, publicName, nil},
+		{node3, caller3, gen.NetworkACLCall, privateName, gen.ErrAccessDenied},
+		{node3, caller3, gen.NetworkACLCall, private, gen.ErrAccessDenied},
+		{node3, caller3, gen.NetworkACLSend, privateName, gen.ErrAccessDenied},
+		{node3, caller3, gen.NetworkACLCall, publicName, nil},
+		{node3, caller3, gen.NetworkACLMonitor, publicName, gen.ErrAccessDenied},
+		{node3, caller3, gen.NetworkACLLink, publicName, gen.ErrAccessDenied},
+	}
+	for i, c := range cases {
+		if err := request(c.node, c.caller, c.op, c.target); err != c.err {
+			t.Fatalf("case %d (%s %s %v): expected %v, got %v", i, c.node.Name(), c.op, c.target, c.err, err)
+		}
+	}
+
+	info, err := node2.Network().Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ACLDenied != 5 {
+		t.Fatalf("expected 5 denied requests, got %d", info.ACLDenied)
+	}
+
+	// change the ACL at runtime
+	acl = node2.Network().ACL()
+	acl.Groups["trusted"] = append(acl.Groups["trusted"], name3)
+	acl.Rules = acl.Rules[:2]
+	node2.Network().SetACL(acl)
+
+	if err := request(node3, caller3, gen.NetworkACLCall, privateName); err != nil {
+		t.Fatal(err)
+	}
+	if err := request(node3, caller3, gen.NetworkACLMonitor, publicName); err != nil {
+		t.Fatal(err)
+	}
+
+	// deny everything not allowed explicitly
+	node2.Network().SetACL(gen.NetworkACL{
+		Rules: []gen.NetworkACLRule{
+			{Nodes: []gen.Atom{name1}, Operations: gen.NetworkACLCall},
+		},
+		DefaultDeny: true,
+	})
+	if err := request(node1, caller1, gen.NetworkACLCall, publicName); err != nil {
+		t.Fatal(err)
+	}
+	if err := request(node1, caller1, gen.NetworkACLSend, publicName); err != gen.ErrAccessDenied {
+		t.Fatalf("expected %v, got %v", gen.ErrAccessDenied, err)
+	}
+}
@@ -0,0 +1,103 @@
+package distributed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/transport"
+)
+
+func TestT11Transport(t *testing.T) {
+	unix := transport.CreateUnix(transport.UnixOptions{Dir: t.TempDir()})
+	cert, err := lib.GenerateSelfSignedCert("sparrow test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		transport gen.NetworkTransport
+		tls       bool
+	}{
+		{"unix", unix, false},
+		{"memory", transport.CreateMemory(), false},
+		{"memorytls", transport.CreateMemory(), true},
+		{"websocket", transport.CreateWebSocket(transport.WebSocketOptions{Path: "/t11"}), false},
+		{"websockettls", transport.CreateWebSocket(transport.WebSocketOptions{}), true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nopts := gen.NodeOptions{}
+			nopts.Network.Cookie = "123"
+			nopts.Network.InsecureSkipVerify = true
+			nopts.Log.DefaultLogger.Disable = true
+			if c.tls {
+				nopts.CertManager = gen.CreateCertManager(cert)
+			}
+			nopts.Network.Acceptors = []gen.AcceptorOptions{
+				{Transport: c.transport},
+			}
+			node1, err := sparrow.StartNode(gen.Atom("distT11node1"+c.name+"@localhost"), nopts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer node1.Stop()
+
+			acceptors, err := node1.Network().Acceptors()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info := acceptors[0].Info(); info.Transport != c.transport.Name() || info.TLS != c.tls {
+				t.Fatalf("incorrect acceptor info %#v", info)
+			}
+
+			node2, err := sparrow.StartNode(gen.Atom("distT11node2"+c.name+"@localhost"), nopts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer node2.Stop()
+			// the registrar advertises the transport by name, so use the same
+			// socket directory
+			node2.Network().RegisterTransport(c.transport)
+
+			ch := make(chan any, 10)
+			pid, err := node1.Spawn(factory_t0codec, gen.ProcessOptions{}, ch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 10; i++ {
+				if err := node2.Send(pid, i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 10; i++ {
+				select {
+				case <-ch:
+				case <-time.After(time.Second):
+					t.Fatal(gen.ErrTimeout)
+				}
+			}
+
+			remote, err := node2.Network().Node(node1.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := remote.Info()
+			if len(info.PoolDSN) == 0 {
+				t.Fatalf("empty pool: %#v", info)
+			}
+			// pool connections are made through the same HTTP path
+			if tp, ok := c.transport.(gen.NetworkTransportPath); ok {
+				if strings.HasSuffix(info.PoolDSN[0], tp.Path()) == false {
+					t.Fatalf("pool DSN %q has no path %q", info.PoolDSN[0], tp.Path())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package distributed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/node/nodetest"
+)
+
+func TestT12NetworkFaults(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	cluster, err := nodetest.Start(options, "distT12node1faults", "distT12node2faults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	name1 := gen.Atom("distT12node1faults@localhost")
+	name2 := gen.Atom("distT12node2faults@localhost")
+	node1 := cluster.Node(name1)
+	node2 := cluster.Node(name2)
+
+	ch := make(chan any, 10)
+	if _, err := node2.SpawnRegister("t12", factory_t0codec, gen.ProcessOptions{}, ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+	remote, err := node1.Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := gen.ProcessID{Name: "t12", Node: name2}
+	receive := func(timeout time.Duration) (any, bool) {
+		select {
+		case m := <-ch:
+			return m, true
+		case <-time.After(timeout):
+			return nil, false
+		}
+	}
+
+	info, err := node1.Network().Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FaultInjection {
+		t.Fatal("fault injection must be disabled by default")
+	}
+
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{DropRate: 2}); err != gen.ErrIncorrect {
+		t.Fatalf("expected %v, got %v", gen.ErrIncorrect, err)
+	}
+
+	// drop everything
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{DropRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	info, err = node1.Network().Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FaultInjection == false || info.Faults[name2].DropRate != 1 {
+		t.Fatalf("fault injection is not reported: %#v", info.Faults)
+	}
+	if err := node1.Send(target, "dropped"); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := receive(200 * time.Millisecond); ok {
+		t.Fatalf("message must be dropped, got %v", m)
+	}
+	if remote.Info().Faults.Dropped != 1 {
+		t.Fatalf("expected 1 dropped message, got %#v", remote.Info().Faults)
+	}
+
+	// delayed messages
+	faults := gen.NetworkFaults{Latency: 100 * time.Millisecond, Jitter: 50 * time.Millisecond}
+	if err := node1.Network().InjectFaults(name2, faults); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := node1.Send(target, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := make(map[any]bool)
+	for i := 0; i < 5; i++ {
+		m, ok := receive(time.Second)
+		if ok == false {
+			t.Fatal(gen.ErrTimeout)
+		}
+		received[m] = true
+	}
+	if len(received) != 5 {
+		t.Fatalf("expected 5 different messages, got %v", received)
+	}
+	if elapsed := time.Since(start); elapsed < faults.Latency {
+		t.Fatalf("messages are not delayed (%s)", elapsed)
+	}
+	if remote.Info().Faults.Delayed != 5 {
+		t.Fatalf("expected 5 delayed messages, got %#v", remote.Info().Faults)
+	}
+
+	// delayed messages are throttled by the bandwidth limit as well
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{Latency: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Network().SetBandwidthLimit(name2, 1024); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := node1.Send(target, strings.Repeat("t", 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := receive(5 * time.Second); ok == false {
+			t.Fatal(gen.ErrTimeout)
+		}
+	}
+	if remote.Info().SendQueue.Throttled == 0 {
+		t.Fatalf("delayed messages must be throttled: %#v", remote.Info().SendQueue)
+	}
+	if err := node1.Network().SetBandwidthLimit(name2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// default faults are not applied to the peer with the explicit ones
+	if err := node1.Network().InjectFaults("", gen.NetworkFaults{DropRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Send(target, "delivered"); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := receive(time.Second); ok == false || m != "delivered" {
+		t.Fatalf("expected message, got %v", m)
+	}
+
+	// reordering across the links of the pool
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{ReorderRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Send(target, "reordered"); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := receive(time.Second); ok == false || m != "reordered" {
+		t.Fatalf("expected message, got %v", m)
+	}
+	if remote.Info().Faults.Reordered != 1 {
+		t.Fatalf("expected 1 reordered message, got %#v", remote.Info().Faults)
+	}
+
+	// forced disconnect
+	if err := node1.Network().InjectFaults(name2, gen.NetworkFaults{DisconnectRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Send(target, "lost"); err != nil {
+		t.Fatal(err)
+	}
+	if remote.Info().Faults.Disconnected != 1 {
+		t.Fatalf("expected 1 disconnect, got %#v", remote.Info().Faults)
+	}
+	if m, ok := receive(200 * time.Millisecond); ok {
+		t.Fatalf("message must be lost, got %v", m)
+	}
+
+	node1.Network().RemoveFaults(name2)
+	node1.Network().RemoveFaults("")
+	info, err = node1.Network().Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FaultInjection || len(node1.Network().Faults()) != 0 {
+		t.Fatal("fault injection must be disabled")
+	}
+}
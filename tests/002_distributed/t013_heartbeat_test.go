@@ -0,0 +1,72 @@
+package distributed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/node/nodetest"
+)
+
+func TestT13Heartbeat(t *testing.T) {
+	cases := []struct {
+		name      string
+		heartbeat gen.NetworkHeartbeat
+	}{
+		{"missed", gen.NetworkHeartbeat{Enable: true, Interval: 20 * time.Millisecond, MaxMissed: 3}},
+		{"phi", gen.NetworkHeartbeat{Enable: true, Interval: 20 * time.Millisecond, PhiThreshold: 8}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := nodetest.Options{}
+			options.Node.Log.DefaultLogger.Disable = true
+			options.Node.Network.Heartbeat = c.heartbeat
+
+			name1 := gen.Atom("distT13node1" + c.name + "@localhost")
+			name2 := gen.Atom("distT13node2" + c.name + "@localhost")
+			cluster, err := nodetest.Start(options, name1, name2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cluster.Stop()
+
+			if err := cluster.ConnectAll(); err != nil {
+				t.Fatal(err)
+			}
+			remote, err := cluster.Node(name1).Network().Node(name2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// wait for a few heartbeats
+			time.Sleep(10 * c.heartbeat.Interval)
+			info := remote.Info().Heartbeat
+			if info.Enabled == false {
+				t.Fatal("heartbeat must be enabled")
+			}
+			if info.RTTMax == 0 || info.RTTMin > info.RTTAvg || info.RTTAvg > info.RTTMax {
+				t.Fatalf("incorrect RTT: %#v", info)
+			}
+			if info.Missed > 0 {
+				t.Fatalf("missed heartbeats on the healthy connection: %#v", info)
+			}
+			if c.heartbeat.PhiThreshold > 0 && info.Phi > c.heartbeat.PhiThreshold {
+				t.Fatalf("too high phi on the healthy connection: %#v", info)
+			}
+
+			// the link is alive, but the heartbeats are lost (half-open link)
+			err = cluster.Node(name1).Network().InjectFaults(name2, gen.NetworkFaults{DropRate: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cluster.WaitDisconnected(name1, name2, time.Second); err != nil {
+				t.Fatal(err)
+			}
+			info = remote.Info().Heartbeat
+			if info.MissedTotal == 0 {
+				t.Fatalf("no missed heartbeats: %#v", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package distributed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/net/handshake"
+	"github.com/sllt/sparrow/node/nodetest"
+)
+
+type t14burst struct {
+	to   gen.ProcessID
+	n    int
+	size int
+}
+
+type t14max struct {
+	to gen.ProcessID
+}
+
+func factory_t14() gen.ProcessBehavior {
+	return &t14{}
+}
+
+type t14 struct {
+	actor.Actor
+}
+
+func (t *t14) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case t14burst:
+		for i := 0; i < m.n; i++ {
+			if err := t.Send(m.to, strings.Repeat("n", m.size)); err != nil {
+				return err
+			}
+		}
+	case t14max:
+		return t.SendWithPriority(m.to, "max", gen.MessagePriorityMax)
+	case t14exit:
+		for i := 0; i < m.n; i++ {
+			if err := t.Send(m.to, strings.Repeat("n", 512)); err != nil {
+				return err
+			}
+		}
+		return t.SendExit(m.to, gen.TerminateReasonNormal)
+	}
+	return nil
+}
+
+func TestT14SendQueue(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	// single link, so all the senders share the same send queue
+	options.Node.Network.Handshake = handshake.Create(handshake.Options{PoolSize: 1})
+	cluster, err := nodetest.Start(options, "distT14node1queue", "distT14node2queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	name1 := gen.Atom("distT14node1queue@localhost")
+	name2 := gen.Atom("distT14node2queue@localhost")
+	node1 := cluster.Node(name1)
+	node2 := cluster.Node(name2)
+
+	ch := make(chan any, 128)
+	if _, err := node2.SpawnRegister("t14", factory_t0codec, gen.ProcessOptions{}, ch); err != nil {
+		t.Fatal(err)
+	}
+	var senders []gen.PID
+	for i := 0; i < 5; i++ {
+		pid, err := node1.Spawn(factory_t14, gen.ProcessOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		senders = append(senders, pid)
+	}
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := node1.Network().SetBandwidthLimit(name2, -1); err != gen.ErrIncorrect {
+		t.Fatalf("expected %v, got %v", gen.ErrIncorrect, err)
+	}
+	limit := 8 * 1024
+	if err := node1.Network().SetBandwidthLimit(name2, limit); err != nil {
+		t.Fatal(err)
+	}
+	if node1.Network().BandwidthLimits()[name2] != limit {
+		t.Fatal("bandwidth limit is not set")
+	}
+
+	// the bursts exceed the limit, so the messages are queued behind
+	// the throttled link. the message with max priority overtakes them
+	to := gen.ProcessID{Name: "t14", Node: name2}
+	burst := t14burst{to: to, n: 12, size: 512}
+	for _, pid := range senders[1:] {
+		if err := node1.Send(pid, burst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := node1.Send(senders[0], t14max{to: to}); err != nil {
+		t.Fatal(err)
+	}
+
+	total := burst.n*(len(senders)-1) + 1
+	position := -1
+	for i := 0; i < total; i++ {
+		select {
+		case m := <-ch:
+			if m == "max" {
+				position = i
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal(gen.ErrTimeout)
+		}
+	}
+	if position < 0 || position > total/2 {
+		t.Fatalf("message with max priority must overtake the queued ones (position %d of %d)", position, total)
+	}
+
+	remote, err := node1.Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := remote.Info().SendQueue
+	if info.BandwidthLimit != limit || info.Throttled == 0 || info.Peak == 0 {
+		t.Fatalf("incorrect send queue info: %#v", info)
+	}
+	if info.Normal+info.High+info.Max != 0 {
+		t.Fatalf("send queues must be empty: %#v", info)
+	}
+
+	// no limit
+	if err := node1.Network().SetBandwidthLimit(name2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(node1.Network().BandwidthLimits()) != 0 {
+		t.Fatal("bandwidth limit must be removed")
+	}
+}
+
+type t14exit struct {
+	to gen.PID
+	n  int
+}
+
+func factory_t14recv() gen.ProcessBehavior {
+	return &t14recv{}
+}
+
+// t14recv counts the received messages and reports the counter on the exit signal
+type t14recv struct {
+	actor.Actor
+
+	ch       chan any
+	received int
+}
+
+func (t *t14recv) Init(args ...any) error {
+	t.ch = args[0].(chan any)
+	t.SetTrapExit(true)
+	return nil
+}
+
+func (t *t14recv) HandleMessage(from gen.PID, message any) error {
+	switch message.(type) {
+	case string:
+		t.received++
+	case gen.MessageExitPID:
+		t.ch <- t.received
+	}
+	return nil
+}
+
+func TestT14SendQueueExitOrder(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	options.Node.Network.Handshake = handshake.Create(handshake.Options{PoolSize: 1})
+	cluster, err := nodetest.Start(options, "distT14node1exit", "distT14node2exit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	name2 := gen.Atom("distT14node2exit@localhost")
+	node1 := cluster.Node("distT14node1exit@localhost")
+	node2 := cluster.Node(name2)
+
+	ch := make(chan any, 1)
+	to, err := node2.Spawn(factory_t14recv, gen.ProcessOptions{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := node1.Spawn(factory_t14, gen.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Network().SetBandwidthLimit(name2, 8*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages are queued behind the throttled link. the exit signal has
+	// the higher priority, but must not overtake them
+	exit := t14exit{to: to, n: 20}
+	if err := node1.Send(sender, exit); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case received := <-ch:
+		if received != exit.n {
+			t.Fatalf("exit signal overtook the messages (received %d of %d)", received, exit.n)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal(gen.ErrTimeout)
+	}
+}
+
+func TestT14SendQueueLimit(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	options.Node.Network.Handshake = handshake.Create(handshake.Options{PoolSize: 1})
+	options.Node.Network.SendQueueLimit = 4
+	cluster, err := nodetest.Start(options, "distT14node1limit", "distT14node2limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	name2 := gen.Atom("distT14node2limit@localhost")
+	node1 := cluster.Node("distT14node1limit@localhost")
+	node2 := cluster.Node(name2)
+
+	ch := make(chan any, 128)
+	if _, err := node2.SpawnRegister("t14", factory_t0codec, gen.ProcessOptions{}, ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := node1.Network().SetBandwidthLimit(name2, 1024); err != nil {
+		t.Fatal(err)
+	}
+
+	// the writer is throttled, so the queue gets full
+	to := gen.ProcessID{Name: "t14", Node: name2}
+	sent := 0
+	for i := 0; i < 20; i++ {
+		err := node1.Send(to, strings.Repeat("n", 512))
+		if err == nil {
+			sent++
+			continue
+		}
+		if err != gen.ErrTooLarge {
+			t.Fatalf("expected %v, got %v", gen.ErrTooLarge, err)
+		}
+	}
+	if sent == 20 {
+		t.Fatal("the messages must be rejected by the full send queue")
+	}
+
+	remote, err := node1.Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := remote.Info().SendQueue
+	if info.Limit != options.Node.Network.SendQueueLimit || info.Dropped != uint64(20-sent) {
+		t.Fatalf("incorrect send queue info: %#v", info)
+	}
+	if info.Normal > info.Limit {
+		t.Fatalf("send queue exceeds the limit: %#v", info)
+	}
+
+	// the accepted messages are delivered
+	if err := node1.Network().SetBandwidthLimit(name2, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < sent; i++ {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal(gen.ErrTimeout)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package distributed
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sllt/sparrow/actor"
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/node/nodetest"
+)
+
+type t15stream struct {
+	to gen.ProcessID
+}
+
+type t15stop struct{}
+
+func factory_t15sender() gen.ProcessBehavior {
+	return &t15sender{}
+}
+
+// t15sender sends the sequence of numbers in batches until it gets t15stop
+type t15sender struct {
+	actor.Actor
+	to      gen.ProcessID
+	n       int
+	stopped bool
+}
+
+func (t *t15sender) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case t15stream:
+		t.to = m.to
+	case t15stop:
+		t.stopped = true
+		return t.Send(t.to, fmt.Sprintf("total %d", t.n))
+	}
+	if t.stopped {
+		return nil
+	}
+	for i := 0; i < 50; i++ {
+		if err := t.Send(t.to, t.n); err != nil {
+			return err
+		}
+		t.n++
+	}
+	_, err := t.SendAfter(t.PID(), "next", time.Millisecond)
+	return err
+}
+
+func factory_t15recv() gen.ProcessBehavior {
+	return &t15recv{}
+}
+
+// t15recv checks the order of the received numbers
+type t15recv struct {
+	actor.Actor
+	ch   chan any
+	next int
+	err  error
+}
+
+func (t *t15recv) Init(args ...any) error {
+	t.ch = args[0].(chan any)
+	return nil
+}
+
+func (t *t15recv) HandleMessage(from gen.PID, message any) error {
+	switch m := message.(type) {
+	case int:
+		if m != t.next && t.err == nil {
+			t.err = fmt.Errorf("out of order: expected %d, got %d", t.next, m)
+		}
+		t.next = m + 1
+	case string:
+		var total int
+		fmt.Sscanf(m, "total %d", &total)
+		if t.err == nil && total != t.next {
+			t.err = fmt.Errorf("lost messages: expected %d, got %d", total, t.next)
+		}
+		t.ch <- t.err
+	}
+	return nil
+}
+
+func t15waitLinks(t *testing.T, remotes []gen.RemoteNode, links int) {
+	timeout := time.After(5 * time.Second)
+	for {
+		ok := true
+		for _, remote := range remotes {
+			info := remote.Info()
+			if info.PoolLinks != links || info.PoolSize != links {
+				ok = false
+			}
+		}
+		if ok {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("pool is not resized to %d: %d, %d", links,
+				remotes[0].Info().PoolLinks, remotes[1].Info().PoolLinks)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestT15PoolResize(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	name1 := gen.Atom("distT15node1resize@localhost")
+	name2 := gen.Atom("distT15node2resize@localhost")
+	cluster, err := nodetest.Start(options, name1, name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	node1 := cluster.Node(name1)
+	node2 := cluster.Node(name2)
+
+	// the senders have the different orders, so they use different links
+	ch := make(chan any, 4)
+	var senders []gen.PID
+	for i := 0; i < 4; i++ {
+		name := gen.Atom(fmt.Sprintf("t15recv%d", i))
+		if _, err := node2.SpawnRegister(name, factory_t15recv, gen.ProcessOptions{}, ch); err != nil {
+			t.Fatal(err)
+		}
+		pid, err := node1.Spawn(factory_t15sender, gen.ProcessOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		senders = append(senders, pid)
+	}
+
+	// node1 establishes the connection
+	if err := cluster.Connect(name1, name2); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.WaitConnected(name1, name2, 0); err != nil {
+		t.Fatal(err)
+	}
+	remote1, err := node1.Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote2, err := node2.Network().Node(name1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remotes := []gen.RemoteNode{remote1, remote2}
+	t15waitLinks(t, remotes, remote1.Info().PoolSize)
+
+	if err := remote1.SetPoolSize(0); err != gen.ErrIncorrect {
+		t.Fatalf("expected %v, got %v", gen.ErrIncorrect, err)
+	}
+
+	// the jitter makes the links overtake each other
+	faults := gen.NetworkFaults{Latency: time.Millisecond, Jitter: 10 * time.Millisecond}
+	if err := node1.Network().InjectFaults(name2, faults); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ordered messages are streamed while the pool is resizing
+	for i, pid := range senders {
+		to := gen.ProcessID{Name: gen.Atom(fmt.Sprintf("t15recv%d", i)), Node: name2}
+		if err := node1.Send(pid, t15stream{to: to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, size := range []int{5, 1, 4} {
+		if err := remote1.SetPoolSize(size); err != nil {
+			t.Fatal(err)
+		}
+		t15waitLinks(t, remotes, size)
+	}
+
+	// requested by the node accepted the connection
+	if err := remote2.SetPoolSize(2); err != nil {
+		t.Fatal(err)
+	}
+	t15waitLinks(t, remotes, 2)
+
+	for _, pid := range senders {
+		if err := node1.Send(pid, t15stop{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range senders {
+		select {
+		case err := <-ch:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal(gen.ErrTimeout)
+		}
+	}
+}
+
+func TestT15PoolResizeSending(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	name1 := gen.Atom("distT15node1sending@localhost")
+	name2 := gen.Atom("distT15node2sending@localhost")
+	cluster, err := nodetest.Start(options, name1, name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	node1 := cluster.Node(name1)
+	node2 := cluster.Node(name2)
+	ch := make(chan any, 128)
+	if _, err := node2.SpawnRegister("t15", factory_t0codec, gen.ProcessOptions{}, ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.Connect(name1, name2); err != nil {
+		t.Fatal(err)
+	}
+	remote1, err := node1.Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the queued messages delay the sync of the links
+	if err := node1.Network().SetBandwidthLimit(name2, 8*1024); err != nil {
+		t.Fatal(err)
+	}
+	to := gen.ProcessID{Name: "t15", Node: name2}
+	for i := 0; i < 32; i++ {
+		if err := node1.Send(to, strings.Repeat("n", 512)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resized := make(chan error, 1)
+	go func() {
+		resized <- remote1.SetPoolSize(remote1.Info().PoolSize + 1)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// the sending is not stopped while the links are syncing
+	start := time.Now()
+	if err := node1.Send(to, "sent"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("sending was blocked by resizing for %s", elapsed)
+	}
+
+	select {
+	case err := <-resized:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal(gen.ErrTimeout)
+	}
+}
+
+func TestT15PoolScaling(t *testing.T) {
+	options := nodetest.Options{}
+	options.Node.Log.DefaultLogger.Disable = true
+	options.Node.Network.PoolScaling = gen.NetworkPoolScaling{
+		Enable:   true,
+		Interval: 20 * time.Millisecond,
+		MinSize:  2,
+		MaxSize:  2,
+	}
+	name1 := gen.Atom("distT15node1scaling@localhost")
+	name2 := gen.Atom("distT15node2scaling@localhost")
+	cluster, err := nodetest.Start(options, name1, name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Stop()
+
+	if err := cluster.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+	remote1, err := cluster.Node(name1).Network().Node(name2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote2, err := cluster.Node(name2).Network().Node(name1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the default pool size exceeds the limit
+	t15waitLinks(t, []gen.RemoteNode{remote1, remote2}, 2)
+}
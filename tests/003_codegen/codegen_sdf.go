@@ -0,0 +1,113 @@
+// Code generated by sdfgen. DO NOT EDIT.
+
+package codegen
+
+import (
+	"io"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+func init() {
+	types := []any{
+		Item{},
+		Order{},
+	}
+
+	for _, t := range types {
+		err := sdf.RegisterTypeOf(t)
+		if err == nil || err == gen.ErrTaken {
+			continue
+		}
+		panic(err)
+	}
+}
+
+// EncodeSDF implements sdf.GeneratedEncoder
+func (x Item) EncodeSDF(e *sdf.Encoder) error {
+	e.Int32(x.Code)
+	e.Float32(x.Weight)
+	e.Int8(x.Count)
+	e.Int16(x.Level)
+	e.Uint32(x.Mask)
+	e.Uint(x.Size)
+	e.Uint8(x.Flags)
+	e.Int(x.Total)
+	e.Uint64(x.Big)
+	e.Int32(x.Small)
+	e.Uint8(x.B)
+	return e.Err()
+}
+
+// DecodeSDF implements sdf.GeneratedDecoder
+func (x *Item) DecodeSDF(d *sdf.Decoder) error {
+	x.Code = d.Int32()
+	x.Weight = d.Float32()
+	x.Count = d.Int8()
+	x.Level = d.Int16()
+	x.Mask = d.Uint32()
+	x.Size = d.Uint()
+	x.Flags = d.Uint8()
+	x.Total = d.Int()
+	x.Big = d.Uint64()
+	x.Small = d.Int32()
+	x.B = d.Uint8()
+	return d.Err()
+}
+
+// MarshalSDF implements sdf.Marshaler
+func (x Item) MarshalSDF(w io.Writer) error {
+	return sdf.MarshalGenerated(x, w)
+}
+
+// UnmarshalSDF implements sdf.Unmarshaler
+func (x *Item) UnmarshalSDF(data []byte) error {
+	return sdf.UnmarshalGenerated(x, data)
+}
+
+// EncodeSDF implements sdf.GeneratedEncoder
+func (x Order) EncodeSDF(e *sdf.Encoder) error {
+	e.Int64(x.ID)
+	e.String(x.Name)
+	e.Float64(x.Price)
+	e.Uint16(x.Qty)
+	e.Bool(x.Active)
+	e.Binary(x.Data)
+	e.Field(&x.Tags)
+	e.Field(&x.Attrs)
+	e.Field(&x.Owner)
+	e.Field(&x.From)
+	e.Field(&x.Payload)
+	e.Field(&x.Item)
+	e.Field(&x.Next)
+	return e.Err()
+}
+
+// DecodeSDF implements sdf.GeneratedDecoder
+func (x *Order) DecodeSDF(d *sdf.Decoder) error {
+	x.ID = d.Int64()
+	x.Name = d.String()
+	x.Price = d.Float64()
+	x.Qty = d.Uint16()
+	x.Active = d.Bool()
+	x.Data = d.Binary()
+	d.Field(&x.Tags)
+	d.Field(&x.Attrs)
+	d.Field(&x.Owner)
+	d.Field(&x.From)
+	d.Field(&x.Payload)
+	d.Field(&x.Item)
+	d.Field(&x.Next)
+	return d.Err()
+}
+
+// MarshalSDF implements sdf.Marshaler
+func (x Order) MarshalSDF(w io.Writer) error {
+	return sdf.MarshalGenerated(x, w)
+}
+
+// UnmarshalSDF implements sdf.Unmarshaler
+func (x *Order) UnmarshalSDF(data []byte) error {
+	return sdf.UnmarshalGenerated(x, data)
+}
@@ -0,0 +1,207 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/sllt/sparrow/gen"
+	"github.com/sllt/sparrow/lib"
+	"github.com/sllt/sparrow/net/sdf"
+)
+
+// orderReflect and itemReflect have the same layout as Order and Item,
+// but they are encoded by the reflection-based encoder
+type orderReflect struct {
+	ID      int64
+	Name    string
+	Price   float64
+	Qty     uint16
+	Active  bool
+	Data    []byte
+	Tags    []string
+	Attrs   map[string]int
+	Owner   gen.Atom
+	From    gen.PID
+	Payload any
+	Item    itemReflect
+	Next    *orderReflect
+}
+
+type itemReflect struct {
+	Code   rune
+	Weight float32
+	Count  int8
+	Level  int16
+	Mask   uint32
+	Size   uint
+	Flags  uint8
+	Total  int
+	Big    uint64
+	Small  int32
+	B      byte
+}
+
+func init() {
+	types := []any{
+		itemReflect{},
+		orderReflect{},
+	}
+
+	for _, t := range types {
+		err := sdf.RegisterTypeOf(t)
+		if err == nil || err == gen.ErrTaken {
+			continue
+		}
+		panic(err)
+	}
+}
+
+func testOrder() Order {
+	item := Item{
+		Code:   'z',
+		Weight: 1.5,
+		Count:  -8,
+		Level:  -16,
+		Mask:   0xdeadbeef,
+		Size:   123,
+		Flags:  0x81,
+		Total:  -1,
+		Big:    1 << 63,
+		Small:  -32,
+		B:      'b',
+	}
+	return Order{
+		ID:      -42,
+		Name:    "order",
+		Price:   3.14,
+		Qty:     7,
+		Active:  true,
+		Data:    []byte{1, 2, 3},
+		Tags:    []string{"a", "b"},
+		Attrs:   map[string]int{"x": 1},
+		Owner:   "owner",
+		From:    gen.PID{Node: "node@localhost", ID: 1000, Creation: 1},
+		Payload: "payload",
+		Item:    item,
+		Next:    &Order{ID: 1, Data: []byte{}, Item: item},
+	}
+}
+
+func testOrderReflect(o Order) orderReflect {
+	or := orderReflect{
+		ID:      o.ID,
+		Name:    o.Name,
+		Price:   o.Price,
+		Qty:     o.Qty,
+		Active:  o.Active,
+		Data:    o.Data,
+		Tags:    o.Tags,
+		Attrs:   o.Attrs,
+		Owner:   o.Owner,
+		From:    o.From,
+		Payload: o.Payload,
+		Item:    itemReflect(o.Item),
+	}
+	if o.Next != nil {
+		next := testOrderReflect(*o.Next)
+		or.Next = &next
+	}
+	return or
+}
+
+// body returns the encoded value without the name of the registered type
+func body(t *testing.T, v any) []byte {
+	b := lib.TakeBuffer()
+	defer lib.ReleaseBuffer(b)
+	if err := sdf.Encode(v, b, sdf.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	l := int(binary.BigEndian.Uint16(b.B[1:3]))
+	return append([]byte{}, b.B[3+l:]...)
+}
+
+func prefix(v any) []byte {
+	name := "#" + reflect.TypeOf(v).PkgPath() + "/" + reflect.TypeOf(v).Name()
+	p := []byte{131, 0, 0} // sdtReg
+	binary.BigEndian.PutUint16(p[1:3], uint16(len(name)))
+	return append(p, name...)
+}
+
+func TestT0GeneratedWireCompatible(t *testing.T) {
+	order := testOrder()
+	generated := body(t, order)
+	reflected := body(t, testOrderReflect(order))
+	if bytes.Equal(generated, reflected) == false {
+		t.Fatalf("generated and reflection-based encodings differ:\n%v\n%v", generated, reflected)
+	}
+
+	// reflection-based encoding is decoded by the generated code
+	packet := append(prefix(Order{}), reflected...)
+	value, _, err := sdf.Decode(packet, sdf.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(value, order) == false {
+		t.Fatalf("incorrect value: %#v", value)
+	}
+
+	// generated encoding is decoded by the reflection-based code
+	packet = append(prefix(orderReflect{}), generated...)
+	value, _, err = sdf.Decode(packet, sdf.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(value, testOrderReflect(order)) == false {
+		t.Fatalf("incorrect value: %#v", value)
+	}
+}
+
+func TestT0GeneratedNested(t *testing.T) {
+	// generated types within the reflection-based containers
+	orders := []Order{testOrder(), {Name: "second", Data: []byte{}}}
+	values := []any{
+		orders,
+		map[string]Item{"item": testOrder().Item},
+		[]any{testOrder(), testOrder().Item},
+	}
+	for _, v := range values {
+		b := lib.TakeBuffer()
+		if err := sdf.Encode(v, b, sdf.Options{}); err != nil {
+			t.Fatal(err)
+		}
+		value, _, err := sdf.Decode(b.B, sdf.Options{})
+		lib.ReleaseBuffer(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(value, v) == false {
+			t.Fatalf("incorrect value: %#v (exp: %#v)", value, v)
+		}
+	}
+}
+
+func TestT0GeneratedMarshal(t *testing.T) {
+	order := testOrder()
+	var b bytes.Buffer
+	if err := order.MarshalSDF(&b); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(b.Bytes(), body(t, order)) == false {
+		t.Fatal("MarshalSDF must produce the encoded fields")
+	}
+
+	var value Order
+	if err := value.UnmarshalSDF(b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(value, order) == false {
+		t.Fatalf("incorrect value: %#v", value)
+	}
+
+	// malformed data
+	if err := value.UnmarshalSDF(b.Bytes()[:10]); err == nil {
+		t.Fatal("must be an error")
+	}
+}
@@ -0,0 +1,39 @@
+package codegen
+
+import (
+	"github.com/sllt/sparrow/gen"
+)
+
+//go:generate go run github.com/sllt/sparrow/net/sdf/sdfgen
+
+//sdf:generate
+type Order struct {
+	ID      int64
+	Name    string
+	Price   float64
+	Qty     uint16
+	Active  bool
+	Data    []byte
+	Tags    []string
+	Attrs   map[string]int
+	Owner   gen.Atom
+	From    gen.PID
+	Payload any
+	Item    Item
+	Next    *Order
+}
+
+//sdf:generate
+type Item struct {
+	Code   rune
+	Weight float32
+	Count  int8
+	Level  int16
+	Mask   uint32
+	Size   uint
+	Flags  uint8
+	Total  int
+	Big    uint64
+	Small  int32
+	B      byte
+}